@@ -0,0 +1,141 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: blocks.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const countBlockedMentions = `-- name: CountBlockedMentions :one
+SELECT COUNT(*) FROM chirp_mentions
+INNER JOIN chirps ON chirp_mentions.chirp_id = chirps.id
+INNER JOIN blocks ON blocks.blocker_id = chirp_mentions.user_id AND blocks.blocked_id = chirps.user_id
+WHERE chirp_mentions.chirp_id = $1
+`
+
+// Mentions in a chirp of users who have blocked its author
+func (q *Queries) CountBlockedMentions(ctx context.Context, chirpID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countBlockedMentions, chirpID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createBlock = `-- name: CreateBlock :execrows
+INSERT INTO blocks (blocker_id, blocked_id, created_at)
+VALUES (
+	$1,
+	$2,
+	NOW()
+) ON CONFLICT DO NOTHING
+`
+
+type CreateBlockParams struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+}
+
+func (q *Queries) CreateBlock(ctx context.Context, arg CreateBlockParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, createBlock, arg.BlockerID, arg.BlockedID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteBlock = `-- name: DeleteBlock :execrows
+DELETE FROM blocks WHERE blocker_id = $1 AND blocked_id = $2
+`
+
+type DeleteBlockParams struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+}
+
+func (q *Queries) DeleteBlock(ctx context.Context, arg DeleteBlockParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteBlock, arg.BlockerID, arg.BlockedID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const isBlockedEitherWay = `-- name: IsBlockedEitherWay :one
+SELECT EXISTS (
+	SELECT 1 FROM blocks
+	WHERE (blocker_id = $1 AND blocked_id = $2)
+	OR (blocker_id = $2 AND blocked_id = $1)
+)
+`
+
+type IsBlockedEitherWayParams struct {
+	UserID  uuid.UUID
+	OtherID uuid.UUID
+}
+
+func (q *Queries) IsBlockedEitherWay(ctx context.Context, arg IsBlockedEitherWayParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, isBlockedEitherWay, arg.UserID, arg.OtherID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const listBlocks = `-- name: ListBlocks :many
+SELECT users.id, users.handle, blocks.created_at AS blocked_at FROM blocks
+INNER JOIN users ON blocks.blocked_id = users.id
+WHERE blocks.blocker_id = $1
+AND (
+	$2::timestamp IS NULL
+	OR (blocks.created_at, users.id) < ($2::timestamp, $3::uuid)
+)
+ORDER BY blocks.created_at DESC, users.id DESC
+LIMIT $4
+`
+
+type ListBlocksParams struct {
+	UserID     uuid.UUID
+	CursorTime sql.NullTime
+	CursorID   uuid.NullUUID
+	RowLimit   int32
+}
+
+type ListBlocksRow struct {
+	ID        uuid.UUID
+	Handle    string
+	BlockedAt time.Time
+}
+
+func (q *Queries) ListBlocks(ctx context.Context, arg ListBlocksParams) ([]ListBlocksRow, error) {
+	rows, err := q.db.QueryContext(ctx, listBlocks,
+		arg.UserID,
+		arg.CursorTime,
+		arg.CursorID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListBlocksRow
+	for rows.Next() {
+		var i ListBlocksRow
+		if err := rows.Scan(&i.ID, &i.Handle, &i.BlockedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
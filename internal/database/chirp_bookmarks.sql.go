@@ -0,0 +1,134 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: chirp_bookmarks.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createBookmark = `-- name: CreateBookmark :exec
+INSERT INTO chirp_bookmarks (user_id, chirp_id, created_at)
+VALUES (
+	$1,
+	$2,
+	NOW()
+) ON CONFLICT DO NOTHING
+`
+
+type CreateBookmarkParams struct {
+	UserID  uuid.UUID
+	ChirpID uuid.UUID
+}
+
+func (q *Queries) CreateBookmark(ctx context.Context, arg CreateBookmarkParams) error {
+	_, err := q.db.ExecContext(ctx, createBookmark, arg.UserID, arg.ChirpID)
+	return err
+}
+
+const deleteBookmark = `-- name: DeleteBookmark :exec
+DELETE FROM chirp_bookmarks WHERE user_id = $1 AND chirp_id = $2
+`
+
+type DeleteBookmarkParams struct {
+	UserID  uuid.UUID
+	ChirpID uuid.UUID
+}
+
+func (q *Queries) DeleteBookmark(ctx context.Context, arg DeleteBookmarkParams) error {
+	_, err := q.db.ExecContext(ctx, deleteBookmark, arg.UserID, arg.ChirpID)
+	return err
+}
+
+const listBookmarkedChirps = `-- name: ListBookmarkedChirps :many
+SELECT chirps.id, chirps.body, chirps.user_id, chirps.created_at, chirps.updated_at, chirps.in_reply_to, chirps.rechirp_of, chirps.quote_of, chirps.publish_at, chirps.content_warning, (chirps.sensitive OR chirps.sensitive_by_moderator)::boolean AS sensitive, chirps.visibility, users.is_chirpy_red, chirp_bookmarks.created_at AS bookmarked_at FROM chirp_bookmarks
+INNER JOIN chirps ON chirp_bookmarks.chirp_id = chirps.id
+INNER JOIN users ON chirps.user_id = users.id
+WHERE chirp_bookmarks.user_id = $1
+AND chirp_visible_to(chirps.id, $1)
+AND NOT chirp_muted_for(chirps.id, $1)
+AND (NOT $2::boolean OR NOT chirp_sensitive(chirps.id))
+AND (
+	$3::timestamp IS NULL
+	OR (chirp_bookmarks.created_at, chirp_bookmarks.chirp_id) < ($3::timestamp, $4::uuid)
+)
+ORDER BY chirp_bookmarks.created_at DESC, chirp_bookmarks.chirp_id DESC
+LIMIT $5
+`
+
+type ListBookmarkedChirpsParams struct {
+	UserID        uuid.UUID
+	HideSensitive bool
+	CursorTime    sql.NullTime
+	CursorID      uuid.NullUUID
+	RowLimit      int32
+}
+
+type ListBookmarkedChirpsRow struct {
+	ID             uuid.UUID
+	Body           string
+	UserID         uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	InReplyTo      uuid.NullUUID
+	RechirpOf      uuid.NullUUID
+	QuoteOf        uuid.NullUUID
+	PublishAt      sql.NullTime
+	ContentWarning sql.NullString
+	Sensitive      bool
+	Visibility     string
+	IsChirpyRed    bool
+	BookmarkedAt   time.Time
+}
+
+// Bookmarks of chirps that are deleted but not yet purged stay stored, they
+// are hidden until the chirp is restored or the purge cascades them away
+func (q *Queries) ListBookmarkedChirps(ctx context.Context, arg ListBookmarkedChirpsParams) ([]ListBookmarkedChirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listBookmarkedChirps,
+		arg.UserID,
+		arg.HideSensitive,
+		arg.CursorTime,
+		arg.CursorID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListBookmarkedChirpsRow
+	for rows.Next() {
+		var i ListBookmarkedChirpsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Body,
+			&i.UserID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.InReplyTo,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.PublishAt,
+			&i.ContentWarning,
+			&i.Sensitive,
+			&i.Visibility,
+			&i.IsChirpyRed,
+			&i.BookmarkedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,146 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: chirp_entities.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const createChirpHashtags = `-- name: CreateChirpHashtags :exec
+INSERT INTO chirp_hashtags (chirp_id, tag, start_index, end_index)
+SELECT $1, UNNEST($2::text[]), UNNEST($3::int[]), UNNEST($4::int[])
+`
+
+type CreateChirpHashtagsParams struct {
+	ChirpID      uuid.UUID
+	Tags         []string
+	StartIndexes []int32
+	EndIndexes   []int32
+}
+
+func (q *Queries) CreateChirpHashtags(ctx context.Context, arg CreateChirpHashtagsParams) error {
+	_, err := q.db.ExecContext(ctx, createChirpHashtags,
+		arg.ChirpID,
+		pq.Array(arg.Tags),
+		pq.Array(arg.StartIndexes),
+		pq.Array(arg.EndIndexes),
+	)
+	return err
+}
+
+const createChirpMentions = `-- name: CreateChirpMentions :exec
+INSERT INTO chirp_mentions (chirp_id, user_id, start_index, end_index)
+SELECT $1, users.id, mentions.start_index, mentions.end_index
+FROM (
+	SELECT UNNEST($2::text[]) AS handle, UNNEST($3::int[]) AS start_index, UNNEST($4::int[]) AS end_index
+) AS mentions
+INNER JOIN users ON mentions.handle = users.handle
+`
+
+type CreateChirpMentionsParams struct {
+	ChirpID      uuid.UUID
+	Handles      []string
+	StartIndexes []int32
+	EndIndexes   []int32
+}
+
+// Mentions of handles that do not belong to any user are dropped
+func (q *Queries) CreateChirpMentions(ctx context.Context, arg CreateChirpMentionsParams) error {
+	_, err := q.db.ExecContext(ctx, createChirpMentions,
+		arg.ChirpID,
+		pq.Array(arg.Handles),
+		pq.Array(arg.StartIndexes),
+		pq.Array(arg.EndIndexes),
+	)
+	return err
+}
+
+const deleteChirpHashtags = `-- name: DeleteChirpHashtags :exec
+DELETE FROM chirp_hashtags WHERE chirp_id = $1
+`
+
+func (q *Queries) DeleteChirpHashtags(ctx context.Context, chirpID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteChirpHashtags, chirpID)
+	return err
+}
+
+const deleteChirpMentions = `-- name: DeleteChirpMentions :exec
+DELETE FROM chirp_mentions WHERE chirp_id = $1
+`
+
+func (q *Queries) DeleteChirpMentions(ctx context.Context, chirpID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteChirpMentions, chirpID)
+	return err
+}
+
+const getChirpHashtags = `-- name: GetChirpHashtags :many
+SELECT chirp_id, tag, start_index, end_index FROM chirp_hashtags
+WHERE chirp_id = ANY($1::uuid[])
+`
+
+func (q *Queries) GetChirpHashtags(ctx context.Context, chirpIds []uuid.UUID) ([]ChirpHashtag, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpHashtags, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ChirpHashtag
+	for rows.Next() {
+		var i ChirpHashtag
+		if err := rows.Scan(
+			&i.ChirpID,
+			&i.Tag,
+			&i.StartIndex,
+			&i.EndIndex,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpMentions = `-- name: GetChirpMentions :many
+SELECT chirp_id, user_id, start_index, end_index FROM chirp_mentions
+WHERE chirp_id = ANY($1::uuid[])
+`
+
+func (q *Queries) GetChirpMentions(ctx context.Context, chirpIds []uuid.UUID) ([]ChirpMention, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpMentions, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ChirpMention
+	for rows.Next() {
+		var i ChirpMention
+		if err := rows.Scan(
+			&i.ChirpID,
+			&i.UserID,
+			&i.StartIndex,
+			&i.EndIndex,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
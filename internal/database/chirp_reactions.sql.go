@@ -0,0 +1,213 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: chirp_reactions.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const createReaction = `-- name: CreateReaction :exec
+INSERT INTO chirp_reactions (chirp_id, user_id, reaction, created_at)
+VALUES (
+	$1,
+	$2,
+	$3,
+	NOW()
+) ON CONFLICT DO NOTHING
+`
+
+type CreateReactionParams struct {
+	ChirpID  uuid.UUID
+	UserID   uuid.UUID
+	Reaction string
+}
+
+func (q *Queries) CreateReaction(ctx context.Context, arg CreateReactionParams) error {
+	_, err := q.db.ExecContext(ctx, createReaction, arg.ChirpID, arg.UserID, arg.Reaction)
+	return err
+}
+
+const deleteReaction = `-- name: DeleteReaction :exec
+DELETE FROM chirp_reactions WHERE chirp_id = $1 AND user_id = $2 AND reaction = $3
+`
+
+type DeleteReactionParams struct {
+	ChirpID  uuid.UUID
+	UserID   uuid.UUID
+	Reaction string
+}
+
+func (q *Queries) DeleteReaction(ctx context.Context, arg DeleteReactionParams) error {
+	_, err := q.db.ExecContext(ctx, deleteReaction, arg.ChirpID, arg.UserID, arg.Reaction)
+	return err
+}
+
+const getReactionCounts = `-- name: GetReactionCounts :many
+SELECT chirp_id, reaction, COUNT(*) AS reaction_count FROM chirp_reactions
+WHERE chirp_id = ANY($1::uuid[])
+GROUP BY chirp_id, reaction
+`
+
+type GetReactionCountsRow struct {
+	ChirpID       uuid.UUID
+	Reaction      string
+	ReactionCount int64
+}
+
+func (q *Queries) GetReactionCounts(ctx context.Context, chirpIds []uuid.UUID) ([]GetReactionCountsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getReactionCounts, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetReactionCountsRow
+	for rows.Next() {
+		var i GetReactionCountsRow
+		if err := rows.Scan(&i.ChirpID, &i.Reaction, &i.ReactionCount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getViewerReactions = `-- name: GetViewerReactions :many
+SELECT chirp_id, reaction FROM chirp_reactions
+WHERE chirp_id = ANY($1::uuid[]) AND user_id = $2
+`
+
+type GetViewerReactionsParams struct {
+	ChirpIds []uuid.UUID
+	UserID   uuid.UUID
+}
+
+type GetViewerReactionsRow struct {
+	ChirpID  uuid.UUID
+	Reaction string
+}
+
+func (q *Queries) GetViewerReactions(ctx context.Context, arg GetViewerReactionsParams) ([]GetViewerReactionsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getViewerReactions, pq.Array(arg.ChirpIds), arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetViewerReactionsRow
+	for rows.Next() {
+		var i GetViewerReactionsRow
+		if err := rows.Scan(&i.ChirpID, &i.Reaction); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listReactedChirps = `-- name: ListReactedChirps :many
+SELECT chirps.id, chirps.body, chirps.user_id, chirps.created_at, chirps.updated_at, chirps.in_reply_to, chirps.rechirp_of, chirps.quote_of, chirps.publish_at, chirps.content_warning, (chirps.sensitive OR chirps.sensitive_by_moderator)::boolean AS sensitive, chirps.visibility, users.is_chirpy_red, chirp_reactions.created_at AS reacted_at FROM chirp_reactions
+INNER JOIN chirps ON chirp_reactions.chirp_id = chirps.id
+INNER JOIN users ON chirps.user_id = users.id
+WHERE chirp_reactions.user_id = $1 AND chirp_reactions.reaction = $2
+AND chirp_visible_to(chirps.id, $3::uuid)
+AND NOT chirp_muted_for(chirps.id, $3::uuid)
+AND (NOT $4::boolean OR NOT chirp_sensitive(chirps.id))
+AND (
+	$5::timestamp IS NULL
+	OR (chirp_reactions.created_at, chirp_reactions.chirp_id) < ($5::timestamp, $6::uuid)
+)
+ORDER BY chirp_reactions.created_at DESC, chirp_reactions.chirp_id DESC
+LIMIT $7
+`
+
+type ListReactedChirpsParams struct {
+	UserID        uuid.UUID
+	Reaction      string
+	ViewerID      uuid.NullUUID
+	HideSensitive bool
+	CursorTime    sql.NullTime
+	CursorID      uuid.NullUUID
+	RowLimit      int32
+}
+
+type ListReactedChirpsRow struct {
+	ID             uuid.UUID
+	Body           string
+	UserID         uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	InReplyTo      uuid.NullUUID
+	RechirpOf      uuid.NullUUID
+	QuoteOf        uuid.NullUUID
+	PublishAt      sql.NullTime
+	ContentWarning sql.NullString
+	Sensitive      bool
+	Visibility     string
+	IsChirpyRed    bool
+	ReactedAt      time.Time
+}
+
+func (q *Queries) ListReactedChirps(ctx context.Context, arg ListReactedChirpsParams) ([]ListReactedChirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listReactedChirps,
+		arg.UserID,
+		arg.Reaction,
+		arg.ViewerID,
+		arg.HideSensitive,
+		arg.CursorTime,
+		arg.CursorID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListReactedChirpsRow
+	for rows.Next() {
+		var i ListReactedChirpsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Body,
+			&i.UserID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.InReplyTo,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.PublishAt,
+			&i.ContentWarning,
+			&i.Sensitive,
+			&i.Visibility,
+			&i.IsChirpyRed,
+			&i.ReactedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
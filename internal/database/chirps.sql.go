@@ -7,42 +7,126 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const cancelScheduledChirp = `-- name: CancelScheduledChirp :execrows
+DELETE FROM chirps WHERE id = $1 AND user_id = $2 AND publish_at IS NOT NULL AND deleted_at IS NULL
+`
+
+type CancelScheduledChirpParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) CancelScheduledChirp(ctx context.Context, arg CancelScheduledChirpParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, cancelScheduledChirp, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const claimPurgeableChirps = `-- name: ClaimPurgeableChirps :many
+SELECT id FROM chirps
+WHERE deleted_at < $1::timestamp
+ORDER BY deleted_at ASC
+LIMIT $2
+FOR UPDATE SKIP LOCKED
+`
+
+type ClaimPurgeableChirpsParams struct {
+	DeletedBefore time.Time
+	RowLimit      int32
+}
+
+// Locked so two instances purging at once never work on the same chirps
+func (q *Queries) ClaimPurgeableChirps(ctx context.Context, arg ClaimPurgeableChirpsParams) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, claimPurgeableChirps, arg.DeletedBefore, arg.RowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createChirp = `-- name: CreateChirp :one
 WITH chirpinsert AS (
-	INSERT INTO chirps (id, body, user_id, created_at, updated_at)
+	INSERT INTO chirps (id, body, user_id, created_at, updated_at, in_reply_to, quote_of, publish_at, content_warning, sensitive, visibility)
 	VALUES (
 		GEN_RANDOM_UUID(),
 		$1,
 		$2,
 		NOW(),
-		NOW()
-	) RETURNING id, body, user_id, created_at, updated_at
+		NOW(),
+		$3,
+		$4,
+		$5,
+		$6,
+		$7,
+		$8
+	) RETURNING id, body, user_id, created_at, updated_at, in_reply_to, rechirp_of, quote_of, publish_at, content_warning, (sensitive OR sensitive_by_moderator)::boolean AS sensitive, visibility
 )
-SELECT chirpinsert.id, chirpinsert.body, chirpinsert.user_id, chirpinsert.created_at, chirpinsert.updated_at, users.is_chirpy_red FROM chirpinsert
+SELECT chirpinsert.id, chirpinsert.body, chirpinsert.user_id, chirpinsert.created_at, chirpinsert.updated_at, chirpinsert.in_reply_to, chirpinsert.rechirp_of, chirpinsert.quote_of, chirpinsert.publish_at, chirpinsert.content_warning, chirpinsert.sensitive, chirpinsert.visibility, users.is_chirpy_red FROM chirpinsert
 INNER JOIN users ON chirpinsert.user_id = users.id
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body           string
+	UserID         uuid.UUID
+	InReplyTo      uuid.NullUUID
+	QuoteOf        uuid.NullUUID
+	PublishAt      sql.NullTime
+	ContentWarning sql.NullString
+	Sensitive      bool
+	Visibility     string
 }
 
 type CreateChirpRow struct {
-	ID          uuid.UUID
-	Body        string
-	UserID      uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	IsChirpyRed bool
+	ID             uuid.UUID
+	Body           string
+	UserID         uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	InReplyTo      uuid.NullUUID
+	RechirpOf      uuid.NullUUID
+	QuoteOf        uuid.NullUUID
+	PublishAt      sql.NullTime
+	ContentWarning sql.NullString
+	Sensitive      bool
+	Visibility     string
+	IsChirpyRed    bool
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (CreateChirpRow, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp,
+		arg.Body,
+		arg.UserID,
+		arg.InReplyTo,
+		arg.QuoteOf,
+		arg.PublishAt,
+		arg.ContentWarning,
+		arg.Sensitive,
+		arg.Visibility,
+	)
 	var i CreateChirpRow
 	err := row.Scan(
 		&i.ID,
@@ -50,6 +134,73 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Creat
 		&i.UserID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.InReplyTo,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.PublishAt,
+		&i.ContentWarning,
+		&i.Sensitive,
+		&i.Visibility,
+		&i.IsChirpyRed,
+	)
+	return i, err
+}
+
+const createRechirp = `-- name: CreateRechirp :one
+WITH chirpinsert AS (
+	INSERT INTO chirps (id, body, user_id, created_at, updated_at, rechirp_of)
+	VALUES (
+		GEN_RANDOM_UUID(),
+		'',
+		$1,
+		NOW(),
+		NOW(),
+		$2::uuid
+	)
+	ON CONFLICT (user_id, rechirp_of) WHERE rechirp_of IS NOT NULL DO NOTHING
+	RETURNING id, body, user_id, created_at, updated_at, in_reply_to, rechirp_of, quote_of, publish_at, content_warning, (sensitive OR sensitive_by_moderator)::boolean AS sensitive, visibility
+)
+SELECT chirpinsert.id, chirpinsert.body, chirpinsert.user_id, chirpinsert.created_at, chirpinsert.updated_at, chirpinsert.in_reply_to, chirpinsert.rechirp_of, chirpinsert.quote_of, chirpinsert.publish_at, chirpinsert.content_warning, chirpinsert.sensitive, chirpinsert.visibility, users.is_chirpy_red FROM chirpinsert
+INNER JOIN users ON chirpinsert.user_id = users.id
+`
+
+type CreateRechirpParams struct {
+	UserID    uuid.UUID
+	RechirpOf uuid.UUID
+}
+
+type CreateRechirpRow struct {
+	ID             uuid.UUID
+	Body           string
+	UserID         uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	InReplyTo      uuid.NullUUID
+	RechirpOf      uuid.NullUUID
+	QuoteOf        uuid.NullUUID
+	PublishAt      sql.NullTime
+	ContentWarning sql.NullString
+	Sensitive      bool
+	Visibility     string
+	IsChirpyRed    bool
+}
+
+func (q *Queries) CreateRechirp(ctx context.Context, arg CreateRechirpParams) (CreateRechirpRow, error) {
+	row := q.db.QueryRowContext(ctx, createRechirp, arg.UserID, arg.RechirpOf)
+	var i CreateRechirpRow
+	err := row.Scan(
+		&i.ID,
+		&i.Body,
+		&i.UserID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.InReplyTo,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.PublishAt,
+		&i.ContentWarning,
+		&i.Sensitive,
+		&i.Visibility,
 		&i.IsChirpyRed,
 	)
 	return i, err
@@ -64,36 +215,316 @@ func (q *Queries) DeleteChirp(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
-const getAllChirps = `-- name: GetAllChirps :many
-SELECT chirps.id, chirps.body, chirps.user_id, chirps.created_at, chirps.updated_at, users.is_chirpy_red FROM chirps
+const deleteRechirp = `-- name: DeleteRechirp :execrows
+DELETE FROM chirps WHERE user_id = $1 AND rechirp_of = $2::uuid
+`
+
+type DeleteRechirpParams struct {
+	UserID    uuid.UUID
+	RechirpOf uuid.UUID
+}
+
+func (q *Queries) DeleteRechirp(ctx context.Context, arg DeleteRechirpParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteRechirp, arg.UserID, arg.RechirpOf)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getChirpAncestors = `-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors AS (
+	SELECT chirps.id, chirps.in_reply_to, 0 AS depth FROM chirps
+	WHERE chirps.id = $2
+	UNION ALL
+	SELECT chirps.id, chirps.in_reply_to, ancestors.depth + 1 FROM chirps
+	INNER JOIN ancestors ON chirps.id = ancestors.in_reply_to
+)
+SELECT chirps.id, chirps.body, chirps.user_id, chirps.created_at, chirps.updated_at, chirps.in_reply_to, chirps.rechirp_of, chirps.quote_of, chirps.publish_at, chirps.content_warning, (chirps.sensitive OR chirps.sensitive_by_moderator)::boolean AS sensitive, chirps.visibility, users.is_chirpy_red FROM ancestors
+INNER JOIN chirps ON ancestors.id = chirps.id
+INNER JOIN users ON chirps.user_id = users.id
+WHERE ancestors.depth > 0
+AND chirp_visible_to(chirps.id, $1::uuid)
+AND NOT chirp_muted_for(chirps.id, $1::uuid)
+ORDER BY ancestors.depth DESC
+`
+
+type GetChirpAncestorsParams struct {
+	ViewerID uuid.NullUUID
+	ID       uuid.UUID
+}
+
+type GetChirpAncestorsRow struct {
+	ID             uuid.UUID
+	Body           string
+	UserID         uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	InReplyTo      uuid.NullUUID
+	RechirpOf      uuid.NullUUID
+	QuoteOf        uuid.NullUUID
+	PublishAt      sql.NullTime
+	ContentWarning sql.NullString
+	Sensitive      bool
+	Visibility     string
+	IsChirpyRed    bool
+}
+
+func (q *Queries) GetChirpAncestors(ctx context.Context, arg GetChirpAncestorsParams) ([]GetChirpAncestorsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpAncestors, arg.ViewerID, arg.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpAncestorsRow
+	for rows.Next() {
+		var i GetChirpAncestorsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Body,
+			&i.UserID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.InReplyTo,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.PublishAt,
+			&i.ContentWarning,
+			&i.Sensitive,
+			&i.Visibility,
+			&i.IsChirpyRed,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpAuthor = `-- name: GetChirpAuthor :one
+SELECT users.id, users.protected FROM chirps
+INNER JOIN users ON chirps.user_id = users.id
+WHERE chirps.id = $1
+`
+
+type GetChirpAuthorRow struct {
+	ID        uuid.UUID
+	Protected bool
+}
+
+func (q *Queries) GetChirpAuthor(ctx context.Context, id uuid.UUID) (GetChirpAuthorRow, error) {
+	row := q.db.QueryRowContext(ctx, getChirpAuthor, id)
+	var i GetChirpAuthorRow
+	err := row.Scan(&i.ID, &i.Protected)
+	return i, err
+}
+
+const getChirpDescendants = `-- name: GetChirpDescendants :many
+WITH RECURSIVE descendants AS (
+	SELECT chirps.id, 1 AS depth, (TO_CHAR(chirps.created_at, 'YYYYMMDDHH24MISSUS') || chirps.id::text)::text AS path FROM chirps
+	WHERE chirps.in_reply_to = $5::uuid
+	UNION ALL
+	SELECT chirps.id, descendants.depth + 1, (descendants.path || '/' || TO_CHAR(chirps.created_at, 'YYYYMMDDHH24MISSUS') || chirps.id::text)::text FROM chirps
+	INNER JOIN descendants ON chirps.in_reply_to = descendants.id
+	WHERE descendants.depth < $6::int
+	AND (
+		$3::text IS NULL
+		OR descendants.path COLLATE "C" >= LEFT($3::text, LENGTH(descendants.path))
+	)
+)
+SELECT chirps.id, chirps.body, chirps.user_id, chirps.created_at, chirps.updated_at, chirps.in_reply_to, chirps.rechirp_of, chirps.quote_of, chirps.publish_at, chirps.content_warning, (chirps.sensitive OR chirps.sensitive_by_moderator)::boolean AS sensitive, chirps.visibility, users.is_chirpy_red, descendants.depth::int AS depth, descendants.path FROM descendants
+INNER JOIN chirps ON descendants.id = chirps.id
+INNER JOIN users ON chirps.user_id = users.id
+WHERE chirp_visible_to(chirps.id, $1::uuid)
+AND NOT chirp_muted_for(chirps.id, $1::uuid)
+AND (NOT $2::boolean OR NOT chirp_sensitive(chirps.id))
+AND (
+	$3::text IS NULL
+	OR descendants.path COLLATE "C" > $3::text
+)
+ORDER BY descendants.path COLLATE "C"
+LIMIT $4
+`
+
+type GetChirpDescendantsParams struct {
+	ViewerID      uuid.NullUUID
+	HideSensitive bool
+	CursorPath    sql.NullString
+	RowLimit      int32
+	ID            uuid.UUID
+	MaxDepth      int32
+}
+
+type GetChirpDescendantsRow struct {
+	ID             uuid.UUID
+	Body           string
+	UserID         uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	InReplyTo      uuid.NullUUID
+	RechirpOf      uuid.NullUUID
+	QuoteOf        uuid.NullUUID
+	PublishAt      sql.NullTime
+	ContentWarning sql.NullString
+	Sensitive      bool
+	Visibility     string
+	IsChirpyRed    bool
+	Depth          int32
+	Path           string
+}
+
+// Paths are built from fixed width timestamps and ids so sorting them walks
+// the reply tree depth first, oldest reply first at every level. A page goes
+// on from the last path served, and branches that sort wholly before it are
+// never walked
+func (q *Queries) GetChirpDescendants(ctx context.Context, arg GetChirpDescendantsParams) ([]GetChirpDescendantsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpDescendants,
+		arg.ViewerID,
+		arg.HideSensitive,
+		arg.CursorPath,
+		arg.RowLimit,
+		arg.ID,
+		arg.MaxDepth,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpDescendantsRow
+	for rows.Next() {
+		var i GetChirpDescendantsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Body,
+			&i.UserID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.InReplyTo,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.PublishAt,
+			&i.ContentWarning,
+			&i.Sensitive,
+			&i.Visibility,
+			&i.IsChirpyRed,
+			&i.Depth,
+			&i.Path,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpForModeration = `-- name: GetChirpForModeration :one
+SELECT chirps.id, chirps.body, chirps.user_id, chirps.created_at, chirps.updated_at, chirps.in_reply_to, chirps.rechirp_of, chirps.quote_of, chirps.publish_at, chirps.content_warning, (chirps.sensitive OR chirps.sensitive_by_moderator)::boolean AS sensitive, chirps.visibility, chirps.deleted_at, users.is_chirpy_red FROM chirps
+INNER JOIN users ON chirps.user_id = users.id
+WHERE chirps.id = $1
+`
+
+type GetChirpForModerationRow struct {
+	ID             uuid.UUID
+	Body           string
+	UserID         uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	InReplyTo      uuid.NullUUID
+	RechirpOf      uuid.NullUUID
+	QuoteOf        uuid.NullUUID
+	PublishAt      sql.NullTime
+	ContentWarning sql.NullString
+	Sensitive      bool
+	Visibility     string
+	DeletedAt      sql.NullTime
+	IsChirpyRed    bool
+}
+
+// Moderators see chirps in any state, deleted and scheduled ones included
+func (q *Queries) GetChirpForModeration(ctx context.Context, id uuid.UUID) (GetChirpForModerationRow, error) {
+	row := q.db.QueryRowContext(ctx, getChirpForModeration, id)
+	var i GetChirpForModerationRow
+	err := row.Scan(
+		&i.ID,
+		&i.Body,
+		&i.UserID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.InReplyTo,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.PublishAt,
+		&i.ContentWarning,
+		&i.Sensitive,
+		&i.Visibility,
+		&i.DeletedAt,
+		&i.IsChirpyRed,
+	)
+	return i, err
+}
+
+const getChirpsByIDs = `-- name: GetChirpsByIDs :many
+SELECT chirps.id, chirps.body, chirps.user_id, chirps.created_at, chirps.updated_at, chirps.in_reply_to, chirps.rechirp_of, chirps.quote_of, chirps.publish_at, chirps.content_warning, (chirps.sensitive OR chirps.sensitive_by_moderator)::boolean AS sensitive, chirps.visibility, users.is_chirpy_red FROM chirps
 INNER JOIN users ON chirps.user_id = users.id
-ORDER BY chirps.created_at ASC
+WHERE chirps.id = ANY($1::uuid[])
+AND chirp_visible_to(chirps.id, $2::uuid)
 `
 
-type GetAllChirpsRow struct {
-	ID          uuid.UUID
-	Body        string
-	UserID      uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	IsChirpyRed bool
+type GetChirpsByIDsParams struct {
+	Ids      []uuid.UUID
+	ViewerID uuid.NullUUID
 }
 
-func (q *Queries) GetAllChirps(ctx context.Context) ([]GetAllChirpsRow, error) {
-	rows, err := q.db.QueryContext(ctx, getAllChirps)
+type GetChirpsByIDsRow struct {
+	ID             uuid.UUID
+	Body           string
+	UserID         uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	InReplyTo      uuid.NullUUID
+	RechirpOf      uuid.NullUUID
+	QuoteOf        uuid.NullUUID
+	PublishAt      sql.NullTime
+	ContentWarning sql.NullString
+	Sensitive      bool
+	Visibility     string
+	IsChirpyRed    bool
+}
+
+func (q *Queries) GetChirpsByIDs(ctx context.Context, arg GetChirpsByIDsParams) ([]GetChirpsByIDsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByIDs, pq.Array(arg.Ids), arg.ViewerID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetAllChirpsRow
+	var items []GetChirpsByIDsRow
 	for rows.Next() {
-		var i GetAllChirpsRow
+		var i GetChirpsByIDsRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.Body,
 			&i.UserID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.InReplyTo,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.PublishAt,
+			&i.ContentWarning,
+			&i.Sensitive,
+			&i.Visibility,
 			&i.IsChirpyRed,
 		); err != nil {
 			return nil, err
@@ -110,22 +541,35 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]GetAllChirpsRow, error) {
 }
 
 const getOneChirp = `-- name: GetOneChirp :one
-SELECT chirps.id, chirps.body, chirps.user_id, chirps.created_at, chirps.updated_at, users.is_chirpy_red FROM chirps
+SELECT chirps.id, chirps.body, chirps.user_id, chirps.created_at, chirps.updated_at, chirps.in_reply_to, chirps.rechirp_of, chirps.quote_of, chirps.publish_at, chirps.content_warning, (chirps.sensitive OR chirps.sensitive_by_moderator)::boolean AS sensitive, chirps.visibility, users.is_chirpy_red FROM chirps
 INNER JOIN users ON chirps.user_id = users.id
 WHERE chirps.id = $1
+AND chirp_visible_to(chirps.id, $2::uuid)
 `
 
+type GetOneChirpParams struct {
+	ID       uuid.UUID
+	ViewerID uuid.NullUUID
+}
+
 type GetOneChirpRow struct {
-	ID          uuid.UUID
-	Body        string
-	UserID      uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	IsChirpyRed bool
+	ID             uuid.UUID
+	Body           string
+	UserID         uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	InReplyTo      uuid.NullUUID
+	RechirpOf      uuid.NullUUID
+	QuoteOf        uuid.NullUUID
+	PublishAt      sql.NullTime
+	ContentWarning sql.NullString
+	Sensitive      bool
+	Visibility     string
+	IsChirpyRed    bool
 }
 
-func (q *Queries) GetOneChirp(ctx context.Context, id uuid.UUID) (GetOneChirpRow, error) {
-	row := q.db.QueryRowContext(ctx, getOneChirp, id)
+func (q *Queries) GetOneChirp(ctx context.Context, arg GetOneChirpParams) (GetOneChirpRow, error) {
+	row := q.db.QueryRowContext(ctx, getOneChirp, arg.ID, arg.ViewerID)
 	var i GetOneChirpRow
 	err := row.Scan(
 		&i.ID,
@@ -133,6 +577,1159 @@ func (q *Queries) GetOneChirp(ctx context.Context, id uuid.UUID) (GetOneChirpRow
 		&i.UserID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.InReplyTo,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.PublishAt,
+		&i.ContentWarning,
+		&i.Sensitive,
+		&i.Visibility,
+		&i.IsChirpyRed,
+	)
+	return i, err
+}
+
+const getRechirpCounts = `-- name: GetRechirpCounts :one
+SELECT
+	(SELECT COUNT(*) FROM chirps WHERE chirps.rechirp_of = $1::uuid AND chirps.deleted_at IS NULL) AS rechirp_count,
+	(SELECT COUNT(*) FROM chirps WHERE chirps.quote_of = $1::uuid AND chirps.deleted_at IS NULL) AS quote_count
+`
+
+type GetRechirpCountsRow struct {
+	RechirpCount int64
+	QuoteCount   int64
+}
+
+func (q *Queries) GetRechirpCounts(ctx context.Context, id uuid.UUID) (GetRechirpCountsRow, error) {
+	row := q.db.QueryRowContext(ctx, getRechirpCounts, id)
+	var i GetRechirpCountsRow
+	err := row.Scan(&i.RechirpCount, &i.QuoteCount)
+	return i, err
+}
+
+const listChirpsByCreatedAtAsc = `-- name: ListChirpsByCreatedAtAsc :many
+SELECT chirps.id, chirps.body, chirps.user_id, chirps.created_at, chirps.updated_at, chirps.in_reply_to, chirps.rechirp_of, chirps.quote_of, chirps.publish_at, chirps.content_warning, (chirps.sensitive OR chirps.sensitive_by_moderator)::boolean AS sensitive, chirps.visibility, users.is_chirpy_red FROM chirps
+INNER JOIN users ON chirps.user_id = users.id
+WHERE chirp_listable(
+	chirps, users.is_chirpy_red, $1::uuid, $2::uuid[],
+	$3::timestamp, $4::timestamp, $5::boolean,
+	$6::boolean, $7::boolean, $8::boolean
+)
+AND (
+	$9::timestamp IS NULL
+	OR (chirps.created_at, chirps.id) > ($9::timestamp, $10::uuid)
+)
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT $11
+`
+
+type ListChirpsByCreatedAtAscParams struct {
+	ViewerID      uuid.NullUUID
+	AuthorIds     []uuid.UUID
+	Since         sql.NullTime
+	Until         sql.NullTime
+	IsChirpyRed   sql.NullBool
+	HasMedia      sql.NullBool
+	ExcludePinned bool
+	HideSensitive bool
+	CursorTime    sql.NullTime
+	CursorID      uuid.NullUUID
+	RowLimit      int32
+}
+
+type ListChirpsByCreatedAtAscRow struct {
+	ID             uuid.UUID
+	Body           string
+	UserID         uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	InReplyTo      uuid.NullUUID
+	RechirpOf      uuid.NullUUID
+	QuoteOf        uuid.NullUUID
+	PublishAt      sql.NullTime
+	ContentWarning sql.NullString
+	Sensitive      bool
+	Visibility     string
+	IsChirpyRed    bool
+}
+
+func (q *Queries) ListChirpsByCreatedAtAsc(ctx context.Context, arg ListChirpsByCreatedAtAscParams) ([]ListChirpsByCreatedAtAscRow, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsByCreatedAtAsc,
+		arg.ViewerID,
+		pq.Array(arg.AuthorIds),
+		arg.Since,
+		arg.Until,
+		arg.IsChirpyRed,
+		arg.HasMedia,
+		arg.ExcludePinned,
+		arg.HideSensitive,
+		arg.CursorTime,
+		arg.CursorID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListChirpsByCreatedAtAscRow
+	for rows.Next() {
+		var i ListChirpsByCreatedAtAscRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Body,
+			&i.UserID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.InReplyTo,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.PublishAt,
+			&i.ContentWarning,
+			&i.Sensitive,
+			&i.Visibility,
+			&i.IsChirpyRed,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirpsByCreatedAtDesc = `-- name: ListChirpsByCreatedAtDesc :many
+SELECT chirps.id, chirps.body, chirps.user_id, chirps.created_at, chirps.updated_at, chirps.in_reply_to, chirps.rechirp_of, chirps.quote_of, chirps.publish_at, chirps.content_warning, (chirps.sensitive OR chirps.sensitive_by_moderator)::boolean AS sensitive, chirps.visibility, users.is_chirpy_red FROM chirps
+INNER JOIN users ON chirps.user_id = users.id
+WHERE chirp_listable(
+	chirps, users.is_chirpy_red, $1::uuid, $2::uuid[],
+	$3::timestamp, $4::timestamp, $5::boolean,
+	$6::boolean, $7::boolean, $8::boolean
+)
+AND (
+	$9::timestamp IS NULL
+	OR (chirps.created_at, chirps.id) < ($9::timestamp, $10::uuid)
+)
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT $11
+`
+
+type ListChirpsByCreatedAtDescParams struct {
+	ViewerID      uuid.NullUUID
+	AuthorIds     []uuid.UUID
+	Since         sql.NullTime
+	Until         sql.NullTime
+	IsChirpyRed   sql.NullBool
+	HasMedia      sql.NullBool
+	ExcludePinned bool
+	HideSensitive bool
+	CursorTime    sql.NullTime
+	CursorID      uuid.NullUUID
+	RowLimit      int32
+}
+
+type ListChirpsByCreatedAtDescRow struct {
+	ID             uuid.UUID
+	Body           string
+	UserID         uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	InReplyTo      uuid.NullUUID
+	RechirpOf      uuid.NullUUID
+	QuoteOf        uuid.NullUUID
+	PublishAt      sql.NullTime
+	ContentWarning sql.NullString
+	Sensitive      bool
+	Visibility     string
+	IsChirpyRed    bool
+}
+
+// Listing chirps takes one query per sort key and direction so that the
+// ordering and cursor are plain column comparisons the (created_at, id) and
+// (updated_at, id) indexes can serve. The filters they share live in
+// chirp_listable, so the four only differ in those clauses
+func (q *Queries) ListChirpsByCreatedAtDesc(ctx context.Context, arg ListChirpsByCreatedAtDescParams) ([]ListChirpsByCreatedAtDescRow, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsByCreatedAtDesc,
+		arg.ViewerID,
+		pq.Array(arg.AuthorIds),
+		arg.Since,
+		arg.Until,
+		arg.IsChirpyRed,
+		arg.HasMedia,
+		arg.ExcludePinned,
+		arg.HideSensitive,
+		arg.CursorTime,
+		arg.CursorID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListChirpsByCreatedAtDescRow
+	for rows.Next() {
+		var i ListChirpsByCreatedAtDescRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Body,
+			&i.UserID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.InReplyTo,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.PublishAt,
+			&i.ContentWarning,
+			&i.Sensitive,
+			&i.Visibility,
+			&i.IsChirpyRed,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirpsByUpdatedAtAsc = `-- name: ListChirpsByUpdatedAtAsc :many
+SELECT chirps.id, chirps.body, chirps.user_id, chirps.created_at, chirps.updated_at, chirps.in_reply_to, chirps.rechirp_of, chirps.quote_of, chirps.publish_at, chirps.content_warning, (chirps.sensitive OR chirps.sensitive_by_moderator)::boolean AS sensitive, chirps.visibility, users.is_chirpy_red FROM chirps
+INNER JOIN users ON chirps.user_id = users.id
+WHERE chirp_listable(
+	chirps, users.is_chirpy_red, $1::uuid, $2::uuid[],
+	$3::timestamp, $4::timestamp, $5::boolean,
+	$6::boolean, $7::boolean, $8::boolean
+)
+AND (
+	$9::timestamp IS NULL
+	OR (chirps.updated_at, chirps.id) > ($9::timestamp, $10::uuid)
+)
+ORDER BY chirps.updated_at ASC, chirps.id ASC
+LIMIT $11
+`
+
+type ListChirpsByUpdatedAtAscParams struct {
+	ViewerID      uuid.NullUUID
+	AuthorIds     []uuid.UUID
+	Since         sql.NullTime
+	Until         sql.NullTime
+	IsChirpyRed   sql.NullBool
+	HasMedia      sql.NullBool
+	ExcludePinned bool
+	HideSensitive bool
+	CursorTime    sql.NullTime
+	CursorID      uuid.NullUUID
+	RowLimit      int32
+}
+
+type ListChirpsByUpdatedAtAscRow struct {
+	ID             uuid.UUID
+	Body           string
+	UserID         uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	InReplyTo      uuid.NullUUID
+	RechirpOf      uuid.NullUUID
+	QuoteOf        uuid.NullUUID
+	PublishAt      sql.NullTime
+	ContentWarning sql.NullString
+	Sensitive      bool
+	Visibility     string
+	IsChirpyRed    bool
+}
+
+func (q *Queries) ListChirpsByUpdatedAtAsc(ctx context.Context, arg ListChirpsByUpdatedAtAscParams) ([]ListChirpsByUpdatedAtAscRow, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsByUpdatedAtAsc,
+		arg.ViewerID,
+		pq.Array(arg.AuthorIds),
+		arg.Since,
+		arg.Until,
+		arg.IsChirpyRed,
+		arg.HasMedia,
+		arg.ExcludePinned,
+		arg.HideSensitive,
+		arg.CursorTime,
+		arg.CursorID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListChirpsByUpdatedAtAscRow
+	for rows.Next() {
+		var i ListChirpsByUpdatedAtAscRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Body,
+			&i.UserID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.InReplyTo,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.PublishAt,
+			&i.ContentWarning,
+			&i.Sensitive,
+			&i.Visibility,
+			&i.IsChirpyRed,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirpsByUpdatedAtDesc = `-- name: ListChirpsByUpdatedAtDesc :many
+SELECT chirps.id, chirps.body, chirps.user_id, chirps.created_at, chirps.updated_at, chirps.in_reply_to, chirps.rechirp_of, chirps.quote_of, chirps.publish_at, chirps.content_warning, (chirps.sensitive OR chirps.sensitive_by_moderator)::boolean AS sensitive, chirps.visibility, users.is_chirpy_red FROM chirps
+INNER JOIN users ON chirps.user_id = users.id
+WHERE chirp_listable(
+	chirps, users.is_chirpy_red, $1::uuid, $2::uuid[],
+	$3::timestamp, $4::timestamp, $5::boolean,
+	$6::boolean, $7::boolean, $8::boolean
+)
+AND (
+	$9::timestamp IS NULL
+	OR (chirps.updated_at, chirps.id) < ($9::timestamp, $10::uuid)
+)
+ORDER BY chirps.updated_at DESC, chirps.id DESC
+LIMIT $11
+`
+
+type ListChirpsByUpdatedAtDescParams struct {
+	ViewerID      uuid.NullUUID
+	AuthorIds     []uuid.UUID
+	Since         sql.NullTime
+	Until         sql.NullTime
+	IsChirpyRed   sql.NullBool
+	HasMedia      sql.NullBool
+	ExcludePinned bool
+	HideSensitive bool
+	CursorTime    sql.NullTime
+	CursorID      uuid.NullUUID
+	RowLimit      int32
+}
+
+type ListChirpsByUpdatedAtDescRow struct {
+	ID             uuid.UUID
+	Body           string
+	UserID         uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	InReplyTo      uuid.NullUUID
+	RechirpOf      uuid.NullUUID
+	QuoteOf        uuid.NullUUID
+	PublishAt      sql.NullTime
+	ContentWarning sql.NullString
+	Sensitive      bool
+	Visibility     string
+	IsChirpyRed    bool
+}
+
+func (q *Queries) ListChirpsByUpdatedAtDesc(ctx context.Context, arg ListChirpsByUpdatedAtDescParams) ([]ListChirpsByUpdatedAtDescRow, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsByUpdatedAtDesc,
+		arg.ViewerID,
+		pq.Array(arg.AuthorIds),
+		arg.Since,
+		arg.Until,
+		arg.IsChirpyRed,
+		arg.HasMedia,
+		arg.ExcludePinned,
+		arg.HideSensitive,
+		arg.CursorTime,
+		arg.CursorID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListChirpsByUpdatedAtDescRow
+	for rows.Next() {
+		var i ListChirpsByUpdatedAtDescRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Body,
+			&i.UserID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.InReplyTo,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.PublishAt,
+			&i.ContentWarning,
+			&i.Sensitive,
+			&i.Visibility,
+			&i.IsChirpyRed,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listHashtagChirps = `-- name: ListHashtagChirps :many
+SELECT chirps.id, chirps.body, chirps.user_id, chirps.created_at, chirps.updated_at, chirps.in_reply_to, chirps.rechirp_of, chirps.quote_of, chirps.publish_at, chirps.content_warning, (chirps.sensitive OR chirps.sensitive_by_moderator)::boolean AS sensitive, chirps.visibility, users.is_chirpy_red FROM chirps
+INNER JOIN users ON chirps.user_id = users.id
+WHERE EXISTS (
+	SELECT 1 FROM chirp_hashtags
+	WHERE chirp_hashtags.chirp_id = chirps.id AND chirp_hashtags.tag = $1
+)
+AND chirp_visible_to(chirps.id, $2::uuid)
+AND NOT chirp_muted_for(chirps.id, $2::uuid)
+AND (chirps.visibility <> 'unlisted' OR chirps.user_id = $2::uuid)
+AND (NOT $3::boolean OR NOT chirp_sensitive(chirps.id))
+AND (
+	$4::timestamp IS NULL
+	OR (chirps.created_at, chirps.id) < ($4::timestamp, $5::uuid)
+)
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT $6
+`
+
+type ListHashtagChirpsParams struct {
+	Tag           string
+	ViewerID      uuid.NullUUID
+	HideSensitive bool
+	CursorTime    sql.NullTime
+	CursorID      uuid.NullUUID
+	RowLimit      int32
+}
+
+type ListHashtagChirpsRow struct {
+	ID             uuid.UUID
+	Body           string
+	UserID         uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	InReplyTo      uuid.NullUUID
+	RechirpOf      uuid.NullUUID
+	QuoteOf        uuid.NullUUID
+	PublishAt      sql.NullTime
+	ContentWarning sql.NullString
+	Sensitive      bool
+	Visibility     string
+	IsChirpyRed    bool
+}
+
+func (q *Queries) ListHashtagChirps(ctx context.Context, arg ListHashtagChirpsParams) ([]ListHashtagChirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listHashtagChirps,
+		arg.Tag,
+		arg.ViewerID,
+		arg.HideSensitive,
+		arg.CursorTime,
+		arg.CursorID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListHashtagChirpsRow
+	for rows.Next() {
+		var i ListHashtagChirpsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Body,
+			&i.UserID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.InReplyTo,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.PublishAt,
+			&i.ContentWarning,
+			&i.Sensitive,
+			&i.Visibility,
+			&i.IsChirpyRed,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPinnedChirps = `-- name: ListPinnedChirps :many
+SELECT chirps.id, chirps.body, chirps.user_id, chirps.created_at, chirps.updated_at, chirps.in_reply_to, chirps.rechirp_of, chirps.quote_of, chirps.publish_at, chirps.content_warning, (chirps.sensitive OR chirps.sensitive_by_moderator)::boolean AS sensitive, chirps.visibility, users.is_chirpy_red FROM chirps
+INNER JOIN users ON chirps.user_id = users.id
+WHERE chirps.user_id = $1 AND chirps.pinned_at IS NOT NULL
+AND chirp_visible_to(chirps.id, $2::uuid)
+AND NOT chirp_muted_for(chirps.id, $2::uuid)
+AND (NOT $3::boolean OR NOT chirp_sensitive(chirps.id))
+ORDER BY chirps.pinned_at DESC, chirps.id DESC
+`
+
+type ListPinnedChirpsParams struct {
+	UserID        uuid.UUID
+	ViewerID      uuid.NullUUID
+	HideSensitive bool
+}
+
+type ListPinnedChirpsRow struct {
+	ID             uuid.UUID
+	Body           string
+	UserID         uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	InReplyTo      uuid.NullUUID
+	RechirpOf      uuid.NullUUID
+	QuoteOf        uuid.NullUUID
+	PublishAt      sql.NullTime
+	ContentWarning sql.NullString
+	Sensitive      bool
+	Visibility     string
+	IsChirpyRed    bool
+}
+
+func (q *Queries) ListPinnedChirps(ctx context.Context, arg ListPinnedChirpsParams) ([]ListPinnedChirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listPinnedChirps, arg.UserID, arg.ViewerID, arg.HideSensitive)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListPinnedChirpsRow
+	for rows.Next() {
+		var i ListPinnedChirpsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Body,
+			&i.UserID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.InReplyTo,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.PublishAt,
+			&i.ContentWarning,
+			&i.Sensitive,
+			&i.Visibility,
+			&i.IsChirpyRed,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listScheduledChirps = `-- name: ListScheduledChirps :many
+SELECT chirps.id, chirps.body, chirps.user_id, chirps.created_at, chirps.updated_at, chirps.in_reply_to, chirps.rechirp_of, chirps.quote_of, chirps.publish_at, chirps.content_warning, (chirps.sensitive OR chirps.sensitive_by_moderator)::boolean AS sensitive, chirps.visibility, users.is_chirpy_red FROM chirps
+INNER JOIN users ON chirps.user_id = users.id
+WHERE chirps.user_id = $1 AND chirps.publish_at IS NOT NULL AND chirps.deleted_at IS NULL
+AND (
+	$2::timestamp IS NULL
+	OR (chirps.publish_at, chirps.id) > ($2::timestamp, $3::uuid)
+)
+ORDER BY chirps.publish_at ASC, chirps.id ASC
+LIMIT $4
+`
+
+type ListScheduledChirpsParams struct {
+	UserID     uuid.UUID
+	CursorTime sql.NullTime
+	CursorID   uuid.NullUUID
+	RowLimit   int32
+}
+
+type ListScheduledChirpsRow struct {
+	ID             uuid.UUID
+	Body           string
+	UserID         uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	InReplyTo      uuid.NullUUID
+	RechirpOf      uuid.NullUUID
+	QuoteOf        uuid.NullUUID
+	PublishAt      sql.NullTime
+	ContentWarning sql.NullString
+	Sensitive      bool
+	Visibility     string
+	IsChirpyRed    bool
+}
+
+func (q *Queries) ListScheduledChirps(ctx context.Context, arg ListScheduledChirpsParams) ([]ListScheduledChirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listScheduledChirps,
+		arg.UserID,
+		arg.CursorTime,
+		arg.CursorID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListScheduledChirpsRow
+	for rows.Next() {
+		var i ListScheduledChirpsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Body,
+			&i.UserID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.InReplyTo,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.PublishAt,
+			&i.ContentWarning,
+			&i.Sensitive,
+			&i.Visibility,
+			&i.IsChirpyRed,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTrashedChirps = `-- name: ListTrashedChirps :many
+SELECT chirps.id, chirps.body, chirps.user_id, chirps.created_at, chirps.updated_at, chirps.in_reply_to, chirps.rechirp_of, chirps.quote_of, chirps.publish_at, chirps.content_warning, (chirps.sensitive OR chirps.sensitive_by_moderator)::boolean AS sensitive, chirps.visibility, chirps.deleted_at::timestamp AS deleted_at, users.is_chirpy_red FROM chirps
+INNER JOIN users ON chirps.user_id = users.id
+WHERE chirps.user_id = $1 AND chirps.deleted_at >= $2::timestamp
+AND (
+	$3::timestamp IS NULL
+	OR (chirps.deleted_at, chirps.id) < ($3::timestamp, $4::uuid)
+)
+ORDER BY chirps.deleted_at DESC, chirps.id DESC
+LIMIT $5
+`
+
+type ListTrashedChirpsParams struct {
+	UserID       uuid.UUID
+	DeletedSince time.Time
+	CursorTime   sql.NullTime
+	CursorID     uuid.NullUUID
+	RowLimit     int32
+}
+
+type ListTrashedChirpsRow struct {
+	ID             uuid.UUID
+	Body           string
+	UserID         uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	InReplyTo      uuid.NullUUID
+	RechirpOf      uuid.NullUUID
+	QuoteOf        uuid.NullUUID
+	PublishAt      sql.NullTime
+	ContentWarning sql.NullString
+	Sensitive      bool
+	Visibility     string
+	DeletedAt      time.Time
+	IsChirpyRed    bool
+}
+
+func (q *Queries) ListTrashedChirps(ctx context.Context, arg ListTrashedChirpsParams) ([]ListTrashedChirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listTrashedChirps,
+		arg.UserID,
+		arg.DeletedSince,
+		arg.CursorTime,
+		arg.CursorID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListTrashedChirpsRow
+	for rows.Next() {
+		var i ListTrashedChirpsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Body,
+			&i.UserID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.InReplyTo,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.PublishAt,
+			&i.ContentWarning,
+			&i.Sensitive,
+			&i.Visibility,
+			&i.DeletedAt,
+			&i.IsChirpyRed,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const pinChirp = `-- name: PinChirp :exec
+UPDATE chirps SET pinned_at = NOW() WHERE id = $1 AND pinned_at IS NULL
+`
+
+func (q *Queries) PinChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, pinChirp, id)
+	return err
+}
+
+const publishDueChirps = `-- name: PublishDueChirps :many
+WITH chirppublish AS (
+	UPDATE chirps SET created_at = chirps.publish_at, updated_at = chirps.publish_at, publish_at = NULL
+	WHERE chirps.id IN (
+		SELECT duechirps.id FROM chirps AS duechirps
+		WHERE duechirps.publish_at <= NOW() AND duechirps.deleted_at IS NULL
+		ORDER BY duechirps.publish_at ASC
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING id, body, user_id, created_at, updated_at, in_reply_to, rechirp_of, quote_of, publish_at, content_warning, (sensitive OR sensitive_by_moderator)::boolean AS sensitive, visibility
+)
+SELECT chirppublish.id, chirppublish.body, chirppublish.user_id, chirppublish.created_at, chirppublish.updated_at, chirppublish.in_reply_to, chirppublish.rechirp_of, chirppublish.quote_of, chirppublish.publish_at, chirppublish.content_warning, chirppublish.sensitive, chirppublish.visibility, users.is_chirpy_red, users.protected FROM chirppublish
+INNER JOIN users ON chirppublish.user_id = users.id
+`
+
+type PublishDueChirpsRow struct {
+	ID             uuid.UUID
+	Body           string
+	UserID         uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	InReplyTo      uuid.NullUUID
+	RechirpOf      uuid.NullUUID
+	QuoteOf        uuid.NullUUID
+	PublishAt      sql.NullTime
+	ContentWarning sql.NullString
+	Sensitive      bool
+	Visibility     string
+	IsChirpyRed    bool
+	Protected      bool
+}
+
+// SKIP LOCKED lets several schedulers run at once, each due chirp is claimed
+// by exactly one of them and clearing publish_at means it is never claimed again
+func (q *Queries) PublishDueChirps(ctx context.Context, rowLimit int32) ([]PublishDueChirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, publishDueChirps, rowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PublishDueChirpsRow
+	for rows.Next() {
+		var i PublishDueChirpsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Body,
+			&i.UserID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.InReplyTo,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.PublishAt,
+			&i.ContentWarning,
+			&i.Sensitive,
+			&i.Visibility,
+			&i.IsChirpyRed,
+			&i.Protected,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const purgeChirps = `-- name: PurgeChirps :exec
+DELETE FROM chirps WHERE id = ANY($1::uuid[])
+`
+
+func (q *Queries) PurgeChirps(ctx context.Context, ids []uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, purgeChirps, pq.Array(ids))
+	return err
+}
+
+const rescheduleChirp = `-- name: RescheduleChirp :one
+WITH chirpreschedule AS (
+	UPDATE chirps SET publish_at = $1::timestamp
+	WHERE chirps.id = $2 AND chirps.user_id = $3 AND chirps.publish_at IS NOT NULL AND chirps.deleted_at IS NULL
+	RETURNING id, body, user_id, created_at, updated_at, in_reply_to, rechirp_of, quote_of, publish_at, content_warning, (sensitive OR sensitive_by_moderator)::boolean AS sensitive, visibility
+)
+SELECT chirpreschedule.id, chirpreschedule.body, chirpreschedule.user_id, chirpreschedule.created_at, chirpreschedule.updated_at, chirpreschedule.in_reply_to, chirpreschedule.rechirp_of, chirpreschedule.quote_of, chirpreschedule.publish_at, chirpreschedule.content_warning, chirpreschedule.sensitive, chirpreschedule.visibility, users.is_chirpy_red FROM chirpreschedule
+INNER JOIN users ON chirpreschedule.user_id = users.id
+`
+
+type RescheduleChirpParams struct {
+	PublishAt time.Time
+	ID        uuid.UUID
+	UserID    uuid.UUID
+}
+
+type RescheduleChirpRow struct {
+	ID             uuid.UUID
+	Body           string
+	UserID         uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	InReplyTo      uuid.NullUUID
+	RechirpOf      uuid.NullUUID
+	QuoteOf        uuid.NullUUID
+	PublishAt      sql.NullTime
+	ContentWarning sql.NullString
+	Sensitive      bool
+	Visibility     string
+	IsChirpyRed    bool
+}
+
+func (q *Queries) RescheduleChirp(ctx context.Context, arg RescheduleChirpParams) (RescheduleChirpRow, error) {
+	row := q.db.QueryRowContext(ctx, rescheduleChirp, arg.PublishAt, arg.ID, arg.UserID)
+	var i RescheduleChirpRow
+	err := row.Scan(
+		&i.ID,
+		&i.Body,
+		&i.UserID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.InReplyTo,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.PublishAt,
+		&i.ContentWarning,
+		&i.Sensitive,
+		&i.Visibility,
+		&i.IsChirpyRed,
+	)
+	return i, err
+}
+
+const restoreChirp = `-- name: RestoreChirp :one
+WITH chirprestore AS (
+	UPDATE chirps SET deleted_at = NULL
+	WHERE chirps.id = $1 AND chirps.user_id = $2
+	AND chirps.deleted_at >= $3::timestamp
+	RETURNING id, body, user_id, created_at, updated_at, in_reply_to, rechirp_of, quote_of, publish_at, content_warning, (sensitive OR sensitive_by_moderator)::boolean AS sensitive, visibility
+)
+SELECT chirprestore.id, chirprestore.body, chirprestore.user_id, chirprestore.created_at, chirprestore.updated_at, chirprestore.in_reply_to, chirprestore.rechirp_of, chirprestore.quote_of, chirprestore.publish_at, chirprestore.content_warning, chirprestore.sensitive, chirprestore.visibility, users.is_chirpy_red FROM chirprestore
+INNER JOIN users ON chirprestore.user_id = users.id
+`
+
+type RestoreChirpParams struct {
+	ID           uuid.UUID
+	UserID       uuid.UUID
+	DeletedSince time.Time
+}
+
+type RestoreChirpRow struct {
+	ID             uuid.UUID
+	Body           string
+	UserID         uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	InReplyTo      uuid.NullUUID
+	RechirpOf      uuid.NullUUID
+	QuoteOf        uuid.NullUUID
+	PublishAt      sql.NullTime
+	ContentWarning sql.NullString
+	Sensitive      bool
+	Visibility     string
+	IsChirpyRed    bool
+}
+
+func (q *Queries) RestoreChirp(ctx context.Context, arg RestoreChirpParams) (RestoreChirpRow, error) {
+	row := q.db.QueryRowContext(ctx, restoreChirp, arg.ID, arg.UserID, arg.DeletedSince)
+	var i RestoreChirpRow
+	err := row.Scan(
+		&i.ID,
+		&i.Body,
+		&i.UserID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.InReplyTo,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.PublishAt,
+		&i.ContentWarning,
+		&i.Sensitive,
+		&i.Visibility,
+		&i.IsChirpyRed,
+	)
+	return i, err
+}
+
+const searchChirps = `-- name: SearchChirps :many
+SELECT
+	chirps.id, chirps.body, chirps.user_id, chirps.created_at, chirps.updated_at, chirps.in_reply_to, chirps.rechirp_of, chirps.quote_of, chirps.publish_at, chirps.content_warning, (chirps.sensitive OR chirps.sensitive_by_moderator)::boolean AS sensitive, chirps.visibility, users.is_chirpy_red,
+	TS_RANK_CD(chirps.search_vector, search_query)::real AS rank,
+	TS_HEADLINE(
+		'english',
+		REPLACE(REPLACE(REPLACE(REPLACE(REPLACE(chirps.body, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'), '''', '&#39;'),
+		search_query,
+		'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MinWords=5, MaxWords=20'
+	)::text AS snippet
+FROM chirps
+INNER JOIN users ON chirps.user_id = users.id
+CROSS JOIN TO_TSQUERY('english', $1::text) AS search_query
+WHERE chirps.search_vector @@ search_query
+AND chirp_visible_to(chirps.id, $2::uuid)
+AND NOT chirp_muted_for(chirps.id, $2::uuid)
+AND (chirps.visibility <> 'unlisted' OR chirps.user_id = $2::uuid)
+AND (CARDINALITY($3::uuid[]) = 0 OR chirps.user_id = ANY($3::uuid[]))
+AND ($4::timestamp IS NULL OR chirps.created_at >= $4::timestamp)
+AND ($5::timestamp IS NULL OR chirps.created_at < $5::timestamp)
+AND ($6::boolean IS NULL OR users.is_chirpy_red = $6::boolean)
+AND (
+	$7::boolean IS NULL
+	OR $7::boolean = EXISTS (SELECT 1 FROM chirp_media WHERE chirp_media.chirp_id = chirps.id)
+)
+AND (NOT $8::boolean OR NOT chirp_sensitive(chirps.id))
+ORDER BY rank DESC, chirps.created_at DESC, chirps.id DESC
+LIMIT $10 OFFSET $9
+`
+
+type SearchChirpsParams struct {
+	SearchQuery   string
+	ViewerID      uuid.NullUUID
+	AuthorIds     []uuid.UUID
+	Since         sql.NullTime
+	Until         sql.NullTime
+	IsChirpyRed   sql.NullBool
+	HasMedia      sql.NullBool
+	HideSensitive bool
+	RowOffset     int32
+	RowLimit      int32
+}
+
+type SearchChirpsRow struct {
+	ID             uuid.UUID
+	Body           string
+	UserID         uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	InReplyTo      uuid.NullUUID
+	RechirpOf      uuid.NullUUID
+	QuoteOf        uuid.NullUUID
+	PublishAt      sql.NullTime
+	ContentWarning sql.NullString
+	Sensitive      bool
+	Visibility     string
+	IsChirpyRed    bool
+	Rank           float32
+	Snippet        string
+}
+
+// The body is HTML escaped before it is highlighted, the <mark> tags around
+// matches are the only markup a snippet can contain
+func (q *Queries) SearchChirps(ctx context.Context, arg SearchChirpsParams) ([]SearchChirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchChirps,
+		arg.SearchQuery,
+		arg.ViewerID,
+		pq.Array(arg.AuthorIds),
+		arg.Since,
+		arg.Until,
+		arg.IsChirpyRed,
+		arg.HasMedia,
+		arg.HideSensitive,
+		arg.RowOffset,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchChirpsRow
+	for rows.Next() {
+		var i SearchChirpsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Body,
+			&i.UserID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.InReplyTo,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.PublishAt,
+			&i.ContentWarning,
+			&i.Sensitive,
+			&i.Visibility,
+			&i.IsChirpyRed,
+			&i.Rank,
+			&i.Snippet,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setChirpSensitiveByModerator = `-- name: SetChirpSensitiveByModerator :execrows
+UPDATE chirps SET sensitive_by_moderator = $2 WHERE id = $1
+`
+
+type SetChirpSensitiveByModeratorParams struct {
+	ID                   uuid.UUID
+	SensitiveByModerator bool
+}
+
+func (q *Queries) SetChirpSensitiveByModerator(ctx context.Context, arg SetChirpSensitiveByModeratorParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setChirpSensitiveByModerator, arg.ID, arg.SensitiveByModerator)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setChirpSensitivity = `-- name: SetChirpSensitivity :execrows
+UPDATE chirps SET content_warning = $1, sensitive = $2
+WHERE id = $3 AND user_id = $4 AND deleted_at IS NULL
+`
+
+type SetChirpSensitivityParams struct {
+	ContentWarning sql.NullString
+	Sensitive      bool
+	ID             uuid.UUID
+	UserID         uuid.UUID
+}
+
+func (q *Queries) SetChirpSensitivity(ctx context.Context, arg SetChirpSensitivityParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setChirpSensitivity,
+		arg.ContentWarning,
+		arg.Sensitive,
+		arg.ID,
+		arg.UserID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const softDeleteChirp = `-- name: SoftDeleteChirp :exec
+UPDATE chirps SET deleted_at = NOW(), pinned_at = NULL WHERE id = $1 AND deleted_at IS NULL
+`
+
+// Deleting a chirp also takes it off its author's pins, so restoring it
+// can't go over the pin limit
+func (q *Queries) SoftDeleteChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, softDeleteChirp, id)
+	return err
+}
+
+const unpinChirp = `-- name: UnpinChirp :exec
+UPDATE chirps SET pinned_at = NULL WHERE id = $1
+`
+
+func (q *Queries) UnpinChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, unpinChirp, id)
+	return err
+}
+
+const updateChirp = `-- name: UpdateChirp :one
+WITH chirprevision AS (
+	INSERT INTO chirp_revisions (id, chirp_id, body, created_at, replaced_at)
+	SELECT GEN_RANDOM_UUID(), chirps.id, chirps.body, chirps.updated_at, NOW() FROM chirps
+	WHERE chirps.id = $1
+), chirpupdate AS (
+	UPDATE chirps SET body = $2, updated_at = NOW()
+	WHERE chirps.id = $1
+	RETURNING id, body, user_id, created_at, updated_at, in_reply_to, rechirp_of, quote_of, publish_at, content_warning, (sensitive OR sensitive_by_moderator)::boolean AS sensitive, visibility
+)
+SELECT chirpupdate.id, chirpupdate.body, chirpupdate.user_id, chirpupdate.created_at, chirpupdate.updated_at, chirpupdate.in_reply_to, chirpupdate.rechirp_of, chirpupdate.quote_of, chirpupdate.publish_at, chirpupdate.content_warning, chirpupdate.sensitive, chirpupdate.visibility, users.is_chirpy_red FROM chirpupdate
+INNER JOIN users ON chirpupdate.user_id = users.id
+`
+
+type UpdateChirpParams struct {
+	ID   uuid.UUID
+	Body string
+}
+
+type UpdateChirpRow struct {
+	ID             uuid.UUID
+	Body           string
+	UserID         uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	InReplyTo      uuid.NullUUID
+	RechirpOf      uuid.NullUUID
+	QuoteOf        uuid.NullUUID
+	PublishAt      sql.NullTime
+	ContentWarning sql.NullString
+	Sensitive      bool
+	Visibility     string
+	IsChirpyRed    bool
+}
+
+func (q *Queries) UpdateChirp(ctx context.Context, arg UpdateChirpParams) (UpdateChirpRow, error) {
+	row := q.db.QueryRowContext(ctx, updateChirp, arg.ID, arg.Body)
+	var i UpdateChirpRow
+	err := row.Scan(
+		&i.ID,
+		&i.Body,
+		&i.UserID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.InReplyTo,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.PublishAt,
+		&i.ContentWarning,
+		&i.Sensitive,
+		&i.Visibility,
 		&i.IsChirpyRed,
 	)
 	return i, err
@@ -0,0 +1,158 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: drafts.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+const createDraft = `-- name: CreateDraft :one
+INSERT INTO drafts (id, user_id, body, created_at, updated_at)
+SELECT GEN_RANDOM_UUID(), $1, $2, NOW(), NOW()
+WHERE (SELECT COUNT(*) FROM drafts WHERE drafts.user_id = $1) < $3::int
+RETURNING id, user_id, body, created_at, updated_at
+`
+
+type CreateDraftParams struct {
+	UserID    uuid.UUID
+	Body      string
+	MaxDrafts int32
+}
+
+// A user already at the draft limit inserts nothing, which comes back as no
+// rows. Callers hold the user's row lock so the count can't go stale
+func (q *Queries) CreateDraft(ctx context.Context, arg CreateDraftParams) (Draft, error) {
+	row := q.db.QueryRowContext(ctx, createDraft, arg.UserID, arg.Body, arg.MaxDrafts)
+	var i Draft
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Body,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const deleteDraft = `-- name: DeleteDraft :execrows
+DELETE FROM drafts WHERE id = $1 AND user_id = $2
+`
+
+type DeleteDraftParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) DeleteDraft(ctx context.Context, arg DeleteDraftParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteDraft, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getDraft = `-- name: GetDraft :one
+SELECT id, user_id, body, created_at, updated_at FROM drafts WHERE id = $1 AND user_id = $2
+`
+
+type GetDraftParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) GetDraft(ctx context.Context, arg GetDraftParams) (Draft, error) {
+	row := q.db.QueryRowContext(ctx, getDraft, arg.ID, arg.UserID)
+	var i Draft
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Body,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const listDrafts = `-- name: ListDrafts :many
+SELECT id, user_id, body, created_at, updated_at FROM drafts
+WHERE user_id = $1
+AND (
+	$2::timestamp IS NULL
+	OR (updated_at, id) < ($2::timestamp, $3::uuid)
+)
+ORDER BY updated_at DESC, id DESC
+LIMIT $4
+`
+
+type ListDraftsParams struct {
+	UserID     uuid.UUID
+	CursorTime sql.NullTime
+	CursorID   uuid.NullUUID
+	RowLimit   int32
+}
+
+// Most recently touched first, that is the draft another device was just on
+func (q *Queries) ListDrafts(ctx context.Context, arg ListDraftsParams) ([]Draft, error) {
+	rows, err := q.db.QueryContext(ctx, listDrafts,
+		arg.UserID,
+		arg.CursorTime,
+		arg.CursorID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Draft
+	for rows.Next() {
+		var i Draft
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Body,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateDraft = `-- name: UpdateDraft :one
+UPDATE drafts SET body = $3, updated_at = NOW()
+WHERE id = $1 AND user_id = $2
+RETURNING id, user_id, body, created_at, updated_at
+`
+
+type UpdateDraftParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+	Body   string
+}
+
+func (q *Queries) UpdateDraft(ctx context.Context, arg UpdateDraftParams) (Draft, error) {
+	row := q.db.QueryRowContext(ctx, updateDraft, arg.ID, arg.UserID, arg.Body)
+	var i Draft
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Body,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
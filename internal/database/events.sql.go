@@ -0,0 +1,88 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: events.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
+
+const createEvent = `-- name: CreateEvent :exec
+INSERT INTO events (id, user_id, type, payload, created_at)
+VALUES (
+	GEN_RANDOM_UUID(),
+	$1,
+	$2,
+	$3,
+	NOW()
+)
+`
+
+type CreateEventParams struct {
+	UserID  uuid.UUID
+	Type    string
+	Payload json.RawMessage
+}
+
+func (q *Queries) CreateEvent(ctx context.Context, arg CreateEventParams) error {
+	_, err := q.db.ExecContext(ctx, createEvent, arg.UserID, arg.Type, arg.Payload)
+	return err
+}
+
+const listEvents = `-- name: ListEvents :many
+SELECT id, user_id, type, payload, created_at FROM events
+WHERE user_id = $1
+AND (
+	$2::timestamp IS NULL
+	OR (created_at, id) < ($2::timestamp, $3::uuid)
+)
+ORDER BY created_at DESC, id DESC
+LIMIT $4
+`
+
+type ListEventsParams struct {
+	UserID     uuid.UUID
+	CursorTime sql.NullTime
+	CursorID   uuid.NullUUID
+	RowLimit   int32
+}
+
+func (q *Queries) ListEvents(ctx context.Context, arg ListEventsParams) ([]Event, error) {
+	rows, err := q.db.QueryContext(ctx, listEvents,
+		arg.UserID,
+		arg.CursorTime,
+		arg.CursorID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Event
+	for rows.Next() {
+		var i Event
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Type,
+			&i.Payload,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,164 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: follow_requests.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const approveAllFollowRequests = `-- name: ApproveAllFollowRequests :many
+WITH approved AS (
+	DELETE FROM follow_requests WHERE target_id = $1
+	RETURNING requester_id, target_id
+)
+INSERT INTO follows (follower_id, followee_id, created_at)
+SELECT approved.requester_id, approved.target_id, NOW() FROM approved
+ON CONFLICT DO NOTHING
+RETURNING follower_id
+`
+
+func (q *Queries) ApproveAllFollowRequests(ctx context.Context, targetID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, approveAllFollowRequests, targetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var follower_id uuid.UUID
+		if err := rows.Scan(&follower_id); err != nil {
+			return nil, err
+		}
+		items = append(items, follower_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const createFollowRequest = `-- name: CreateFollowRequest :execrows
+INSERT INTO follow_requests (requester_id, target_id, created_at)
+SELECT $1, $2, NOW()
+WHERE NOT EXISTS (
+	SELECT 1 FROM follows
+	WHERE follows.follower_id = $1 AND follows.followee_id = $2
+) ON CONFLICT DO NOTHING
+`
+
+type CreateFollowRequestParams struct {
+	RequesterID uuid.UUID
+	TargetID    uuid.UUID
+}
+
+// Nothing is created when the requester already follows the target or has
+// already asked to
+func (q *Queries) CreateFollowRequest(ctx context.Context, arg CreateFollowRequestParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, createFollowRequest, arg.RequesterID, arg.TargetID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteFollowRequest = `-- name: DeleteFollowRequest :execrows
+DELETE FROM follow_requests WHERE requester_id = $1 AND target_id = $2
+`
+
+type DeleteFollowRequestParams struct {
+	RequesterID uuid.UUID
+	TargetID    uuid.UUID
+}
+
+func (q *Queries) DeleteFollowRequest(ctx context.Context, arg DeleteFollowRequestParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFollowRequest, arg.RequesterID, arg.TargetID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteFollowRequestsBetween = `-- name: DeleteFollowRequestsBetween :exec
+DELETE FROM follow_requests
+WHERE (requester_id = $1 AND target_id = $2)
+OR (requester_id = $2 AND target_id = $1)
+`
+
+type DeleteFollowRequestsBetweenParams struct {
+	UserID  uuid.UUID
+	OtherID uuid.UUID
+}
+
+func (q *Queries) DeleteFollowRequestsBetween(ctx context.Context, arg DeleteFollowRequestsBetweenParams) error {
+	_, err := q.db.ExecContext(ctx, deleteFollowRequestsBetween, arg.UserID, arg.OtherID)
+	return err
+}
+
+const listFollowRequests = `-- name: ListFollowRequests :many
+SELECT users.id, users.handle, users.is_chirpy_red, follow_requests.created_at AS requested_at FROM follow_requests
+INNER JOIN users ON follow_requests.requester_id = users.id
+WHERE follow_requests.target_id = $1
+AND (
+	$2::timestamp IS NULL
+	OR (follow_requests.created_at, users.id) < ($2::timestamp, $3::uuid)
+)
+ORDER BY follow_requests.created_at DESC, users.id DESC
+LIMIT $4
+`
+
+type ListFollowRequestsParams struct {
+	UserID     uuid.UUID
+	CursorTime sql.NullTime
+	CursorID   uuid.NullUUID
+	RowLimit   int32
+}
+
+type ListFollowRequestsRow struct {
+	ID          uuid.UUID
+	Handle      string
+	IsChirpyRed bool
+	RequestedAt time.Time
+}
+
+func (q *Queries) ListFollowRequests(ctx context.Context, arg ListFollowRequestsParams) ([]ListFollowRequestsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listFollowRequests,
+		arg.UserID,
+		arg.CursorTime,
+		arg.CursorID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListFollowRequestsRow
+	for rows.Next() {
+		var i ListFollowRequestsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Handle,
+			&i.IsChirpyRed,
+			&i.RequestedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
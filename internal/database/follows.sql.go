@@ -0,0 +1,205 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: follows.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createFollow = `-- name: CreateFollow :execrows
+INSERT INTO follows (follower_id, followee_id, created_at)
+VALUES (
+	$1,
+	$2,
+	NOW()
+) ON CONFLICT DO NOTHING
+`
+
+type CreateFollowParams struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+}
+
+func (q *Queries) CreateFollow(ctx context.Context, arg CreateFollowParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, createFollow, arg.FollowerID, arg.FolloweeID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteFollow = `-- name: DeleteFollow :execrows
+DELETE FROM follows WHERE follower_id = $1 AND followee_id = $2
+`
+
+type DeleteFollowParams struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+}
+
+func (q *Queries) DeleteFollow(ctx context.Context, arg DeleteFollowParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFollow, arg.FollowerID, arg.FolloweeID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteFollowsBetween = `-- name: DeleteFollowsBetween :exec
+DELETE FROM follows
+WHERE (follower_id = $1 AND followee_id = $2)
+OR (follower_id = $2 AND followee_id = $1)
+`
+
+type DeleteFollowsBetweenParams struct {
+	UserID  uuid.UUID
+	OtherID uuid.UUID
+}
+
+func (q *Queries) DeleteFollowsBetween(ctx context.Context, arg DeleteFollowsBetweenParams) error {
+	_, err := q.db.ExecContext(ctx, deleteFollowsBetween, arg.UserID, arg.OtherID)
+	return err
+}
+
+const getFollowCounts = `-- name: GetFollowCounts :one
+SELECT
+	(SELECT COUNT(*) FROM follows AS followers WHERE followers.followee_id = $1::uuid) AS follower_count,
+	(SELECT COUNT(*) FROM follows AS following WHERE following.follower_id = $1::uuid) AS following_count
+`
+
+type GetFollowCountsRow struct {
+	FollowerCount  int64
+	FollowingCount int64
+}
+
+func (q *Queries) GetFollowCounts(ctx context.Context, userID uuid.UUID) (GetFollowCountsRow, error) {
+	row := q.db.QueryRowContext(ctx, getFollowCounts, userID)
+	var i GetFollowCountsRow
+	err := row.Scan(&i.FollowerCount, &i.FollowingCount)
+	return i, err
+}
+
+const listFollowers = `-- name: ListFollowers :many
+SELECT users.id, users.handle, users.is_chirpy_red, follows.created_at AS followed_at FROM follows
+INNER JOIN users ON follows.follower_id = users.id
+WHERE follows.followee_id = $1
+AND (
+	$2::timestamp IS NULL
+	OR (follows.created_at, users.id) < ($2::timestamp, $3::uuid)
+)
+ORDER BY follows.created_at DESC, users.id DESC
+LIMIT $4
+`
+
+type ListFollowersParams struct {
+	UserID     uuid.UUID
+	CursorTime sql.NullTime
+	CursorID   uuid.NullUUID
+	RowLimit   int32
+}
+
+type ListFollowersRow struct {
+	ID          uuid.UUID
+	Handle      string
+	IsChirpyRed bool
+	FollowedAt  time.Time
+}
+
+func (q *Queries) ListFollowers(ctx context.Context, arg ListFollowersParams) ([]ListFollowersRow, error) {
+	rows, err := q.db.QueryContext(ctx, listFollowers,
+		arg.UserID,
+		arg.CursorTime,
+		arg.CursorID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListFollowersRow
+	for rows.Next() {
+		var i ListFollowersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Handle,
+			&i.IsChirpyRed,
+			&i.FollowedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFollowing = `-- name: ListFollowing :many
+SELECT users.id, users.handle, users.is_chirpy_red, follows.created_at AS followed_at FROM follows
+INNER JOIN users ON follows.followee_id = users.id
+WHERE follows.follower_id = $1
+AND (
+	$2::timestamp IS NULL
+	OR (follows.created_at, users.id) < ($2::timestamp, $3::uuid)
+)
+ORDER BY follows.created_at DESC, users.id DESC
+LIMIT $4
+`
+
+type ListFollowingParams struct {
+	UserID     uuid.UUID
+	CursorTime sql.NullTime
+	CursorID   uuid.NullUUID
+	RowLimit   int32
+}
+
+type ListFollowingRow struct {
+	ID          uuid.UUID
+	Handle      string
+	IsChirpyRed bool
+	FollowedAt  time.Time
+}
+
+func (q *Queries) ListFollowing(ctx context.Context, arg ListFollowingParams) ([]ListFollowingRow, error) {
+	rows, err := q.db.QueryContext(ctx, listFollowing,
+		arg.UserID,
+		arg.CursorTime,
+		arg.CursorID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListFollowingRow
+	for rows.Next() {
+		var i ListFollowingRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Handle,
+			&i.IsChirpyRed,
+			&i.FollowedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,401 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: media.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const createChirpMedia = `-- name: CreateChirpMedia :exec
+INSERT INTO chirp_media (chirp_id, media_id, position)
+SELECT $1, UNNEST($2::uuid[]), UNNEST($3::int[])
+`
+
+type CreateChirpMediaParams struct {
+	ChirpID   uuid.UUID
+	MediaIds  []uuid.UUID
+	Positions []int32
+}
+
+func (q *Queries) CreateChirpMedia(ctx context.Context, arg CreateChirpMediaParams) error {
+	_, err := q.db.ExecContext(ctx, createChirpMedia, arg.ChirpID, pq.Array(arg.MediaIds), pq.Array(arg.Positions))
+	return err
+}
+
+const createMedia = `-- name: CreateMedia :one
+INSERT INTO media (id, user_id, storage_key, content_type, size_bytes, created_at, status)
+VALUES (
+	$1,
+	$2,
+	$3,
+	$4,
+	$5,
+	NOW(),
+	'processing'
+) RETURNING id, user_id, storage_key, content_type, size_bytes, created_at, status, width, height, blurhash
+`
+
+type CreateMediaParams struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	StorageKey  string
+	ContentType string
+	SizeBytes   int64
+}
+
+func (q *Queries) CreateMedia(ctx context.Context, arg CreateMediaParams) (Medium, error) {
+	row := q.db.QueryRowContext(ctx, createMedia,
+		arg.ID,
+		arg.UserID,
+		arg.StorageKey,
+		arg.ContentType,
+		arg.SizeBytes,
+	)
+	var i Medium
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.StorageKey,
+		&i.ContentType,
+		&i.SizeBytes,
+		&i.CreatedAt,
+		&i.Status,
+		&i.Width,
+		&i.Height,
+		&i.Blurhash,
+	)
+	return i, err
+}
+
+const createMediaVariants = `-- name: CreateMediaVariants :exec
+INSERT INTO media_variants (media_id, name, storage_key, content_type, width, height)
+SELECT $1, UNNEST($2::text[]), UNNEST($3::text[]),
+UNNEST($4::text[]), UNNEST($5::int[]), UNNEST($6::int[])
+`
+
+type CreateMediaVariantsParams struct {
+	MediaID      uuid.UUID
+	Names        []string
+	StorageKeys  []string
+	ContentTypes []string
+	Widths       []int32
+	Heights      []int32
+}
+
+func (q *Queries) CreateMediaVariants(ctx context.Context, arg CreateMediaVariantsParams) error {
+	_, err := q.db.ExecContext(ctx, createMediaVariants,
+		arg.MediaID,
+		pq.Array(arg.Names),
+		pq.Array(arg.StorageKeys),
+		pq.Array(arg.ContentTypes),
+		pq.Array(arg.Widths),
+		pq.Array(arg.Heights),
+	)
+	return err
+}
+
+const deleteMedia = `-- name: DeleteMedia :exec
+DELETE FROM media WHERE id = $1
+`
+
+func (q *Queries) DeleteMedia(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteMedia, id)
+	return err
+}
+
+const deleteMediaForChirps = `-- name: DeleteMediaForChirps :exec
+DELETE FROM media
+WHERE id IN (SELECT chirp_media.media_id FROM chirp_media WHERE chirp_media.chirp_id = ANY($1::uuid[]))
+`
+
+// Attached media has no use once its chirp is gone, leaving it would make it
+// attachable again
+func (q *Queries) DeleteMediaForChirps(ctx context.Context, chirpIds []uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteMediaForChirps, pq.Array(chirpIds))
+	return err
+}
+
+const failStaleMedia = `-- name: FailStaleMedia :exec
+UPDATE media SET status = 'failed'
+WHERE status = 'processing' AND created_at < NOW() - INTERVAL '10 minutes'
+`
+
+// Jobs only live in memory, so media still processing long after upload was
+// lost to a restart
+func (q *Queries) FailStaleMedia(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, failStaleMedia)
+	return err
+}
+
+const getAttachableMedia = `-- name: GetAttachableMedia :many
+SELECT media.id, media.user_id, media.storage_key, media.content_type, media.size_bytes, media.created_at, media.status, media.width, media.height, media.blurhash FROM media
+WHERE media.id = ANY($1::uuid[]) AND media.user_id = $2
+AND media.status <> 'failed'
+AND NOT EXISTS (SELECT 1 FROM chirp_media WHERE chirp_media.media_id = media.id)
+`
+
+type GetAttachableMediaParams struct {
+	Ids    []uuid.UUID
+	UserID uuid.UUID
+}
+
+// Media can only go on a chirp by the user who uploaded it and only once,
+// uploads that failed processing can never be attached
+func (q *Queries) GetAttachableMedia(ctx context.Context, arg GetAttachableMediaParams) ([]Medium, error) {
+	rows, err := q.db.QueryContext(ctx, getAttachableMedia, pq.Array(arg.Ids), arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Medium
+	for rows.Next() {
+		var i Medium
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.StorageKey,
+			&i.ContentType,
+			&i.SizeBytes,
+			&i.CreatedAt,
+			&i.Status,
+			&i.Width,
+			&i.Height,
+			&i.Blurhash,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpMedia = `-- name: GetChirpMedia :many
+SELECT chirp_media.chirp_id, chirp_media.position, media.id, media.user_id, media.storage_key, media.content_type, media.size_bytes, media.created_at, media.status, media.width, media.height, media.blurhash FROM chirp_media
+INNER JOIN media ON chirp_media.media_id = media.id
+WHERE chirp_media.chirp_id = ANY($1::uuid[])
+ORDER BY chirp_media.chirp_id, chirp_media.position ASC
+`
+
+type GetChirpMediaRow struct {
+	ChirpID     uuid.UUID
+	Position    int32
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	StorageKey  string
+	ContentType string
+	SizeBytes   int64
+	CreatedAt   time.Time
+	Status      string
+	Width       sql.NullInt32
+	Height      sql.NullInt32
+	Blurhash    sql.NullString
+}
+
+func (q *Queries) GetChirpMedia(ctx context.Context, chirpIds []uuid.UUID) ([]GetChirpMediaRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpMedia, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpMediaRow
+	for rows.Next() {
+		var i GetChirpMediaRow
+		if err := rows.Scan(
+			&i.ChirpID,
+			&i.Position,
+			&i.ID,
+			&i.UserID,
+			&i.StorageKey,
+			&i.ContentType,
+			&i.SizeBytes,
+			&i.CreatedAt,
+			&i.Status,
+			&i.Width,
+			&i.Height,
+			&i.Blurhash,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMedia = `-- name: GetMedia :one
+SELECT id, user_id, storage_key, content_type, size_bytes, created_at, status, width, height, blurhash FROM media WHERE id = $1
+`
+
+func (q *Queries) GetMedia(ctx context.Context, id uuid.UUID) (Medium, error) {
+	row := q.db.QueryRowContext(ctx, getMedia, id)
+	var i Medium
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.StorageKey,
+		&i.ContentType,
+		&i.SizeBytes,
+		&i.CreatedAt,
+		&i.Status,
+		&i.Width,
+		&i.Height,
+		&i.Blurhash,
+	)
+	return i, err
+}
+
+const getMediaVariants = `-- name: GetMediaVariants :many
+SELECT media_id, name, storage_key, content_type, width, height FROM media_variants
+WHERE media_id = ANY($1::uuid[])
+ORDER BY media_id, width ASC
+`
+
+func (q *Queries) GetMediaVariants(ctx context.Context, mediaIds []uuid.UUID) ([]MediaVariant, error) {
+	rows, err := q.db.QueryContext(ctx, getMediaVariants, pq.Array(mediaIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MediaVariant
+	for rows.Next() {
+		var i MediaVariant
+		if err := rows.Scan(
+			&i.MediaID,
+			&i.Name,
+			&i.StorageKey,
+			&i.ContentType,
+			&i.Width,
+			&i.Height,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getReadableMediaFile = `-- name: GetReadableMediaFile :one
+WITH stored AS (
+	SELECT media.id AS media_id, media.storage_key, media.content_type FROM media
+	UNION ALL
+	SELECT media_variants.media_id, media_variants.storage_key, media_variants.content_type FROM media_variants
+)
+SELECT stored.content_type FROM stored
+INNER JOIN media ON stored.media_id = media.id
+LEFT JOIN chirp_media ON media.id = chirp_media.media_id
+WHERE stored.storage_key = $1::text
+AND media.status = 'ready'
+AND (
+	media.user_id = $2::uuid
+	OR (chirp_media.chirp_id IS NOT NULL AND chirp_visible_to(chirp_media.chirp_id, $2::uuid))
+)
+`
+
+type GetReadableMediaFileParams struct {
+	StorageKey string
+	ViewerID   uuid.NullUUID
+}
+
+// Stored files are readable by their uploader, anyone else can only read
+// them while they are attached to a chirp they can see
+func (q *Queries) GetReadableMediaFile(ctx context.Context, arg GetReadableMediaFileParams) (string, error) {
+	row := q.db.QueryRowContext(ctx, getReadableMediaFile, arg.StorageKey, arg.ViewerID)
+	var content_type string
+	err := row.Scan(&content_type)
+	return content_type, err
+}
+
+const getStorageKeysForChirps = `-- name: GetStorageKeysForChirps :many
+SELECT media.storage_key FROM media
+INNER JOIN chirp_media ON media.id = chirp_media.media_id
+WHERE chirp_media.chirp_id = ANY($1::uuid[])
+UNION ALL
+SELECT media_variants.storage_key FROM media_variants
+INNER JOIN chirp_media ON media_variants.media_id = chirp_media.media_id
+WHERE chirp_media.chirp_id = ANY($1::uuid[])
+`
+
+func (q *Queries) GetStorageKeysForChirps(ctx context.Context, chirpIds []uuid.UUID) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getStorageKeysForChirps, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var storage_key string
+		if err := rows.Scan(&storage_key); err != nil {
+			return nil, err
+		}
+		items = append(items, storage_key)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markMediaFailed = `-- name: MarkMediaFailed :exec
+UPDATE media SET status = 'failed' WHERE id = $1
+`
+
+func (q *Queries) MarkMediaFailed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, markMediaFailed, id)
+	return err
+}
+
+const markMediaReady = `-- name: MarkMediaReady :exec
+UPDATE media SET status = 'ready', storage_key = $2, content_type = $3, size_bytes = $4, width = $5, height = $6, blurhash = $7
+WHERE id = $1
+`
+
+type MarkMediaReadyParams struct {
+	ID          uuid.UUID
+	StorageKey  string
+	ContentType string
+	SizeBytes   int64
+	Width       sql.NullInt32
+	Height      sql.NullInt32
+	Blurhash    sql.NullString
+}
+
+func (q *Queries) MarkMediaReady(ctx context.Context, arg MarkMediaReadyParams) error {
+	_, err := q.db.ExecContext(ctx, markMediaReady,
+		arg.ID,
+		arg.StorageKey,
+		arg.ContentType,
+		arg.SizeBytes,
+		arg.Width,
+		arg.Height,
+		arg.Blurhash,
+	)
+	return err
+}
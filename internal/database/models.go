@@ -6,19 +6,161 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type Block struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+	CreatedAt time.Time
+}
+
 type Chirp struct {
+	ID                   uuid.UUID
+	Body                 string
+	UserID               uuid.UUID
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+	SearchVector         interface{}
+	InReplyTo            uuid.NullUUID
+	RechirpOf            uuid.NullUUID
+	QuoteOf              uuid.NullUUID
+	PublishAt            sql.NullTime
+	DeletedAt            sql.NullTime
+	PinnedAt             sql.NullTime
+	ContentWarning       sql.NullString
+	Sensitive            bool
+	SensitiveByModerator bool
+	Visibility           string
+	FannedOutAt          sql.NullTime
+	FanoutOnRead         bool
+}
+
+type ChirpBookmark struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type ChirpHashtag struct {
+	ChirpID    uuid.UUID
+	Tag        string
+	StartIndex int32
+	EndIndex   int32
+}
+
+type ChirpMedium struct {
+	ChirpID  uuid.UUID
+	MediaID  uuid.UUID
+	Position int32
+}
+
+type ChirpMention struct {
+	ChirpID    uuid.UUID
+	UserID     uuid.UUID
+	StartIndex int32
+	EndIndex   int32
+}
+
+type ChirpReaction struct {
+	ChirpID   uuid.UUID
+	UserID    uuid.UUID
+	Reaction  string
+	CreatedAt time.Time
+}
+
+type ChirpRevision struct {
+	ID         uuid.UUID
+	ChirpID    uuid.UUID
+	Body       string
+	CreatedAt  time.Time
+	ReplacedAt time.Time
+}
+
+type Draft struct {
 	ID        uuid.UUID
-	Body      string
 	UserID    uuid.UUID
+	Body      string
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
 
+type Event struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Type      string
+	Payload   json.RawMessage
+	CreatedAt time.Time
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type FollowRequest struct {
+	RequesterID uuid.UUID
+	TargetID    uuid.UUID
+	CreatedAt   time.Time
+}
+
+type MediaVariant struct {
+	MediaID     uuid.UUID
+	Name        string
+	StorageKey  string
+	ContentType string
+	Width       int32
+	Height      int32
+}
+
+type Medium struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	StorageKey  string
+	ContentType string
+	SizeBytes   int64
+	CreatedAt   time.Time
+	Status      string
+	Width       sql.NullInt32
+	Height      sql.NullInt32
+	Blurhash    sql.NullString
+}
+
+type Mute struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Kind        string
+	MutedUserID uuid.NullUUID
+	Term        sql.NullString
+	CreatedAt   time.Time
+	ExpiresAt   sql.NullTime
+}
+
+type Poll struct {
+	ID        uuid.UUID
+	ChirpID   uuid.UUID
+	ClosesAt  time.Time
+	CreatedAt time.Time
+	ClosedAt  sql.NullTime
+}
+
+type PollOption struct {
+	PollID   uuid.UUID
+	Position int32
+	Text     string
+}
+
+type PollVote struct {
+	PollID    uuid.UUID
+	UserID    uuid.UUID
+	Position  int32
+	CreatedAt time.Time
+}
+
 type RefreshToken struct {
 	Token     string
 	CreatedAt time.Time
@@ -28,11 +170,26 @@ type RefreshToken struct {
 	RevokedAt sql.NullTime
 }
 
+type TimelineEntry struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type TrendExclusion struct {
+	Tag       string
+	CreatedAt time.Time
+}
+
 type User struct {
-	ID             uuid.UUID
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
-	Email          string
-	HashedPassword string
-	IsChirpyRed    bool
+	ID               uuid.UUID
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	Email            string
+	HashedPassword   string
+	IsChirpyRed      bool
+	IsModerator      bool
+	SensitiveContent string
+	Protected        bool
+	Handle           string
 }
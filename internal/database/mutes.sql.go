@@ -0,0 +1,111 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: mutes.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+const createMute = `-- name: CreateMute :one
+INSERT INTO mutes (id, user_id, kind, muted_user_id, term, created_at, expires_at)
+VALUES (
+	GEN_RANDOM_UUID(),
+	$1,
+	$2,
+	$3,
+	$4,
+	NOW(),
+	$5
+) ON CONFLICT DO NOTHING
+RETURNING id, user_id, kind, muted_user_id, term, created_at, expires_at
+`
+
+type CreateMuteParams struct {
+	UserID      uuid.UUID
+	Kind        string
+	MutedUserID uuid.NullUUID
+	Term        sql.NullString
+	ExpiresAt   sql.NullTime
+}
+
+// Muting the same thing twice creates nothing, which comes back as no rows
+func (q *Queries) CreateMute(ctx context.Context, arg CreateMuteParams) (Mute, error) {
+	row := q.db.QueryRowContext(ctx, createMute,
+		arg.UserID,
+		arg.Kind,
+		arg.MutedUserID,
+		arg.Term,
+		arg.ExpiresAt,
+	)
+	var i Mute
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Kind,
+		&i.MutedUserID,
+		&i.Term,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const deleteMute = `-- name: DeleteMute :execrows
+DELETE FROM mutes WHERE id = $1 AND user_id = $2
+`
+
+type DeleteMuteParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) DeleteMute(ctx context.Context, arg DeleteMuteParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteMute, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getActiveMutes = `-- name: GetActiveMutes :many
+SELECT id, user_id, kind, muted_user_id, term, created_at, expires_at FROM mutes
+WHERE user_id = $1 AND (expires_at IS NULL OR expires_at > NOW())
+ORDER BY created_at DESC, id DESC
+`
+
+func (q *Queries) GetActiveMutes(ctx context.Context, userID uuid.UUID) ([]Mute, error) {
+	rows, err := q.db.QueryContext(ctx, getActiveMutes, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Mute
+	for rows.Next() {
+		var i Mute
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Kind,
+			&i.MutedUserID,
+			&i.Term,
+			&i.CreatedAt,
+			&i.ExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
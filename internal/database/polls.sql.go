@@ -0,0 +1,265 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: polls.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const claimClosingPolls = `-- name: ClaimClosingPolls :many
+SELECT polls.id, polls.chirp_id, chirps.user_id FROM polls
+INNER JOIN chirps ON polls.chirp_id = chirps.id
+WHERE polls.closes_at <= NOW() AND polls.closed_at IS NULL
+AND chirps.publish_at IS NULL AND chirps.deleted_at IS NULL
+ORDER BY polls.closes_at ASC
+LIMIT $1
+FOR UPDATE OF polls SKIP LOCKED
+`
+
+type ClaimClosingPollsRow struct {
+	ID      uuid.UUID
+	ChirpID uuid.UUID
+	UserID  uuid.UUID
+}
+
+// SKIP LOCKED keeps several instances from closing the same poll twice.
+// Polls on chirps that are not out yet or sit in the trash are left alone,
+// no one is told a poll closed that nobody could see
+func (q *Queries) ClaimClosingPolls(ctx context.Context, rowLimit int32) ([]ClaimClosingPollsRow, error) {
+	rows, err := q.db.QueryContext(ctx, claimClosingPolls, rowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ClaimClosingPollsRow
+	for rows.Next() {
+		var i ClaimClosingPollsRow
+		if err := rows.Scan(&i.ID, &i.ChirpID, &i.UserID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const createPoll = `-- name: CreatePoll :one
+INSERT INTO polls (id, chirp_id, closes_at, created_at)
+VALUES (
+	GEN_RANDOM_UUID(),
+	$1,
+	$2,
+	NOW()
+) RETURNING id, chirp_id, closes_at, created_at, closed_at
+`
+
+type CreatePollParams struct {
+	ChirpID  uuid.UUID
+	ClosesAt time.Time
+}
+
+func (q *Queries) CreatePoll(ctx context.Context, arg CreatePollParams) (Poll, error) {
+	row := q.db.QueryRowContext(ctx, createPoll, arg.ChirpID, arg.ClosesAt)
+	var i Poll
+	err := row.Scan(
+		&i.ID,
+		&i.ChirpID,
+		&i.ClosesAt,
+		&i.CreatedAt,
+		&i.ClosedAt,
+	)
+	return i, err
+}
+
+const createPollOptions = `-- name: CreatePollOptions :exec
+INSERT INTO poll_options (poll_id, position, text)
+SELECT $1, UNNEST($2::int[]), UNNEST($3::text[])
+`
+
+type CreatePollOptionsParams struct {
+	PollID    uuid.UUID
+	Positions []int32
+	Texts     []string
+}
+
+func (q *Queries) CreatePollOptions(ctx context.Context, arg CreatePollOptionsParams) error {
+	_, err := q.db.ExecContext(ctx, createPollOptions, arg.PollID, pq.Array(arg.Positions), pq.Array(arg.Texts))
+	return err
+}
+
+const createVote = `-- name: CreateVote :execrows
+INSERT INTO poll_votes (poll_id, user_id, position, created_at)
+SELECT polls.id, $1, $2, NOW() FROM polls
+WHERE polls.id = $3 AND polls.closes_at > NOW()
+ON CONFLICT DO NOTHING
+`
+
+type CreateVoteParams struct {
+	UserID   uuid.UUID
+	Position int32
+	PollID   uuid.UUID
+}
+
+// The closing check is repeated here so a vote racing the deadline loses
+func (q *Queries) CreateVote(ctx context.Context, arg CreateVoteParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, createVote, arg.UserID, arg.Position, arg.PollID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getPollByChirp = `-- name: GetPollByChirp :one
+SELECT id, chirp_id, closes_at, created_at, closed_at FROM polls WHERE chirp_id = $1
+`
+
+func (q *Queries) GetPollByChirp(ctx context.Context, chirpID uuid.UUID) (Poll, error) {
+	row := q.db.QueryRowContext(ctx, getPollByChirp, chirpID)
+	var i Poll
+	err := row.Scan(
+		&i.ID,
+		&i.ChirpID,
+		&i.ClosesAt,
+		&i.CreatedAt,
+		&i.ClosedAt,
+	)
+	return i, err
+}
+
+const getPollTallies = `-- name: GetPollTallies :many
+SELECT poll_id, position, COUNT(*) AS vote_count FROM poll_votes
+WHERE poll_id = ANY($1::uuid[])
+GROUP BY poll_id, position
+`
+
+type GetPollTalliesRow struct {
+	PollID    uuid.UUID
+	Position  int32
+	VoteCount int64
+}
+
+func (q *Queries) GetPollTallies(ctx context.Context, pollIds []uuid.UUID) ([]GetPollTalliesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPollTallies, pq.Array(pollIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPollTalliesRow
+	for rows.Next() {
+		var i GetPollTalliesRow
+		if err := rows.Scan(&i.PollID, &i.Position, &i.VoteCount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPollsForChirps = `-- name: GetPollsForChirps :many
+SELECT polls.id, polls.chirp_id, polls.closes_at, poll_options.position, poll_options.text FROM polls
+INNER JOIN poll_options ON polls.id = poll_options.poll_id
+WHERE polls.chirp_id = ANY($1::uuid[])
+ORDER BY polls.id, poll_options.position ASC
+`
+
+type GetPollsForChirpsRow struct {
+	ID       uuid.UUID
+	ChirpID  uuid.UUID
+	ClosesAt time.Time
+	Position int32
+	Text     string
+}
+
+func (q *Queries) GetPollsForChirps(ctx context.Context, chirpIds []uuid.UUID) ([]GetPollsForChirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPollsForChirps, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPollsForChirpsRow
+	for rows.Next() {
+		var i GetPollsForChirpsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ChirpID,
+			&i.ClosesAt,
+			&i.Position,
+			&i.Text,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getViewerVotes = `-- name: GetViewerVotes :many
+SELECT poll_id, position FROM poll_votes
+WHERE poll_id = ANY($1::uuid[]) AND user_id = $2
+`
+
+type GetViewerVotesParams struct {
+	PollIds []uuid.UUID
+	UserID  uuid.UUID
+}
+
+type GetViewerVotesRow struct {
+	PollID   uuid.UUID
+	Position int32
+}
+
+func (q *Queries) GetViewerVotes(ctx context.Context, arg GetViewerVotesParams) ([]GetViewerVotesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getViewerVotes, pq.Array(arg.PollIds), arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetViewerVotesRow
+	for rows.Next() {
+		var i GetViewerVotesRow
+		if err := rows.Scan(&i.PollID, &i.Position); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markPollClosed = `-- name: MarkPollClosed :exec
+UPDATE polls SET closed_at = NOW() WHERE id = $1
+`
+
+func (q *Queries) MarkPollClosed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, markPollClosed, id)
+	return err
+}
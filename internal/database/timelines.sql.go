@@ -0,0 +1,262 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: timelines.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const backfillTimeline = `-- name: BackfillTimeline :exec
+INSERT INTO timeline_entries (user_id, chirp_id, created_at)
+SELECT $1::uuid, recentchirps.id, recentchirps.created_at FROM (
+	SELECT chirps.id, chirps.created_at FROM chirps
+	WHERE chirps.user_id = $2 AND chirps.fanned_out_at IS NOT NULL AND NOT chirps.fanout_on_read
+	ORDER BY chirps.created_at DESC
+	LIMIT $3
+) AS recentchirps
+ON CONFLICT DO NOTHING
+`
+
+type BackfillTimelineParams struct {
+	UserID   uuid.UUID
+	AuthorID uuid.UUID
+	RowLimit int32
+}
+
+// A new follow brings the followee's latest chirps into the follower's
+// timeline rather than leaving it empty until they next post
+func (q *Queries) BackfillTimeline(ctx context.Context, arg BackfillTimelineParams) error {
+	_, err := q.db.ExecContext(ctx, backfillTimeline, arg.UserID, arg.AuthorID, arg.RowLimit)
+	return err
+}
+
+const claimPendingFanouts = `-- name: ClaimPendingFanouts :many
+SELECT id, user_id, created_at FROM chirps
+WHERE fanned_out_at IS NULL AND publish_at IS NULL AND deleted_at IS NULL
+ORDER BY created_at ASC
+LIMIT $1
+FOR UPDATE SKIP LOCKED
+`
+
+type ClaimPendingFanoutsRow struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+// SKIP LOCKED keeps several instances from fanning out the same chirp twice
+func (q *Queries) ClaimPendingFanouts(ctx context.Context, rowLimit int32) ([]ClaimPendingFanoutsRow, error) {
+	rows, err := q.db.QueryContext(ctx, claimPendingFanouts, rowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ClaimPendingFanoutsRow
+	for rows.Next() {
+		var i ClaimPendingFanoutsRow
+		if err := rows.Scan(&i.ID, &i.UserID, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countFollowers = `-- name: CountFollowers :one
+SELECT COUNT(*) FROM follows WHERE followee_id = $1
+`
+
+func (q *Queries) CountFollowers(ctx context.Context, followeeID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countFollowers, followeeID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const deleteTimelineAuthor = `-- name: DeleteTimelineAuthor :exec
+DELETE FROM timeline_entries
+WHERE timeline_entries.user_id = $1
+AND timeline_entries.chirp_id IN (SELECT chirps.id FROM chirps WHERE chirps.user_id = $2)
+`
+
+type DeleteTimelineAuthorParams struct {
+	UserID   uuid.UUID
+	AuthorID uuid.UUID
+}
+
+func (q *Queries) DeleteTimelineAuthor(ctx context.Context, arg DeleteTimelineAuthorParams) error {
+	_, err := q.db.ExecContext(ctx, deleteTimelineAuthor, arg.UserID, arg.AuthorID)
+	return err
+}
+
+const fanOutChirp = `-- name: FanOutChirp :exec
+INSERT INTO timeline_entries (user_id, chirp_id, created_at)
+SELECT follows.follower_id, $1::uuid, $2::timestamp FROM follows
+WHERE follows.followee_id = $3
+ON CONFLICT DO NOTHING
+`
+
+type FanOutChirpParams struct {
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+}
+
+func (q *Queries) FanOutChirp(ctx context.Context, arg FanOutChirpParams) error {
+	_, err := q.db.ExecContext(ctx, fanOutChirp, arg.ChirpID, arg.CreatedAt, arg.UserID)
+	return err
+}
+
+const listTimeline = `-- name: ListTimeline :many
+WITH timeline AS (
+	(
+		SELECT chirps.id, chirps.created_at FROM timeline_entries
+		INNER JOIN chirps ON timeline_entries.chirp_id = chirps.id
+		WHERE timeline_entries.user_id = $2
+		AND (
+			$3::timestamp IS NULL
+			OR (timeline_entries.created_at, timeline_entries.chirp_id) < ($3::timestamp, $4::uuid)
+		)
+		AND chirps.publish_at IS NULL
+		AND chirp_visible_to(chirps.id, $2)
+		AND NOT chirp_muted_for(chirps.id, $2)
+		AND (NOT $5::boolean OR NOT chirp_sensitive(chirps.id))
+		ORDER BY timeline_entries.created_at DESC, timeline_entries.chirp_id DESC
+		LIMIT $1
+	)
+	UNION
+	(
+		SELECT chirps.id, chirps.created_at FROM follows
+		INNER JOIN chirps ON follows.followee_id = chirps.user_id
+		WHERE follows.follower_id = $2 AND chirps.fanout_on_read
+		AND (
+			$3::timestamp IS NULL
+			OR (chirps.created_at, chirps.id) < ($3::timestamp, $4::uuid)
+		)
+		AND chirps.publish_at IS NULL
+		AND chirp_visible_to(chirps.id, $2)
+		AND NOT chirp_muted_for(chirps.id, $2)
+		AND (NOT $5::boolean OR NOT chirp_sensitive(chirps.id))
+		ORDER BY chirps.created_at DESC, chirps.id DESC
+		LIMIT $1
+	)
+	UNION
+	(
+		SELECT chirps.id, chirps.created_at FROM chirps
+		WHERE chirps.user_id = $2
+		AND (
+			$3::timestamp IS NULL
+			OR (chirps.created_at, chirps.id) < ($3::timestamp, $4::uuid)
+		)
+		AND chirps.publish_at IS NULL
+		AND chirp_visible_to(chirps.id, $2)
+		AND NOT chirp_muted_for(chirps.id, $2)
+		AND (NOT $5::boolean OR NOT chirp_sensitive(chirps.id))
+		ORDER BY chirps.created_at DESC, chirps.id DESC
+		LIMIT $1
+	)
+)
+SELECT chirps.id, chirps.body, chirps.user_id, chirps.created_at, chirps.updated_at, chirps.in_reply_to, chirps.rechirp_of, chirps.quote_of, chirps.publish_at, chirps.content_warning, (chirps.sensitive OR chirps.sensitive_by_moderator)::boolean AS sensitive, chirps.visibility, users.is_chirpy_red FROM timeline
+INNER JOIN chirps ON timeline.id = chirps.id
+INNER JOIN users ON chirps.user_id = users.id
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT $1
+`
+
+type ListTimelineParams struct {
+	RowLimit      int32
+	UserID        uuid.UUID
+	CursorTime    sql.NullTime
+	CursorID      uuid.NullUUID
+	HideSensitive bool
+}
+
+type ListTimelineRow struct {
+	ID             uuid.UUID
+	Body           string
+	UserID         uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	InReplyTo      uuid.NullUUID
+	RechirpOf      uuid.NullUUID
+	QuoteOf        uuid.NullUUID
+	PublishAt      sql.NullTime
+	ContentWarning sql.NullString
+	Sensitive      bool
+	Visibility     string
+	IsChirpyRed    bool
+}
+
+// A timeline is what was written to it, the chirps of followed accounts
+// that are read instead, and the user's own chirps. Each part is filtered
+// and cut to a page on its own, so a page only ever reads a page's worth of
+// rows from each
+func (q *Queries) ListTimeline(ctx context.Context, arg ListTimelineParams) ([]ListTimelineRow, error) {
+	rows, err := q.db.QueryContext(ctx, listTimeline,
+		arg.RowLimit,
+		arg.UserID,
+		arg.CursorTime,
+		arg.CursorID,
+		arg.HideSensitive,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListTimelineRow
+	for rows.Next() {
+		var i ListTimelineRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Body,
+			&i.UserID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.InReplyTo,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.PublishAt,
+			&i.ContentWarning,
+			&i.Sensitive,
+			&i.Visibility,
+			&i.IsChirpyRed,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markChirpFannedOut = `-- name: MarkChirpFannedOut :exec
+UPDATE chirps SET fanned_out_at = NOW(), fanout_on_read = $2 WHERE id = $1
+`
+
+type MarkChirpFannedOutParams struct {
+	ID           uuid.UUID
+	FanoutOnRead bool
+}
+
+func (q *Queries) MarkChirpFannedOut(ctx context.Context, arg MarkChirpFannedOutParams) error {
+	_, err := q.db.ExecContext(ctx, markChirpFannedOut, arg.ID, arg.FanoutOnRead)
+	return err
+}
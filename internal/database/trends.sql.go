@@ -0,0 +1,100 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: trends.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createTrendExclusion = `-- name: CreateTrendExclusion :exec
+INSERT INTO trend_exclusions (tag, created_at)
+VALUES (
+	$1,
+	NOW()
+) ON CONFLICT DO NOTHING
+`
+
+func (q *Queries) CreateTrendExclusion(ctx context.Context, tag string) error {
+	_, err := q.db.ExecContext(ctx, createTrendExclusion, tag)
+	return err
+}
+
+const deleteTrendExclusion = `-- name: DeleteTrendExclusion :exec
+DELETE FROM trend_exclusions WHERE tag = $1
+`
+
+func (q *Queries) DeleteTrendExclusion(ctx context.Context, tag string) error {
+	_, err := q.db.ExecContext(ctx, deleteTrendExclusion, tag)
+	return err
+}
+
+const getRecentHashtags = `-- name: GetRecentHashtags :many
+SELECT chirp_hashtags.tag, chirp_hashtags.chirp_id, chirps.created_at FROM chirp_hashtags
+INNER JOIN chirps ON chirp_hashtags.chirp_id = chirps.id
+INNER JOIN users ON chirps.user_id = users.id
+WHERE chirps.created_at >= $1 AND chirps.publish_at IS NULL AND chirps.deleted_at IS NULL AND chirps.visibility = 'public'
+AND NOT users.protected
+ORDER BY chirps.created_at ASC
+`
+
+type GetRecentHashtagsRow struct {
+	Tag       string
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
+func (q *Queries) GetRecentHashtags(ctx context.Context, createdAt time.Time) ([]GetRecentHashtagsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getRecentHashtags, createdAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRecentHashtagsRow
+	for rows.Next() {
+		var i GetRecentHashtagsRow
+		if err := rows.Scan(&i.Tag, &i.ChirpID, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTrendExclusions = `-- name: GetTrendExclusions :many
+SELECT tag, created_at FROM trend_exclusions ORDER BY tag ASC
+`
+
+func (q *Queries) GetTrendExclusions(ctx context.Context) ([]TrendExclusion, error) {
+	rows, err := q.db.QueryContext(ctx, getTrendExclusions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TrendExclusion
+	for rows.Next() {
+		var i TrendExclusion
+		if err := rows.Scan(&i.Tag, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
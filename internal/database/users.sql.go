@@ -13,19 +13,22 @@ import (
 )
 
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, handle)
 VALUES (
 	GEN_RANDOM_UUID(),
 	NOW(),
 	NOW(),
 	$1,
-	$2
-) RETURNING id, created_at, updated_at, email, is_chirpy_red
+	$2,
+	$3
+) ON CONFLICT (handle) DO NOTHING
+RETURNING id, created_at, updated_at, email, is_chirpy_red, handle
 `
 
 type CreateUserParams struct {
 	Email          string
 	HashedPassword string
+	Handle         string
 }
 
 type CreateUserRow struct {
@@ -34,10 +37,12 @@ type CreateUserRow struct {
 	UpdatedAt   time.Time
 	Email       string
 	IsChirpyRed bool
+	Handle      string
 }
 
+// A handle that is already taken creates nothing, which comes back as no rows
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (CreateUserRow, error) {
-	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword)
+	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword, arg.Handle)
 	var i CreateUserRow
 	err := row.Scan(
 		&i.ID,
@@ -45,6 +50,7 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (CreateU
 		&i.UpdatedAt,
 		&i.Email,
 		&i.IsChirpyRed,
+		&i.Handle,
 	)
 	return i, err
 }
@@ -58,8 +64,35 @@ func (q *Queries) DeleteUsers(ctx context.Context) error {
 	return err
 }
 
+const getPreferences = `-- name: GetPreferences :one
+SELECT sensitive_content, protected FROM users WHERE id = $1
+`
+
+type GetPreferencesRow struct {
+	SensitiveContent string
+	Protected        bool
+}
+
+func (q *Queries) GetPreferences(ctx context.Context, id uuid.UUID) (GetPreferencesRow, error) {
+	row := q.db.QueryRowContext(ctx, getPreferences, id)
+	var i GetPreferencesRow
+	err := row.Scan(&i.SensitiveContent, &i.Protected)
+	return i, err
+}
+
+const getSensitiveContent = `-- name: GetSensitiveContent :one
+SELECT sensitive_content FROM users WHERE id = $1
+`
+
+func (q *Queries) GetSensitiveContent(ctx context.Context, id uuid.UUID) (string, error) {
+	row := q.db.QueryRowContext(ctx, getSensitiveContent, id)
+	var sensitive_content string
+	err := row.Scan(&sensitive_content)
+	return sensitive_content, err
+}
+
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red FROM users WHERE email = $1
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, is_moderator, sensitive_content, protected, handle FROM users WHERE email = $1
 `
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
@@ -72,10 +105,137 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.IsModerator,
+		&i.SensitiveContent,
+		&i.Protected,
+		&i.Handle,
+	)
+	return i, err
+}
+
+const getUserByID = `-- name: GetUserByID :one
+SELECT id, created_at, updated_at, email, is_chirpy_red, protected, handle FROM users WHERE id = $1
+`
+
+type GetUserByIDRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Email       string
+	IsChirpyRed bool
+	Protected   bool
+	Handle      string
+}
+
+func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (GetUserByIDRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserByID, id)
+	var i GetUserByIDRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.IsChirpyRed,
+		&i.Protected,
+		&i.Handle,
 	)
 	return i, err
 }
 
+const isModerator = `-- name: IsModerator :one
+SELECT is_moderator FROM users WHERE id = $1
+`
+
+func (q *Queries) IsModerator(ctx context.Context, id uuid.UUID) (bool, error) {
+	row := q.db.QueryRowContext(ctx, isModerator, id)
+	var is_moderator bool
+	err := row.Scan(&is_moderator)
+	return is_moderator, err
+}
+
+const lockUser = `-- name: LockUser :one
+SELECT is_chirpy_red FROM users WHERE id = $1 FOR UPDATE
+`
+
+// Serialises changes that have to respect a per user limit
+func (q *Queries) LockUser(ctx context.Context, id uuid.UUID) (bool, error) {
+	row := q.db.QueryRowContext(ctx, lockUser, id)
+	var is_chirpy_red bool
+	err := row.Scan(&is_chirpy_red)
+	return is_chirpy_red, err
+}
+
+const setHandle = `-- name: SetHandle :execrows
+UPDATE users SET handle = $1, updated_at = NOW()
+WHERE users.id = $2
+AND NOT EXISTS (SELECT 1 FROM users AS others WHERE others.handle = $1 AND others.id <> $2::uuid)
+`
+
+type SetHandleParams struct {
+	Handle string
+	ID     uuid.UUID
+}
+
+// A handle another user has creates nothing, which comes back as no rows
+func (q *Queries) SetHandle(ctx context.Context, arg SetHandleParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setHandle, arg.Handle, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setModerator = `-- name: SetModerator :execrows
+UPDATE users SET is_moderator = $2, updated_at = NOW() WHERE id = $1
+`
+
+type SetModeratorParams struct {
+	ID          uuid.UUID
+	IsModerator bool
+}
+
+func (q *Queries) SetModerator(ctx context.Context, arg SetModeratorParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setModerator, arg.ID, arg.IsModerator)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setProtected = `-- name: SetProtected :execrows
+UPDATE users SET protected = $2, updated_at = NOW() WHERE id = $1
+`
+
+type SetProtectedParams struct {
+	ID        uuid.UUID
+	Protected bool
+}
+
+func (q *Queries) SetProtected(ctx context.Context, arg SetProtectedParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setProtected, arg.ID, arg.Protected)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setSensitiveContent = `-- name: SetSensitiveContent :execrows
+UPDATE users SET sensitive_content = $2, updated_at = NOW() WHERE id = $1
+`
+
+type SetSensitiveContentParams struct {
+	ID               uuid.UUID
+	SensitiveContent string
+}
+
+func (q *Queries) SetSensitiveContent(ctx context.Context, arg SetSensitiveContentParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setSensitiveContent, arg.ID, arg.SensitiveContent)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const updateRedUser = `-- name: UpdateRedUser :exec
 UPDATE users SET is_chirpy_red = TRUE, updated_at = NOW() WHERE id = $1
 `
@@ -86,7 +246,7 @@ func (q *Queries) UpdateRedUser(ctx context.Context, id uuid.UUID) error {
 }
 
 const updateUserDetails = `-- name: UpdateUserDetails :one
-UPDATE users SET email = $1, hashed_password = $2, updated_at = NOW() WHERE id = $3 RETURNING id, created_at, updated_at, email, is_chirpy_red
+UPDATE users SET email = $1, hashed_password = $2, updated_at = NOW() WHERE id = $3 RETURNING id, created_at, updated_at, email, is_chirpy_red, handle
 `
 
 type UpdateUserDetailsParams struct {
@@ -101,6 +261,7 @@ type UpdateUserDetailsRow struct {
 	UpdatedAt   time.Time
 	Email       string
 	IsChirpyRed bool
+	Handle      string
 }
 
 func (q *Queries) UpdateUserDetails(ctx context.Context, arg UpdateUserDetailsParams) (UpdateUserDetailsRow, error) {
@@ -112,6 +273,7 @@ func (q *Queries) UpdateUserDetails(ctx context.Context, arg UpdateUserDetailsPa
 		&i.UpdatedAt,
 		&i.Email,
 		&i.IsChirpyRed,
+		&i.Handle,
 	)
 	return i, err
 }
@@ -0,0 +1,114 @@
+package entities
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	Hashtag = "hashtag"
+	Mention = "mention"
+)
+
+const MaxHandleLength = 30
+
+// Entity is a hashtag or mention found in a chirp body. Start and End are
+// offsets in characters (Unicode code points), End is exclusive and both
+// include the leading # or @. Text is the entity without that leading sign,
+// lowercased so they can be matched regardless of case.
+type Entity struct {
+	Type string
+	Text string
+	Start int
+	End int
+}
+
+// Extract finds the hashtags and mentions in a chirp body. A mention is an @
+// followed by a user's handle, an @ that turns out to start an email address
+// is left alone so addresses are never looked up.
+func Extract(body string) []Entity {
+	runes := []rune(body)
+	foundEntities := []Entity{}
+
+	for index := 0; index < len(runes); index++ {
+		if runes[index] != '#' && runes[index] != '@' {
+			continue
+		}
+		if index > 0 && isWordRune(runes[index-1]) {
+			continue
+		}
+
+		if runes[index] == '#' {
+			end := index + 1
+			for end < len(runes) && isWordRune(runes[end]) {
+				end++
+			}
+			tag := string(runes[index+1 : end])
+			if hasLetter(tag) {
+				foundEntities = append(foundEntities, Entity{
+					Type: Hashtag,
+					Text: strings.ToLower(tag),
+					Start: index,
+					End: end,
+				})
+			}
+			index = end - 1
+			continue
+		}
+
+		end := index + 1
+		for end < len(runes) && isEmailRune(runes[end]) {
+			end++
+		}
+		if strings.ContainsRune(string(runes[index+1:end]), '@') {
+			index = end - 1
+			continue
+		}
+		end = index + 1
+		for end < len(runes) && isWordRune(runes[end]) {
+			end++
+		}
+		if handle, ok := NormalizeHandle(string(runes[index+1 : end])); ok {
+			foundEntities = append(foundEntities, Entity{
+				Type: Mention,
+				Text: handle,
+				Start: index,
+				End: end,
+			})
+		}
+		index = end - 1
+	}
+
+	return foundEntities
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+func isEmailRune(r rune) bool {
+	return r < unicode.MaxASCII && (isWordRune(r) || strings.ContainsRune(".%+-@", r))
+}
+
+func hasLetter(text string) bool {
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeHandle lowercases a handle and reports whether it is one, handles
+// are ASCII letters, digits and underscores up to MaxHandleLength long
+func NormalizeHandle(handle string) (string, bool) {
+	if handle == "" || len(handle) > MaxHandleLength {
+		return "", false
+	}
+	for _, r := range handle {
+		if r >= unicode.MaxASCII || !isWordRune(r) {
+			return "", false
+		}
+	}
+	return strings.ToLower(handle), true
+}
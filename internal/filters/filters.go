@@ -0,0 +1,143 @@
+package filters
+
+import (
+	"fmt"
+	"time"
+	"strings"
+	"strconv"
+	"net/url"
+	"github.com/google/uuid"
+)
+
+const MaxAuthorIDs = 50
+
+type InvalidParameterError struct {
+	Parameter string
+	Reason string
+}
+
+func (e *InvalidParameterError) Error() string {
+	return fmt.Sprintf("invalid query parameter %q: %s", e.Parameter, e.Reason)
+}
+
+type ChirpFilters struct {
+	AuthorIDs []uuid.UUID
+	Since *time.Time
+	Until *time.Time
+	ChirpyRed *bool
+	HasMedia *bool
+	SortBy string
+	SortDesc bool
+}
+
+// Order is one way a chirp listing can be sorted, each has its own query
+type Order int
+
+const (
+	CreatedAtAsc Order = iota
+	CreatedAtDesc
+	UpdatedAtAsc
+	UpdatedAtDesc
+)
+
+// Order picks the listing order the sort_by and sort parameters asked for
+func (f ChirpFilters) Order() Order {
+	switch {
+	case f.SortBy == "updated_at" && f.SortDesc:
+		return UpdatedAtDesc
+	case f.SortBy == "updated_at":
+		return UpdatedAtAsc
+	case f.SortDesc:
+		return CreatedAtDesc
+	default:
+		return CreatedAtAsc
+	}
+}
+
+func ParseChirpFilters(query url.Values) (ChirpFilters, error) {
+	chirpFilters := ChirpFilters{
+		AuthorIDs: []uuid.UUID{},
+		SortBy: "created_at",
+	}
+
+	// author_id can be repeated or given as a comma separated list
+	for _, rawAuthorIDs := range query["author_id"] {
+		for _, rawAuthorID := range strings.Split(rawAuthorIDs, ",") {
+			authorID, err := uuid.Parse(strings.TrimSpace(rawAuthorID))
+			if err != nil {
+				return ChirpFilters{}, &InvalidParameterError{Parameter: "author_id", Reason: "must be a UUID"}
+			}
+			chirpFilters.AuthorIDs = append(chirpFilters.AuthorIDs, authorID)
+		}
+	}
+	if len(chirpFilters.AuthorIDs) > MaxAuthorIDs {
+		return ChirpFilters{}, &InvalidParameterError{Parameter: "author_id", Reason: fmt.Sprintf("at most %d authors can be given", MaxAuthorIDs)}
+	}
+
+	since, err := parseTime(query, "since")
+	if err != nil {
+		return ChirpFilters{}, err
+	}
+	chirpFilters.Since = since
+	until, err := parseTime(query, "until")
+	if err != nil {
+		return ChirpFilters{}, err
+	}
+	chirpFilters.Until = until
+	if since != nil && until != nil && !since.Before(*until) {
+		return ChirpFilters{}, &InvalidParameterError{Parameter: "until", Reason: "must be after since"}
+	}
+
+	chirpyRed, err := parseBool(query, "is_chirpy_red")
+	if err != nil {
+		return ChirpFilters{}, err
+	}
+	chirpFilters.ChirpyRed = chirpyRed
+	hasMedia, err := parseBool(query, "has_media")
+	if err != nil {
+		return ChirpFilters{}, err
+	}
+	chirpFilters.HasMedia = hasMedia
+
+	switch sortBy := query.Get("sort_by"); sortBy {
+	case "":
+	case "created_at", "updated_at":
+		chirpFilters.SortBy = sortBy
+	default:
+		return ChirpFilters{}, &InvalidParameterError{Parameter: "sort_by", Reason: "must be created_at or updated_at"}
+	}
+	switch sortOrder := query.Get("sort"); sortOrder {
+	case "", "asc":
+	case "desc":
+		chirpFilters.SortDesc = true
+	default:
+		return ChirpFilters{}, &InvalidParameterError{Parameter: "sort", Reason: "must be asc or desc"}
+	}
+
+	return chirpFilters, nil
+}
+
+func parseTime(query url.Values, parameter string) (*time.Time, error) {
+	rawTime := query.Get(parameter)
+	if rawTime == "" {
+		return nil, nil
+	}
+	parsedTime, err := time.Parse(time.RFC3339, rawTime)
+	if err != nil {
+		return nil, &InvalidParameterError{Parameter: parameter, Reason: "must be an RFC 3339 timestamp"}
+	}
+	parsedTime = parsedTime.UTC()
+	return &parsedTime, nil
+}
+
+func parseBool(query url.Values, parameter string) (*bool, error) {
+	rawBool := query.Get(parameter)
+	if rawBool == "" {
+		return nil, nil
+	}
+	parsedBool, err := strconv.ParseBool(rawBool)
+	if err != nil {
+		return nil, &InvalidParameterError{Parameter: parameter, Reason: "must be true or false"}
+	}
+	return &parsedBool, nil
+}
@@ -0,0 +1,51 @@
+package length
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"github.com/rivo/uniseg"
+)
+
+// URLWeight is what every link counts as, however long it really is, so
+// sharing a long link costs the same as a short one
+const URLWeight = 23
+
+var urlPattern = regexp.MustCompile(`https?://\S+`)
+
+// Normalize trims a chirp body, collapses runs of spaces and tabs into a
+// single space and allows at most one blank line between lines
+func Normalize(body string) string {
+	body = strings.ReplaceAll(body, "\r\n", "\n")
+	lines := strings.Split(body, "\n")
+	for index, line := range lines {
+		lines[index] = strings.Join(strings.FieldsFunc(line, isHorizontalSpace), " ")
+	}
+	normalized := strings.Join(lines, "\n")
+	for strings.Contains(normalized, "\n\n\n") {
+		normalized = strings.ReplaceAll(normalized, "\n\n\n", "\n\n")
+	}
+	return strings.TrimSpace(normalized)
+}
+
+// Count is the length of a chirp body as users see it: one per grapheme
+// cluster, so an emoji or a CJK character counts once whatever its size in
+// bytes, with every link counting as URLWeight
+func Count(body string) int {
+	count := 0
+	previousEnd := 0
+	for _, location := range urlPattern.FindAllStringIndex(body, -1) {
+		// Sentence punctuation after a link is not part of it
+		end := location[1]
+		for end > location[0] && strings.ContainsRune(".,!?;:'\")", rune(body[end-1])) {
+			end--
+		}
+		count += uniseg.GraphemeClusterCount(body[previousEnd:location[0]]) + URLWeight
+		previousEnd = end
+	}
+	return count + uniseg.GraphemeClusterCount(body[previousEnd:])
+}
+
+func isHorizontalSpace(r rune) bool {
+	return unicode.IsSpace(r) && r != '\n'
+}
@@ -0,0 +1,96 @@
+package media
+
+import (
+	"image"
+	"math"
+	"strings"
+)
+
+const base83Characters = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+// Blurhash encodes img as a blurhash string (https://blurha.sh) made of
+// xComponents by yComponents colour components. img should already be
+// small, every component walks every pixel.
+func Blurhash(img image.Image, xComponents, yComponents int) string {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	factors := make([][3]float64, 0, xComponents*yComponents)
+
+	for y := 0; y < yComponents; y++ {
+		for x := 0; x < xComponents; x++ {
+			normalisation := 2.0
+			if x == 0 && y == 0 {
+				normalisation = 1.0
+			}
+			factor := [3]float64{}
+			for pixelY := 0; pixelY < height; pixelY++ {
+				for pixelX := 0; pixelX < width; pixelX++ {
+					basis := math.Cos(math.Pi*float64(x)*float64(pixelX)/float64(width)) *
+						math.Cos(math.Pi*float64(y)*float64(pixelY)/float64(height))
+					r, g, b, _ := img.At(bounds.Min.X+pixelX, bounds.Min.Y+pixelY).RGBA()
+					factor[0] += basis * sRGBToLinear(r>>8)
+					factor[1] += basis * sRGBToLinear(g>>8)
+					factor[2] += basis * sRGBToLinear(b>>8)
+				}
+			}
+			scale := normalisation / float64(width*height)
+			factors = append(factors, [3]float64{factor[0] * scale, factor[1] * scale, factor[2] * scale})
+		}
+	}
+
+	hash := strings.Builder{}
+	hash.WriteString(encodeBase83((xComponents-1)+(yComponents-1)*9, 1))
+
+	maximumValue := 1.0
+	if len(factors) > 1 {
+		actualMaximumValue := 0.0
+		for _, factor := range factors[1:] {
+			actualMaximumValue = math.Max(actualMaximumValue, math.Max(math.Abs(factor[0]), math.Max(math.Abs(factor[1]), math.Abs(factor[2]))))
+		}
+		quantisedMaximumValue := int(math.Max(0, math.Min(82, math.Floor(actualMaximumValue*166-0.5))))
+		maximumValue = float64(quantisedMaximumValue+1) / 166
+		hash.WriteString(encodeBase83(quantisedMaximumValue, 1))
+	} else {
+		hash.WriteString(encodeBase83(0, 1))
+	}
+
+	dc := factors[0]
+	hash.WriteString(encodeBase83(linearToSRGB(dc[0])<<16+linearToSRGB(dc[1])<<8+linearToSRGB(dc[2]), 4))
+	for _, factor := range factors[1:] {
+		quantised := [3]int{}
+		for index, value := range factor {
+			quantised[index] = int(math.Max(0, math.Min(18, math.Floor(signPow(value/maximumValue, 0.5)*9+9.5))))
+		}
+		hash.WriteString(encodeBase83(quantised[0]*19*19+quantised[1]*19+quantised[2], 2))
+	}
+	return hash.String()
+}
+
+func encodeBase83(value, length int) string {
+	encoded := make([]byte, length)
+	for index := 1; index <= length; index++ {
+		digit := (value / int(math.Pow(83, float64(length-index)))) % 83
+		encoded[index-1] = base83Characters[digit]
+	}
+	return string(encoded)
+}
+
+func sRGBToLinear(value uint32) float64 {
+	v := float64(value) / 255
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(value float64) int {
+	v := math.Max(0, math.Min(1, value))
+	if v <= 0.0031308 {
+		return int(v*12.92*255 + 0.5)
+	}
+	return int((1.055*math.Pow(v, 1/2.4)-0.055)*255 + 0.5)
+}
+
+func signPow(value, exponent float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(value), exponent), value)
+}
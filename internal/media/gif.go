@@ -0,0 +1,64 @@
+package media
+
+// gifFrames walks the block structure of a GIF without decoding any pixels
+// and returns how many frames it has and how many pixels they add up to, so
+// an animation can be turned away before DecodeAll allocates every frame.
+// Data that ends early is counted up to where it stops, the decoder is left
+// to reject it.
+func gifFrames(data []byte) (int, int, error) {
+	const headerLength = 13
+	if len(data) < headerLength {
+		return 0, 0, ErrUndecodable
+	}
+	position := headerLength
+	// A global colour table follows the screen descriptor when its flag is set
+	if flags := data[10]; flags&0x80 != 0 {
+		position += 3 << ((flags & 0x07) + 1)
+	}
+
+	frames, pixels := 0, 0
+	for position < len(data) {
+		introducer := data[position]
+		position++
+		switch introducer {
+		case 0x21:
+			// Extension label, then its data sub-blocks
+			position++
+		case 0x2C:
+			if position + 9 > len(data) {
+				return frames, pixels, nil
+			}
+			width := int(data[position+4]) | int(data[position+5])<<8
+			height := int(data[position+6]) | int(data[position+7])<<8
+			flags := data[position+8]
+			position += 9
+			if flags&0x80 != 0 {
+				position += 3 << ((flags & 0x07) + 1)
+			}
+			frames++
+			pixels += width * height
+			// LZW minimum code size, then the image data sub-blocks
+			position++
+		case 0x3B:
+			return frames, pixels, nil
+		default:
+			return 0, 0, ErrUndecodable
+		}
+		position = skipSubBlocks(data, position)
+	}
+	return frames, pixels, nil
+}
+
+// skipSubBlocks returns the position just past a run of length prefixed
+// sub-blocks and the empty block that ends it
+func skipSubBlocks(data []byte, position int) int {
+	for position < len(data) {
+		blockLength := int(data[position])
+		position++
+		if blockLength == 0 {
+			break
+		}
+		position += blockLength
+	}
+	return position
+}
@@ -0,0 +1,18 @@
+package media
+
+const MaxUploadBytes = 5 << 20
+const MaxPerChirp = 4
+
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png": ".png",
+	"image/gif": ".gif",
+	"image/webp": ".webp",
+}
+
+// ExtensionFor reports the file extension stored media of contentType gets,
+// and false when that type cannot be uploaded at all
+func ExtensionFor(contentType string) (string, bool) {
+	extension, ok := extensions[contentType]
+	return extension, ok
+}
@@ -0,0 +1,114 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/draw"
+)
+
+// jpegOrientation reads the EXIF orientation tag out of a JPEG, returning 1
+// (no transform needed) when there is none. Re-encoding drops EXIF, so the
+// rotation it describes has to be applied to the pixels first.
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	offset := 2
+	for offset+4 <= len(data) {
+		if data[offset] != 0xFF {
+			return 1
+		}
+		marker := data[offset+1]
+		segmentLength := int(binary.BigEndian.Uint16(data[offset+2 : offset+4]))
+		if segmentLength < 2 || offset+2+segmentLength > len(data) {
+			return 1
+		}
+		segment := data[offset+4 : offset+2+segmentLength]
+		// Start of scan means the metadata segments are over
+		if marker == 0xDA {
+			return 1
+		}
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		offset += 2 + segmentLength
+	}
+	return 1
+}
+
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var byteOrder binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		byteOrder = binary.LittleEndian
+	case "MM":
+		byteOrder = binary.BigEndian
+	default:
+		return 1
+	}
+	ifdOffset := int(byteOrder.Uint32(tiff[4:8]))
+	if ifdOffset+2 > len(tiff) {
+		return 1
+	}
+	entryCount := int(byteOrder.Uint16(tiff[ifdOffset : ifdOffset+2]))
+	for index := 0; index < entryCount; index++ {
+		entryOffset := ifdOffset + 2 + index*12
+		if entryOffset+12 > len(tiff) {
+			return 1
+		}
+		if byteOrder.Uint16(tiff[entryOffset:entryOffset+2]) == 0x0112 {
+			orientation := int(byteOrder.Uint16(tiff[entryOffset+8 : entryOffset+10]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+			return orientation
+		}
+	}
+	return 1
+}
+
+// applyOrientation returns img transformed the way EXIF orientation says it
+// should be displayed
+func applyOrientation(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	// Orientations 5 to 8 swap the axes
+	outputWidth, outputHeight := width, height
+	if orientation >= 5 {
+		outputWidth, outputHeight = height, width
+	}
+	source := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(source, source.Bounds(), img, bounds.Min, draw.Src)
+	output := image.NewNRGBA(image.Rect(0, 0, outputWidth, outputHeight))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var outputX, outputY int
+			switch orientation {
+			case 2:
+				outputX, outputY = width-1-x, y
+			case 3:
+				outputX, outputY = width-1-x, height-1-y
+			case 4:
+				outputX, outputY = x, height-1-y
+			case 5:
+				outputX, outputY = y, x
+			case 6:
+				outputX, outputY = height-1-y, x
+			case 7:
+				outputX, outputY = height-1-y, width-1-x
+			case 8:
+				outputX, outputY = y, width-1-x
+			}
+			output.SetNRGBA(outputX, outputY, source.NRGBAAt(x, y))
+		}
+	}
+	return output
+}
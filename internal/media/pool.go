@@ -0,0 +1,52 @@
+package media
+
+import (
+	"context"
+	"sync"
+)
+
+// WorkerPool runs jobs on a fixed number of goroutines behind a bounded
+// queue, so a burst of uploads queues up or is turned away instead of
+// spawning unbounded work
+type WorkerPool struct {
+	workers int
+	jobs chan func(ctx context.Context)
+}
+
+func NewWorkerPool(workers, queueSize int) *WorkerPool {
+	return &WorkerPool{
+		workers: max(1, workers),
+		jobs: make(chan func(ctx context.Context), queueSize),
+	}
+}
+
+// Submit queues job without blocking and reports false when the queue is full
+func (p *WorkerPool) Submit(job func(ctx context.Context)) bool {
+	select {
+	case p.jobs <- job:
+		return true
+	default:
+		return false
+	}
+}
+
+// Run starts the workers and blocks until ctx is done and every job already
+// running has returned. Jobs still queued at that point are dropped.
+func (p *WorkerPool) Run(ctx context.Context) {
+	waitGroup := sync.WaitGroup{}
+	for range p.workers {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-p.jobs:
+					job(ctx)
+				}
+			}
+		}()
+	}
+	waitGroup.Wait()
+}
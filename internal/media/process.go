@@ -0,0 +1,199 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// Uploads are decoded in full, so their size in pixels, and for GIFs the
+// number and total size of their frames, is capped before any pixel memory
+// gets allocated
+const MaxDimension = 8192
+const MaxPixels = 16 << 20
+const MaxGIFFrames = 200
+const MaxGIFPixels = 64 << 20
+
+const jpegQuality = 85
+const blurhashSide = 32
+
+var ErrUndecodable = errors.New("media could not be decoded")
+var ErrDimensionsTooLarge = errors.New("media dimensions exceed the limit")
+
+// Thumbnail is a variant whose longest side is scaled down to at most MaxSide
+type Thumbnail struct {
+	Name string
+	MaxSide int
+}
+
+var Thumbnails = []Thumbnail{
+	{Name: "small", MaxSide: 150},
+	{Name: "medium", MaxSide: 600},
+	{Name: "large", MaxSide: 1200},
+}
+
+// Variant is one re-encoded rendition of an upload, ready to be stored
+type Variant struct {
+	Name string
+	Data []byte
+	ContentType string
+	Extension string
+	Width int
+	Height int
+}
+
+// Processed is everything derived from an upload. None of it carries the
+// metadata of the original bytes, the pixels are all that survive.
+type Processed struct {
+	Original Variant
+	Thumbnails []Variant
+	Blurhash string
+}
+
+// CheckDimensions reads only the header of data, so it is cheap enough to
+// call on the request goroutine before an upload is queued
+func CheckDimensions(data []byte) error {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return ErrUndecodable
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return ErrUndecodable
+	}
+	if config.Width > MaxDimension || config.Height > MaxDimension || config.Width * config.Height > MaxPixels {
+		return ErrDimensionsTooLarge
+	}
+	// Every frame of a GIF is decoded, so the frames are capped as a whole
+	if format == "gif" {
+		frames, pixels, err := gifFrames(data)
+		if err != nil {
+			return err
+		}
+		if frames > MaxGIFFrames || pixels > MaxGIFPixels {
+			return ErrDimensionsTooLarge
+		}
+	}
+	return nil
+}
+
+// Process decodes data, re-encodes it and renders its thumbnails and
+// blurhash. GIFs keep their animation in the original variant, every other
+// format is flattened to a single frame with its EXIF orientation applied.
+func Process(data []byte) (Processed, error) {
+	if err := CheckDimensions(data); err != nil {
+		return Processed{}, err
+	}
+
+	_, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Processed{}, ErrUndecodable
+	}
+
+	var original Variant
+	var img image.Image
+	if format == "gif" {
+		animation, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil || len(animation.Image) == 0 {
+			return Processed{}, ErrUndecodable
+		}
+		// Only the frames, their timing and the loop count are written back out
+		sanitised := &gif.GIF{
+			Image: animation.Image,
+			Delay: animation.Delay,
+			Disposal: animation.Disposal,
+			LoopCount: animation.LoopCount,
+			Config: animation.Config,
+			BackgroundIndex: animation.BackgroundIndex,
+		}
+		encoded := bytes.Buffer{}
+		if err := gif.EncodeAll(&encoded, sanitised); err != nil {
+			return Processed{}, err
+		}
+		img = animation.Image[0]
+		original = Variant{
+			Name: "original",
+			Data: encoded.Bytes(),
+			ContentType: "image/gif",
+			Extension: ".gif",
+			Width: animation.Config.Width,
+			Height: animation.Config.Height,
+		}
+	} else {
+		img, _, err = image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return Processed{}, ErrUndecodable
+		}
+		if format == "jpeg" {
+			img = applyOrientation(img, jpegOrientation(data))
+		}
+		original, err = encodeVariant("original", img, format == "jpeg")
+		if err != nil {
+			return Processed{}, err
+		}
+	}
+
+	processed := Processed{
+		Original: original,
+		Thumbnails: []Variant{},
+	}
+	asJPEG := original.ContentType == "image/jpeg"
+	for _, thumbnail := range Thumbnails {
+		variant, err := encodeVariant(thumbnail.Name, scaleToFit(img, thumbnail.MaxSide), asJPEG)
+		if err != nil {
+			return Processed{}, err
+		}
+		processed.Thumbnails = append(processed.Thumbnails, variant)
+	}
+	processed.Blurhash = Blurhash(scaleToFit(img, blurhashSide), 4, 3)
+	return processed, nil
+}
+
+// encodeVariant writes img as a JPEG when asked to, PNG otherwise, so
+// transparency is kept for everything that was not a JPEG to begin with
+func encodeVariant(name string, img image.Image, asJPEG bool) (Variant, error) {
+	encoded := bytes.Buffer{}
+	variant := Variant{
+		Name: name,
+		Width: img.Bounds().Dx(),
+		Height: img.Bounds().Dy(),
+	}
+	if asJPEG {
+		if err := jpeg.Encode(&encoded, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return Variant{}, err
+		}
+		variant.ContentType = "image/jpeg"
+		variant.Extension = ".jpg"
+	} else {
+		if err := png.Encode(&encoded, img); err != nil {
+			return Variant{}, err
+		}
+		variant.ContentType = "image/png"
+		variant.Extension = ".png"
+	}
+	variant.Data = encoded.Bytes()
+	return variant, nil
+}
+
+// scaleToFit shrinks img until its longest side is at most maxSide, images
+// already small enough are never scaled up
+func scaleToFit(img image.Image, maxSide int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= maxSide && height <= maxSide {
+		return img
+	}
+	scaledWidth, scaledHeight := maxSide, maxSide
+	if width > height {
+		scaledHeight = max(1, height * maxSide / width)
+	} else {
+		scaledWidth = max(1, width * maxSide / height)
+	}
+	scaled := image.NewNRGBA(image.Rect(0, 0, scaledWidth, scaledHeight))
+	draw.CatmullRom.Scale(scaled, scaled.Bounds(), img, bounds, draw.Src, nil)
+	return scaled
+}
@@ -0,0 +1,88 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Storage is where uploaded media bytes live, the database only keeps keys
+type Storage interface {
+	Save(ctx context.Context, key string, reader io.Reader) error
+	Open(ctx context.Context, key string) (io.ReadSeekCloser, error)
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+}
+
+// LocalStorage writes files under Root and hands out URLs under URLPrefix,
+// which should be a path the server answers by reading files back through
+// Open once it has checked the viewer may see them
+type LocalStorage struct {
+	Root string
+	URLPrefix string
+}
+
+func NewLocalStorage(root, urlPrefix string) (*LocalStorage, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+	return &LocalStorage{
+		Root: root,
+		URLPrefix: strings.TrimSuffix(urlPrefix, "/"),
+	}, nil
+}
+
+func (l *LocalStorage) path(key string) (string, error) {
+	if key == "" || key != filepath.Base(key) || strings.HasPrefix(key, ".") {
+		return "", errors.New("invalid media key")
+	}
+	return filepath.Join(l.Root, key), nil
+}
+
+func (l *LocalStorage) Save(ctx context.Context, key string, reader io.Reader) error {
+	filePath, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	// Bytes go to a temporary file first so a failed upload never leaves a
+	// half written file at a servable path
+	tempFile, err := os.CreateTemp(l.Root, ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempFile.Name())
+	if _, err := io.Copy(tempFile, reader); err != nil {
+		tempFile.Close()
+		return err
+	}
+	if err := tempFile.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tempFile.Name(), filePath)
+}
+
+func (l *LocalStorage) Open(ctx context.Context, key string) (io.ReadSeekCloser, error) {
+	filePath, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(filePath)
+}
+
+func (l *LocalStorage) Delete(ctx context.Context, key string) error {
+	filePath, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (l *LocalStorage) URL(key string) string {
+	return l.URLPrefix + "/" + key
+}
@@ -0,0 +1,38 @@
+package mutes
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	Account = "account"
+	Keyword = "keyword"
+	Hashtag = "hashtag"
+)
+
+const MaxTermLength = 100
+
+var ErrInvalidTerm = errors.New("invalid mute term")
+
+// NormalizeTerm puts a keyword or hashtag in the form it is stored and
+// matched in: lowercased, whitespace collapsed and without a leading #
+func NormalizeTerm(kind, term string) (string, error) {
+	normalized := strings.Join(strings.Fields(strings.ToLower(term)), " ")
+	if kind == Hashtag {
+		normalized = strings.TrimPrefix(normalized, "#")
+		if normalized == "" || strings.IndexFunc(normalized, func(r rune) bool { return !isWordRune(r) }) != -1 {
+			return "", ErrInvalidTerm
+		}
+	}
+	if normalized == "" || utf8.RuneCountInString(normalized) > MaxTermLength {
+		return "", ErrInvalidTerm
+	}
+	return normalized, nil
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
@@ -0,0 +1,106 @@
+package pagination
+
+import (
+	"errors"
+	"strings"
+	"strconv"
+	"fmt"
+	"net/url"
+	"time"
+	"encoding/base64"
+	"github.com/google/uuid"
+)
+
+const DefaultLimit = 20
+const MaxLimit = 100
+
+// MaxOffset bounds offset paging, past it a client should narrow its query
+// rather than skip ever more rows
+const MaxOffset = 10000
+
+type Cursor struct {
+	Time time.Time
+	ID uuid.UUID
+}
+
+func EncodeCursor(cursor Cursor) string {
+	rawCursor := cursor.Time.UTC().Format(time.RFC3339Nano) + "|" + cursor.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(rawCursor))
+}
+
+func DecodeCursor(encodedCursor string) (Cursor, error) {
+	rawCursorInBytes, err := base64.RawURLEncoding.DecodeString(encodedCursor)
+	if err != nil {
+		return Cursor{}, errors.New("cursor is not valid base64")
+	}
+	cursorParts := strings.Split(string(rawCursorInBytes), "|")
+	if len(cursorParts) != 2 {
+		return Cursor{}, errors.New("cursor is malformed")
+	}
+	cursorTime, err := time.Parse(time.RFC3339Nano, cursorParts[0])
+	if err != nil {
+		return Cursor{}, errors.New("cursor timestamp is malformed")
+	}
+	cursorID, err := uuid.Parse(cursorParts[1])
+	if err != nil {
+		return Cursor{}, errors.New("cursor id is malformed")
+	}
+	return Cursor{
+		Time: cursorTime,
+		ID: cursorID,
+	}, nil
+}
+
+// EncodePathCursor wraps a sort key that is a string rather than a time and
+// an id, such as a reply's place in its thread
+func EncodePathCursor(path string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(path))
+}
+
+func DecodePathCursor(encodedCursor string) (string, error) {
+	pathInBytes, err := base64.RawURLEncoding.DecodeString(encodedCursor)
+	if err != nil {
+		return "", errors.New("cursor is not valid base64")
+	}
+	if len(pathInBytes) == 0 {
+		return "", errors.New("cursor is malformed")
+	}
+	return string(pathInBytes), nil
+}
+
+func ParseLimit(rawLimit string) (int32, error) {
+	if rawLimit == "" {
+		return DefaultLimit, nil
+	}
+	limit, err := strconv.Atoi(rawLimit)
+	if err != nil {
+		return 0, errors.New("limit is not a number")
+	}
+	if limit < 1 || limit > MaxLimit {
+		return 0, errors.New("limit is out of range")
+	}
+	return int32(limit), nil
+}
+
+func ParseOffset(rawOffset string) (int32, error) {
+	if rawOffset == "" {
+		return 0, nil
+	}
+	offset, err := strconv.ParseInt(rawOffset, 10, 32)
+	if err != nil {
+		return 0, errors.New("offset is not a number")
+	}
+	if offset < 0 || offset > MaxOffset {
+		return 0, errors.New("offset is out of range")
+	}
+	return int32(offset), nil
+}
+
+func NextLink(path string, query url.Values, parameter, value string) string {
+	nextQuery := url.Values{}
+	for key, values := range query {
+		nextQuery[key] = values
+	}
+	nextQuery.Set(parameter, value)
+	return fmt.Sprintf(`<%s?%s>; rel="next"`, path, nextQuery.Encode())
+}
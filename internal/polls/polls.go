@@ -0,0 +1,53 @@
+package polls
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const MinOptions = 2
+const MaxOptions = 4
+const MaxOptionLength = 25
+const MinDuration = 5 * time.Minute
+const MaxDuration = 7 * 24 * time.Hour
+
+var ErrOptionCount = errors.New("polls need between 2 and 4 options")
+var ErrInvalidOption = errors.New("poll options must be non-empty, unique and short")
+var ErrInvalidClosingTime = errors.New("poll closing time is out of range")
+
+// Validate checks a poll before it is stored and returns its options with
+// surrounding whitespace trimmed. opensAt is when the chirp carrying the poll
+// goes out, the poll has to stay open for a sensible time after that.
+func Validate(options []string, closesAt, opensAt time.Time) ([]string, error) {
+	if len(options) < MinOptions || len(options) > MaxOptions {
+		return nil, ErrOptionCount
+	}
+	trimmedOptions := []string{}
+	seenOptions := map[string]struct{}{}
+	for _, option := range options {
+		trimmedOption := strings.TrimSpace(option)
+		if trimmedOption == "" || utf8.RuneCountInString(trimmedOption) > MaxOptionLength {
+			return nil, ErrInvalidOption
+		}
+		// Options differing only in case would read as the same answer
+		if _, ok := seenOptions[strings.ToLower(trimmedOption)]; ok {
+			return nil, ErrInvalidOption
+		}
+		seenOptions[strings.ToLower(trimmedOption)] = struct{}{}
+		trimmedOptions = append(trimmedOptions, trimmedOption)
+	}
+
+	openFor := closesAt.Sub(opensAt)
+	if openFor < MinDuration || openFor > MaxDuration {
+		return nil, ErrInvalidClosingTime
+	}
+	return trimmedOptions, nil
+}
+
+// ShowTallies reports whether a viewer gets to see a poll's counts, which
+// stay hidden until they have voted so earlier votes can't sway theirs
+func ShowTallies(hasVoted bool, closesAt, now time.Time) bool {
+	return hasVoted || !now.Before(closesAt)
+}
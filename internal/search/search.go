@@ -0,0 +1,55 @@
+package search
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+const MaxTerms = 16
+
+// BuildTSQuery turns a user search string into to_tsquery syntax. Quoted text
+// becomes a phrase and a trailing * makes a word match as a prefix, anything
+// else that could be read as a tsquery operator is dropped.
+func BuildTSQuery(rawQuery string) (string, error) {
+	terms := []string{}
+	for index, segment := range strings.Split(rawQuery, `"`) {
+		// Segments at odd indexes sit between a pair of quotes
+		if index%2 == 1 {
+			if phrase := buildPhrase(segment); phrase != "" {
+				terms = append(terms, phrase)
+			}
+			continue
+		}
+		for _, word := range strings.Fields(segment) {
+			if term := buildPhrase(word); term != "" {
+				terms = append(terms, term)
+			}
+		}
+	}
+
+	if len(terms) == 0 {
+		return "", errors.New("search query has no searchable words")
+	}
+	if len(terms) > MaxTerms {
+		return "", errors.New("search query has too many words")
+	}
+	return strings.Join(terms, " & "), nil
+}
+
+func buildPhrase(rawPhrase string) string {
+	isPrefix := strings.HasSuffix(strings.TrimSpace(rawPhrase), "*")
+	words := strings.FieldsFunc(rawPhrase, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return ""
+	}
+	if isPrefix {
+		words[len(words)-1] = words[len(words)-1] + ":*"
+	}
+	if len(words) == 1 {
+		return words[0]
+	}
+	return "(" + strings.Join(words, " <-> ") + ")"
+}
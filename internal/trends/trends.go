@@ -0,0 +1,176 @@
+package trends
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+const MaxTrends = 50
+
+// Clock is how the tracker reads the time, tests swap in a clock they control
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now().UTC()
+}
+
+func SystemClock() Clock {
+	return systemClock{}
+}
+
+type Trend struct {
+	Tag string
+	Score float64
+	ChirpCount int
+}
+
+type event struct {
+	tag string
+	at time.Time
+}
+
+// Tracker keeps recent hashtag uses in memory and periodically turns them
+// into a ranked list per window. A use counts fully when it happens and its
+// weight halves every quarter of the window, so a burst of chirps outranks
+// the same number spread evenly across the window.
+type Tracker struct {
+	mu sync.RWMutex
+	clock Clock
+	windows []time.Duration
+	events []event
+	excluded map[string]struct{}
+	snapshots map[time.Duration][]Trend
+}
+
+func NewTracker(clock Clock, windows []time.Duration) *Tracker {
+	return &Tracker{
+		clock: clock,
+		windows: windows,
+		events: []event{},
+		excluded: map[string]struct{}{},
+		snapshots: map[time.Duration][]Trend{},
+	}
+}
+
+func (t *Tracker) Windows() []time.Duration {
+	return t.windows
+}
+
+// Record adds one chirp's hashtags, at is when the chirp was created
+func (t *Tracker) Record(tags []string, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	seen := map[string]struct{}{}
+	for _, tag := range tags {
+		// A chirp repeating a tag only counts once
+		if _, ok := seen[tag]; ok {
+			continue
+		}
+		seen[tag] = struct{}{}
+		t.events = append(t.events, event{tag: tag, at: at})
+	}
+}
+
+func (t *Tracker) SetExclusions(tags []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.excluded = map[string]struct{}{}
+	for _, tag := range tags {
+		t.excluded[tag] = struct{}{}
+	}
+}
+
+func (t *Tracker) Exclude(tag string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.excluded[tag] = struct{}{}
+}
+
+func (t *Tracker) Include(tag string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.excluded, tag)
+}
+
+// Trending returns the ranking computed by the last Refresh, with tags
+// excluded since then already left out
+func (t *Tracker) Trending(window time.Duration) ([]Trend, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	isTracked := false
+	for _, trackedWindow := range t.windows {
+		isTracked = isTracked || trackedWindow == window
+	}
+	if !isTracked {
+		return nil, false
+	}
+	returnTrends := []Trend{}
+	for _, trend := range t.snapshots[window] {
+		if _, ok := t.excluded[trend.Tag]; !ok {
+			returnTrends = append(returnTrends, trend)
+		}
+	}
+	return returnTrends, true
+}
+
+// Refresh drops uses older than the longest window and recomputes every
+// window's ranking
+func (t *Tracker) Refresh() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.clock.Now()
+
+	longestWindow := time.Duration(0)
+	for _, window := range t.windows {
+		longestWindow = max(longestWindow, window)
+	}
+	keptEvents := []event{}
+	for _, recordedEvent := range t.events {
+		if now.Sub(recordedEvent.at) <= longestWindow {
+			keptEvents = append(keptEvents, recordedEvent)
+		}
+	}
+	t.events = keptEvents
+
+	for _, window := range t.windows {
+		halfLife := window / 4
+		trendsByTag := map[string]*Trend{}
+		for _, recordedEvent := range t.events {
+			age := now.Sub(recordedEvent.at)
+			if age > window {
+				continue
+			}
+			if _, ok := t.excluded[recordedEvent.tag]; ok {
+				continue
+			}
+			// Uses stamped slightly in the future count as happening now
+			age = max(age, 0)
+			if _, ok := trendsByTag[recordedEvent.tag]; !ok {
+				trendsByTag[recordedEvent.tag] = &Trend{Tag: recordedEvent.tag}
+			}
+			trendsByTag[recordedEvent.tag].Score += math.Pow(0.5, float64(age)/float64(halfLife))
+			trendsByTag[recordedEvent.tag].ChirpCount++
+		}
+
+		snapshot := []Trend{}
+		for _, trend := range trendsByTag {
+			snapshot = append(snapshot, *trend)
+		}
+		sort.Slice(snapshot, func(i, j int) bool {
+			if snapshot[i].Score != snapshot[j].Score {
+				return snapshot[i].Score > snapshot[j].Score
+			}
+			return snapshot[i].Tag < snapshot[j].Tag
+		})
+		if len(snapshot) > MaxTrends {
+			snapshot = snapshot[:MaxTrends]
+		}
+		t.snapshots[window] = snapshot
+	}
+}
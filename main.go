@@ -5,10 +5,20 @@ import (
 	"log"
 	"os"
 	"database/sql"
+	"time"
+	"context"
+	"syscall"
+	"runtime"
+	"strconv"
+	"os/signal"
+	"sync"
+	"errors"
 	_ "github.com/lib/pq"
 	"github.com/joho/godotenv"
 	"github.com/junwei890/chirpy/state"
 	"github.com/junwei890/chirpy/internal/database"
+	"github.com/junwei890/chirpy/internal/trends"
+	"github.com/junwei890/chirpy/internal/media"
 )
 
 func main() {
@@ -26,11 +36,87 @@ func main() {
 
 	webhookKey := os.Getenv("POLKA_KEY")
 
+	adminKey := os.Getenv("ADMIN_KEY")
+
+	chirpEditWindow, err := time.ParseDuration(os.Getenv("CHIRP_EDIT_WINDOW"))
+	if err != nil {
+		chirpEditWindow = time.Hour
+	}
+	trashRetention, err := time.ParseDuration(os.Getenv("CHIRP_TRASH_RETENTION"))
+	if err != nil {
+		trashRetention = 30 * 24 * time.Hour
+	}
+	maxPins, err := strconv.Atoi(os.Getenv("MAX_PINS"))
+	if err != nil || maxPins < 0 {
+		maxPins = 1
+	}
+	maxPinsChirpyRed, err := strconv.Atoi(os.Getenv("MAX_PINS_CHIRPY_RED"))
+	if err != nil || maxPinsChirpyRed < 0 {
+		maxPinsChirpyRed = 5
+	}
+
+	maxChirpLength, err := strconv.Atoi(os.Getenv("MAX_CHIRP_LENGTH"))
+	if err != nil || maxChirpLength <= 0 {
+		maxChirpLength = 140
+	}
+	maxChirpLengthChirpyRed, err := strconv.Atoi(os.Getenv("MAX_CHIRP_LENGTH_CHIRPY_RED"))
+	if err != nil || maxChirpLengthChirpyRed <= 0 {
+		maxChirpLengthChirpyRed = 280
+	}
+
+	timelineFanoutLimit, err := strconv.Atoi(os.Getenv("TIMELINE_FANOUT_LIMIT"))
+	if err != nil || timelineFanoutLimit < 0 {
+		timelineFanoutLimit = 10000
+	}
+
+	mediaStorage, err := media.NewLocalStorage("./media", "/app/media")
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	ptrToAppState := &state.APIConfig{
+		PtrToDB: db,
 		PtrToQueries: dbQueries,
 		Platform: platform,
 		SecretKey: secretKey,
 		WebhookKey: webhookKey,
+		AdminKey: adminKey,
+		ChirpEditWindow: chirpEditWindow,
+		TrashRetention: trashRetention,
+		MaxPins: maxPins,
+		MaxPinsChirpyRed: maxPinsChirpyRed,
+		MaxChirpLength: maxChirpLength,
+		MaxChirpLengthChirpyRed: maxChirpLengthChirpyRed,
+		TimelineFanoutLimit: timelineFanoutLimit,
+		TimelineWake: make(chan struct{}, 1),
+		Trends: trends.NewTracker(trends.SystemClock(), []time.Duration{time.Hour, 24 * time.Hour}),
+		Storage: mediaStorage,
+		MediaWorkers: media.NewWorkerPool(runtime.NumCPU(), 32),
+	}
+
+	// Background workers stop when the process is told to shut down
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := ptrToAppState.LoadTrends(ctx); err != nil {
+		log.Println(err)
+	}
+	if err := dbQueries.FailStaleMedia(ctx); err != nil {
+		log.Println(err)
+	}
+	workers := sync.WaitGroup{}
+	for _, runWorker := range []func(){
+		func() { ptrToAppState.RunTrends(ctx, time.Minute) },
+		func() { ptrToAppState.MediaWorkers.Run(ctx) },
+		func() { ptrToAppState.RunScheduler(ctx, 15*time.Second) },
+		func() { ptrToAppState.RunTrashPurge(ctx, time.Hour) },
+		func() { ptrToAppState.RunPollCloser(ctx, 30*time.Second) },
+		func() { ptrToAppState.RunTimelineFanout(ctx, 5*time.Second) },
+	} {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			runWorker()
+		}()
 	}
 
 	const root = "."
@@ -43,13 +129,71 @@ func main() {
 	const postMetrics = "POST /admin/reset"
 	const postUsers = "POST /api/users"
 	const putUsers = "PUT /api/users"
+	const getPreferences = "GET /api/users/preferences"
+	const putPreferences = "PUT /api/users/preferences"
+	const getUserLikes = "GET /api/users/{userID}/likes"
+	const postFollow = "POST /api/users/{userID}/follow"
+	const deleteFollow = "DELETE /api/users/{userID}/follow"
+	const getFollowers = "GET /api/users/{userID}/followers"
+	const getFollowing = "GET /api/users/{userID}/following"
+	const getFollowRequests = "GET /api/follow_requests"
+	const postFollowRequestApprove = "POST /api/follow_requests/{userID}/approve"
+	const postFollowRequestReject = "POST /api/follow_requests/{userID}/reject"
+	const getHashtagChirps = "GET /api/hashtags/{tag}/chirps"
+	const getTrends = "GET /api/trends"
+	const getTrendExclusions = "GET /admin/trends/exclusions"
+	const putTrendExclusion = "PUT /admin/trends/exclusions/{tag}"
+	const deleteTrendExclusion = "DELETE /admin/trends/exclusions/{tag}"
 	const postLogin = "POST /api/login"
 	const postRefresh = "POST /api/refresh"
 	const postRevoke = "POST /api/revoke"
 	const postChirps = "POST /api/chirps"
 	const deleteChirps = "DELETE /api/chirps/{chirpID}"
+	const putChirps = "PUT /api/chirps/{chirpID}"
+	const getChirpRevisions = "GET /api/chirps/{chirpID}/revisions"
+	const getChirpThread = "GET /api/chirps/{chirpID}/thread"
+	const postRechirp = "POST /api/chirps/{chirpID}/rechirp"
+	const deleteRechirp = "DELETE /api/chirps/{chirpID}/rechirp"
+	const postPollVote = "POST /api/chirps/{chirpID}/poll/vote"
+	const getEvents = "GET /api/events"
+	const getTimeline = "GET /api/timeline"
+	const putChirpSensitivity = "PUT /api/chirps/{chirpID}/sensitivity"
+	const putBookmark = "PUT /api/chirps/{chirpID}/bookmark"
+	const deleteBookmark = "DELETE /api/chirps/{chirpID}/bookmark"
+	const getBookmarks = "GET /api/bookmarks"
+	const getBlocks = "GET /api/blocks"
+	const putBlock = "PUT /api/blocks/{userID}"
+	const deleteBlock = "DELETE /api/blocks/{userID}"
+	const getMutes = "GET /api/mutes"
+	const postMutes = "POST /api/mutes"
+	const deleteMute = "DELETE /api/mutes/{muteID}"
+	const putPin = "PUT /api/chirps/{chirpID}/pin"
+	const deletePin = "DELETE /api/chirps/{chirpID}/pin"
+	const getScheduledChirps = "GET /api/chirps/scheduled"
+	const putChirpSchedule = "PUT /api/chirps/{chirpID}/schedule"
+	const deleteChirpSchedule = "DELETE /api/chirps/{chirpID}/schedule"
+	const putReaction = "PUT /api/chirps/{chirpID}/reactions/{reaction}"
+	const deleteReaction = "DELETE /api/chirps/{chirpID}/reactions/{reaction}"
 	const getChirps = "GET /api/chirps"
 	const getChirpsByID = "GET /api/chirps/{chirpID}"
+	const getChirpsSearch = "GET /api/chirps/search"
+	const getTrash = "GET /api/trash"
+	const postTrashRestore = "POST /api/trash/{chirpID}/restore"
+	const getModerationChirp = "GET /api/moderation/chirps/{chirpID}"
+	const putModerationSensitive = "PUT /api/moderation/chirps/{chirpID}/sensitive"
+	const deleteModerationSensitive = "DELETE /api/moderation/chirps/{chirpID}/sensitive"
+	const putModerator = "PUT /admin/moderators/{userID}"
+	const deleteModerator = "DELETE /admin/moderators/{userID}"
+	const postDrafts = "POST /api/drafts"
+	const getDrafts = "GET /api/drafts"
+	const getDraft = "GET /api/drafts/{draftID}"
+	const putDraft = "PUT /api/drafts/{draftID}"
+	const deleteDraft = "DELETE /api/drafts/{draftID}"
+	const postDraftPublish = "POST /api/drafts/{draftID}/publish"
+	const postMedia = "POST /api/media"
+	const getMedia = "GET /api/media/{mediaID}"
+	// No method, so no request under the media directory reaches the file server
+	const getMediaFile = "/app/media/{storageKey...}"
 	const postRed = "POST /api/polka/webhooks"
 
 	requestMultiplexer := http.NewServeMux()
@@ -67,12 +211,79 @@ func main() {
 	// Chirp related
 	requestMultiplexer.HandleFunc(postChirps, ptrToAppState.PostChirps)
 	requestMultiplexer.HandleFunc(deleteChirps, ptrToAppState.DeleteChirps)
+	requestMultiplexer.HandleFunc(putChirps, ptrToAppState.PutChirps)
 	requestMultiplexer.HandleFunc(getChirps, ptrToAppState.GetChirps)
 	requestMultiplexer.HandleFunc(getChirpsByID, ptrToAppState.GetChirpsByID)
+	requestMultiplexer.HandleFunc(getChirpsSearch, ptrToAppState.GetChirpsSearch)
+	requestMultiplexer.HandleFunc(getChirpRevisions, ptrToAppState.GetChirpRevisions)
+	requestMultiplexer.HandleFunc(getChirpThread, ptrToAppState.GetChirpThread)
+	requestMultiplexer.HandleFunc(postRechirp, ptrToAppState.PostRechirp)
+	requestMultiplexer.HandleFunc(deleteRechirp, ptrToAppState.DeleteRechirp)
+	requestMultiplexer.HandleFunc(postPollVote, ptrToAppState.PostPollVote)
+	requestMultiplexer.HandleFunc(putChirpSensitivity, ptrToAppState.PutChirpSensitivity)
+	requestMultiplexer.HandleFunc(putBookmark, ptrToAppState.PutBookmark)
+	requestMultiplexer.HandleFunc(deleteBookmark, ptrToAppState.DeleteBookmark)
+	requestMultiplexer.HandleFunc(getBookmarks, ptrToAppState.GetBookmarks)
+	requestMultiplexer.HandleFunc(putPin, ptrToAppState.PutPin)
+	requestMultiplexer.HandleFunc(deletePin, ptrToAppState.DeletePin)
+	requestMultiplexer.HandleFunc(getScheduledChirps, ptrToAppState.GetScheduledChirps)
+	requestMultiplexer.HandleFunc(putChirpSchedule, ptrToAppState.PutChirpSchedule)
+	requestMultiplexer.HandleFunc(deleteChirpSchedule, ptrToAppState.DeleteChirpSchedule)
+	requestMultiplexer.HandleFunc(putReaction, ptrToAppState.PutReaction)
+	requestMultiplexer.HandleFunc(deleteReaction, ptrToAppState.DeleteReaction)
+
+	// Trash related
+	requestMultiplexer.HandleFunc(getTrash, ptrToAppState.GetTrash)
+	requestMultiplexer.HandleFunc(postTrashRestore, ptrToAppState.PostTrashRestore)
+
+	// Moderation related
+	requestMultiplexer.HandleFunc(getModerationChirp, ptrToAppState.GetModerationChirp)
+	requestMultiplexer.HandleFunc(putModerationSensitive, ptrToAppState.PutModerationSensitive)
+	requestMultiplexer.HandleFunc(deleteModerationSensitive, ptrToAppState.DeleteModerationSensitive)
+	requestMultiplexer.HandleFunc(putModerator, ptrToAppState.PutModerator)
+	requestMultiplexer.HandleFunc(deleteModerator, ptrToAppState.DeleteModerator)
+
+	// Draft related
+	requestMultiplexer.HandleFunc(postDrafts, ptrToAppState.PostDrafts)
+	requestMultiplexer.HandleFunc(getDrafts, ptrToAppState.GetDrafts)
+	requestMultiplexer.HandleFunc(getDraft, ptrToAppState.GetDraft)
+	requestMultiplexer.HandleFunc(putDraft, ptrToAppState.PutDraft)
+	requestMultiplexer.HandleFunc(deleteDraft, ptrToAppState.DeleteDraft)
+	requestMultiplexer.HandleFunc(postDraftPublish, ptrToAppState.PostDraftPublish)
+
+	// Media related
+	requestMultiplexer.HandleFunc(postMedia, ptrToAppState.PostMedia)
+	requestMultiplexer.HandleFunc(getMedia, ptrToAppState.GetMedia)
+	requestMultiplexer.HandleFunc(getMediaFile, ptrToAppState.GetMediaFile)
+
+	// Hashtag and trend related
+	requestMultiplexer.HandleFunc(getHashtagChirps, ptrToAppState.GetHashtagChirps)
+	requestMultiplexer.HandleFunc(getTrends, ptrToAppState.GetTrends)
+	requestMultiplexer.HandleFunc(getTrendExclusions, ptrToAppState.GetTrendExclusions)
+	requestMultiplexer.HandleFunc(putTrendExclusion, ptrToAppState.PutTrendExclusion)
+	requestMultiplexer.HandleFunc(deleteTrendExclusion, ptrToAppState.DeleteTrendExclusion)
 
 	// User related
+	requestMultiplexer.HandleFunc(getEvents, ptrToAppState.GetEvents)
+	requestMultiplexer.HandleFunc(getTimeline, ptrToAppState.GetTimeline)
+	requestMultiplexer.HandleFunc(getBlocks, ptrToAppState.GetBlocks)
+	requestMultiplexer.HandleFunc(putBlock, ptrToAppState.PutBlock)
+	requestMultiplexer.HandleFunc(deleteBlock, ptrToAppState.DeleteBlock)
+	requestMultiplexer.HandleFunc(getMutes, ptrToAppState.GetMutes)
+	requestMultiplexer.HandleFunc(postMutes, ptrToAppState.PostMutes)
+	requestMultiplexer.HandleFunc(deleteMute, ptrToAppState.DeleteMute)
 	requestMultiplexer.HandleFunc(postUsers, ptrToAppState.PostUsers)
 	requestMultiplexer.HandleFunc(putUsers, ptrToAppState.PutUsers)
+	requestMultiplexer.HandleFunc(getPreferences, ptrToAppState.GetPreferences)
+	requestMultiplexer.HandleFunc(putPreferences, ptrToAppState.PutPreferences)
+	requestMultiplexer.HandleFunc(getUserLikes, ptrToAppState.GetUserLikes)
+	requestMultiplexer.HandleFunc(postFollow, ptrToAppState.PostFollow)
+	requestMultiplexer.HandleFunc(deleteFollow, ptrToAppState.DeleteFollow)
+	requestMultiplexer.HandleFunc(getFollowers, ptrToAppState.GetFollowers)
+	requestMultiplexer.HandleFunc(getFollowing, ptrToAppState.GetFollowing)
+	requestMultiplexer.HandleFunc(getFollowRequests, ptrToAppState.GetFollowRequests)
+	requestMultiplexer.HandleFunc(postFollowRequestApprove, ptrToAppState.PostFollowRequestApprove)
+	requestMultiplexer.HandleFunc(postFollowRequestReject, ptrToAppState.PostFollowRequestReject)
 	requestMultiplexer.HandleFunc(postLogin, ptrToAppState.PostLogin)
 	requestMultiplexer.HandleFunc(postRefresh, ptrToAppState.PostRefresh)
 	requestMultiplexer.HandleFunc(postRevoke, ptrToAppState.PostRevoke)
@@ -84,8 +295,19 @@ func main() {
 		Addr: port,
 		Handler: requestMultiplexer,
 	}
-	if err := server.ListenAndServe(); err != nil {
-		log.Fatal(err)
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	// Requests already in flight get a while to finish before the server
+	// closes, then the workers are waited on so none stop mid batch
+	<-ctx.Done()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Println(err)
 	}
-	
+	workers.Wait()
 }
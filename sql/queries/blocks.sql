@@ -0,0 +1,35 @@
+-- name: CreateBlock :execrows
+INSERT INTO blocks (blocker_id, blocked_id, created_at)
+VALUES (
+	$1,
+	$2,
+	NOW()
+) ON CONFLICT DO NOTHING;
+
+-- name: DeleteBlock :execrows
+DELETE FROM blocks WHERE blocker_id = $1 AND blocked_id = $2;
+
+-- name: IsBlockedEitherWay :one
+SELECT EXISTS (
+	SELECT 1 FROM blocks
+	WHERE (blocker_id = sqlc.arg('user_id') AND blocked_id = sqlc.arg('other_id'))
+	OR (blocker_id = sqlc.arg('other_id') AND blocked_id = sqlc.arg('user_id'))
+);
+
+-- name: CountBlockedMentions :one
+-- Mentions in a chirp of users who have blocked its author
+SELECT COUNT(*) FROM chirp_mentions
+INNER JOIN chirps ON chirp_mentions.chirp_id = chirps.id
+INNER JOIN blocks ON blocks.blocker_id = chirp_mentions.user_id AND blocks.blocked_id = chirps.user_id
+WHERE chirp_mentions.chirp_id = $1;
+
+-- name: ListBlocks :many
+SELECT users.id, users.handle, blocks.created_at AS blocked_at FROM blocks
+INNER JOIN users ON blocks.blocked_id = users.id
+WHERE blocks.blocker_id = sqlc.arg('user_id')
+AND (
+	sqlc.narg('cursor_time')::timestamp IS NULL
+	OR (blocks.created_at, users.id) < (sqlc.narg('cursor_time')::timestamp, sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY blocks.created_at DESC, users.id DESC
+LIMIT sqlc.arg('row_limit');
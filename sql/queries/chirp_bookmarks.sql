@@ -0,0 +1,27 @@
+-- name: CreateBookmark :exec
+INSERT INTO chirp_bookmarks (user_id, chirp_id, created_at)
+VALUES (
+	$1,
+	$2,
+	NOW()
+) ON CONFLICT DO NOTHING;
+
+-- name: DeleteBookmark :exec
+DELETE FROM chirp_bookmarks WHERE user_id = $1 AND chirp_id = $2;
+
+-- name: ListBookmarkedChirps :many
+-- Bookmarks of chirps that are deleted but not yet purged stay stored, they
+-- are hidden until the chirp is restored or the purge cascades them away
+SELECT chirps.id, chirps.body, chirps.user_id, chirps.created_at, chirps.updated_at, chirps.in_reply_to, chirps.rechirp_of, chirps.quote_of, chirps.publish_at, chirps.content_warning, (chirps.sensitive OR chirps.sensitive_by_moderator)::boolean AS sensitive, chirps.visibility, users.is_chirpy_red, chirp_bookmarks.created_at AS bookmarked_at FROM chirp_bookmarks
+INNER JOIN chirps ON chirp_bookmarks.chirp_id = chirps.id
+INNER JOIN users ON chirps.user_id = users.id
+WHERE chirp_bookmarks.user_id = sqlc.arg('user_id')
+AND chirp_visible_to(chirps.id, sqlc.arg('user_id'))
+AND NOT chirp_muted_for(chirps.id, sqlc.arg('user_id'))
+AND (NOT sqlc.arg('hide_sensitive')::boolean OR NOT chirp_sensitive(chirps.id))
+AND (
+	sqlc.narg('cursor_time')::timestamp IS NULL
+	OR (chirp_bookmarks.created_at, chirp_bookmarks.chirp_id) < (sqlc.narg('cursor_time')::timestamp, sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY chirp_bookmarks.created_at DESC, chirp_bookmarks.chirp_id DESC
+LIMIT sqlc.arg('row_limit');
@@ -0,0 +1,26 @@
+-- name: CreateChirpHashtags :exec
+INSERT INTO chirp_hashtags (chirp_id, tag, start_index, end_index)
+SELECT sqlc.arg('chirp_id'), UNNEST(sqlc.arg('tags')::text[]), UNNEST(sqlc.arg('start_indexes')::int[]), UNNEST(sqlc.arg('end_indexes')::int[]);
+
+-- name: CreateChirpMentions :exec
+-- Mentions of handles that do not belong to any user are dropped
+INSERT INTO chirp_mentions (chirp_id, user_id, start_index, end_index)
+SELECT sqlc.arg('chirp_id'), users.id, mentions.start_index, mentions.end_index
+FROM (
+	SELECT UNNEST(sqlc.arg('handles')::text[]) AS handle, UNNEST(sqlc.arg('start_indexes')::int[]) AS start_index, UNNEST(sqlc.arg('end_indexes')::int[]) AS end_index
+) AS mentions
+INNER JOIN users ON mentions.handle = users.handle;
+
+-- name: DeleteChirpHashtags :exec
+DELETE FROM chirp_hashtags WHERE chirp_id = $1;
+
+-- name: DeleteChirpMentions :exec
+DELETE FROM chirp_mentions WHERE chirp_id = $1;
+
+-- name: GetChirpHashtags :many
+SELECT * FROM chirp_hashtags
+WHERE chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[]);
+
+-- name: GetChirpMentions :many
+SELECT * FROM chirp_mentions
+WHERE chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[]);
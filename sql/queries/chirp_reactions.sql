@@ -0,0 +1,35 @@
+-- name: CreateReaction :exec
+INSERT INTO chirp_reactions (chirp_id, user_id, reaction, created_at)
+VALUES (
+	$1,
+	$2,
+	$3,
+	NOW()
+) ON CONFLICT DO NOTHING;
+
+-- name: DeleteReaction :exec
+DELETE FROM chirp_reactions WHERE chirp_id = $1 AND user_id = $2 AND reaction = $3;
+
+-- name: GetReactionCounts :many
+SELECT chirp_id, reaction, COUNT(*) AS reaction_count FROM chirp_reactions
+WHERE chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+GROUP BY chirp_id, reaction;
+
+-- name: GetViewerReactions :many
+SELECT chirp_id, reaction FROM chirp_reactions
+WHERE chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[]) AND user_id = sqlc.arg('user_id');
+
+-- name: ListReactedChirps :many
+SELECT chirps.id, chirps.body, chirps.user_id, chirps.created_at, chirps.updated_at, chirps.in_reply_to, chirps.rechirp_of, chirps.quote_of, chirps.publish_at, chirps.content_warning, (chirps.sensitive OR chirps.sensitive_by_moderator)::boolean AS sensitive, chirps.visibility, users.is_chirpy_red, chirp_reactions.created_at AS reacted_at FROM chirp_reactions
+INNER JOIN chirps ON chirp_reactions.chirp_id = chirps.id
+INNER JOIN users ON chirps.user_id = users.id
+WHERE chirp_reactions.user_id = sqlc.arg('user_id') AND chirp_reactions.reaction = sqlc.arg('reaction')
+AND chirp_visible_to(chirps.id, sqlc.narg('viewer_id')::uuid)
+AND NOT chirp_muted_for(chirps.id, sqlc.narg('viewer_id')::uuid)
+AND (NOT sqlc.arg('hide_sensitive')::boolean OR NOT chirp_sensitive(chirps.id))
+AND (
+	sqlc.narg('cursor_time')::timestamp IS NULL
+	OR (chirp_reactions.created_at, chirp_reactions.chirp_id) < (sqlc.narg('cursor_time')::timestamp, sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY chirp_reactions.created_at DESC, chirp_reactions.chirp_id DESC
+LIMIT sqlc.arg('row_limit');
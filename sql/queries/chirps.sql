@@ -1,26 +1,342 @@
 -- name: CreateChirp :one
 WITH chirpinsert AS (
-	INSERT INTO chirps (id, body, user_id, created_at, updated_at)
+	INSERT INTO chirps (id, body, user_id, created_at, updated_at, in_reply_to, quote_of, publish_at, content_warning, sensitive, visibility)
 	VALUES (
 		GEN_RANDOM_UUID(),
 		$1,
 		$2,
 		NOW(),
-		NOW()
-	) RETURNING *
+		NOW(),
+		$3,
+		$4,
+		$5,
+		$6,
+		$7,
+		$8
+	) RETURNING id, body, user_id, created_at, updated_at, in_reply_to, rechirp_of, quote_of, publish_at, content_warning, (sensitive OR sensitive_by_moderator)::boolean AS sensitive, visibility
 )
 SELECT chirpinsert.*, users.is_chirpy_red FROM chirpinsert
 INNER JOIN users ON chirpinsert.user_id = users.id;
 
--- name: GetAllChirps :many
-SELECT chirps.*, users.is_chirpy_red FROM chirps
+-- name: ListChirpsByCreatedAtDesc :many
+-- Listing chirps takes one query per sort key and direction so that the
+-- ordering and cursor are plain column comparisons the (created_at, id) and
+-- (updated_at, id) indexes can serve. The filters they share live in
+-- chirp_listable, so the four only differ in those clauses
+SELECT chirps.id, chirps.body, chirps.user_id, chirps.created_at, chirps.updated_at, chirps.in_reply_to, chirps.rechirp_of, chirps.quote_of, chirps.publish_at, chirps.content_warning, (chirps.sensitive OR chirps.sensitive_by_moderator)::boolean AS sensitive, chirps.visibility, users.is_chirpy_red FROM chirps
+INNER JOIN users ON chirps.user_id = users.id
+WHERE chirp_listable(
+	chirps, users.is_chirpy_red, sqlc.narg('viewer_id')::uuid, sqlc.arg('author_ids')::uuid[],
+	sqlc.narg('since')::timestamp, sqlc.narg('until')::timestamp, sqlc.narg('is_chirpy_red')::boolean,
+	sqlc.narg('has_media')::boolean, sqlc.arg('exclude_pinned')::boolean, sqlc.arg('hide_sensitive')::boolean
+)
+AND (
+	sqlc.narg('cursor_time')::timestamp IS NULL
+	OR (chirps.created_at, chirps.id) < (sqlc.narg('cursor_time')::timestamp, sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('row_limit');
+
+-- name: ListChirpsByCreatedAtAsc :many
+SELECT chirps.id, chirps.body, chirps.user_id, chirps.created_at, chirps.updated_at, chirps.in_reply_to, chirps.rechirp_of, chirps.quote_of, chirps.publish_at, chirps.content_warning, (chirps.sensitive OR chirps.sensitive_by_moderator)::boolean AS sensitive, chirps.visibility, users.is_chirpy_red FROM chirps
 INNER JOIN users ON chirps.user_id = users.id
-ORDER BY chirps.created_at ASC;
+WHERE chirp_listable(
+	chirps, users.is_chirpy_red, sqlc.narg('viewer_id')::uuid, sqlc.arg('author_ids')::uuid[],
+	sqlc.narg('since')::timestamp, sqlc.narg('until')::timestamp, sqlc.narg('is_chirpy_red')::boolean,
+	sqlc.narg('has_media')::boolean, sqlc.arg('exclude_pinned')::boolean, sqlc.arg('hide_sensitive')::boolean
+)
+AND (
+	sqlc.narg('cursor_time')::timestamp IS NULL
+	OR (chirps.created_at, chirps.id) > (sqlc.narg('cursor_time')::timestamp, sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT sqlc.arg('row_limit');
+
+-- name: ListChirpsByUpdatedAtDesc :many
+SELECT chirps.id, chirps.body, chirps.user_id, chirps.created_at, chirps.updated_at, chirps.in_reply_to, chirps.rechirp_of, chirps.quote_of, chirps.publish_at, chirps.content_warning, (chirps.sensitive OR chirps.sensitive_by_moderator)::boolean AS sensitive, chirps.visibility, users.is_chirpy_red FROM chirps
+INNER JOIN users ON chirps.user_id = users.id
+WHERE chirp_listable(
+	chirps, users.is_chirpy_red, sqlc.narg('viewer_id')::uuid, sqlc.arg('author_ids')::uuid[],
+	sqlc.narg('since')::timestamp, sqlc.narg('until')::timestamp, sqlc.narg('is_chirpy_red')::boolean,
+	sqlc.narg('has_media')::boolean, sqlc.arg('exclude_pinned')::boolean, sqlc.arg('hide_sensitive')::boolean
+)
+AND (
+	sqlc.narg('cursor_time')::timestamp IS NULL
+	OR (chirps.updated_at, chirps.id) < (sqlc.narg('cursor_time')::timestamp, sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY chirps.updated_at DESC, chirps.id DESC
+LIMIT sqlc.arg('row_limit');
+
+-- name: ListChirpsByUpdatedAtAsc :many
+SELECT chirps.id, chirps.body, chirps.user_id, chirps.created_at, chirps.updated_at, chirps.in_reply_to, chirps.rechirp_of, chirps.quote_of, chirps.publish_at, chirps.content_warning, (chirps.sensitive OR chirps.sensitive_by_moderator)::boolean AS sensitive, chirps.visibility, users.is_chirpy_red FROM chirps
+INNER JOIN users ON chirps.user_id = users.id
+WHERE chirp_listable(
+	chirps, users.is_chirpy_red, sqlc.narg('viewer_id')::uuid, sqlc.arg('author_ids')::uuid[],
+	sqlc.narg('since')::timestamp, sqlc.narg('until')::timestamp, sqlc.narg('is_chirpy_red')::boolean,
+	sqlc.narg('has_media')::boolean, sqlc.arg('exclude_pinned')::boolean, sqlc.arg('hide_sensitive')::boolean
+)
+AND (
+	sqlc.narg('cursor_time')::timestamp IS NULL
+	OR (chirps.updated_at, chirps.id) > (sqlc.narg('cursor_time')::timestamp, sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY chirps.updated_at ASC, chirps.id ASC
+LIMIT sqlc.arg('row_limit');
 
 -- name: GetOneChirp :one
-SELECT chirps.*, users.is_chirpy_red FROM chirps
+SELECT chirps.id, chirps.body, chirps.user_id, chirps.created_at, chirps.updated_at, chirps.in_reply_to, chirps.rechirp_of, chirps.quote_of, chirps.publish_at, chirps.content_warning, (chirps.sensitive OR chirps.sensitive_by_moderator)::boolean AS sensitive, chirps.visibility, users.is_chirpy_red FROM chirps
 INNER JOIN users ON chirps.user_id = users.id
-WHERE chirps.id = $1;
+WHERE chirps.id = sqlc.arg('id')
+AND chirp_visible_to(chirps.id, sqlc.narg('viewer_id')::uuid);
 
 -- name: DeleteChirp :exec
 DELETE FROM chirps WHERE id = $1;
+
+-- name: SoftDeleteChirp :exec
+-- Deleting a chirp also takes it off its author's pins, so restoring it
+-- can't go over the pin limit
+UPDATE chirps SET deleted_at = NOW(), pinned_at = NULL WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: SearchChirps :many
+-- The body is HTML escaped before it is highlighted, the <mark> tags around
+-- matches are the only markup a snippet can contain
+SELECT
+	chirps.id, chirps.body, chirps.user_id, chirps.created_at, chirps.updated_at, chirps.in_reply_to, chirps.rechirp_of, chirps.quote_of, chirps.publish_at, chirps.content_warning, (chirps.sensitive OR chirps.sensitive_by_moderator)::boolean AS sensitive, chirps.visibility, users.is_chirpy_red,
+	TS_RANK_CD(chirps.search_vector, search_query)::real AS rank,
+	TS_HEADLINE(
+		'english',
+		REPLACE(REPLACE(REPLACE(REPLACE(REPLACE(chirps.body, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'), '''', '&#39;'),
+		search_query,
+		'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MinWords=5, MaxWords=20'
+	)::text AS snippet
+FROM chirps
+INNER JOIN users ON chirps.user_id = users.id
+CROSS JOIN TO_TSQUERY('english', sqlc.arg('search_query')::text) AS search_query
+WHERE chirps.search_vector @@ search_query
+AND chirp_visible_to(chirps.id, sqlc.narg('viewer_id')::uuid)
+AND NOT chirp_muted_for(chirps.id, sqlc.narg('viewer_id')::uuid)
+AND (chirps.visibility <> 'unlisted' OR chirps.user_id = sqlc.narg('viewer_id')::uuid)
+AND (CARDINALITY(sqlc.arg('author_ids')::uuid[]) = 0 OR chirps.user_id = ANY(sqlc.arg('author_ids')::uuid[]))
+AND (sqlc.narg('since')::timestamp IS NULL OR chirps.created_at >= sqlc.narg('since')::timestamp)
+AND (sqlc.narg('until')::timestamp IS NULL OR chirps.created_at < sqlc.narg('until')::timestamp)
+AND (sqlc.narg('is_chirpy_red')::boolean IS NULL OR users.is_chirpy_red = sqlc.narg('is_chirpy_red')::boolean)
+AND (
+	sqlc.narg('has_media')::boolean IS NULL
+	OR sqlc.narg('has_media')::boolean = EXISTS (SELECT 1 FROM chirp_media WHERE chirp_media.chirp_id = chirps.id)
+)
+AND (NOT sqlc.arg('hide_sensitive')::boolean OR NOT chirp_sensitive(chirps.id))
+ORDER BY rank DESC, chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('row_limit') OFFSET sqlc.arg('row_offset');
+
+-- name: UpdateChirp :one
+WITH chirprevision AS (
+	INSERT INTO chirp_revisions (id, chirp_id, body, created_at, replaced_at)
+	SELECT GEN_RANDOM_UUID(), chirps.id, chirps.body, chirps.updated_at, NOW() FROM chirps
+	WHERE chirps.id = sqlc.arg('id')
+), chirpupdate AS (
+	UPDATE chirps SET body = sqlc.arg('body'), updated_at = NOW()
+	WHERE chirps.id = sqlc.arg('id')
+	RETURNING id, body, user_id, created_at, updated_at, in_reply_to, rechirp_of, quote_of, publish_at, content_warning, (sensitive OR sensitive_by_moderator)::boolean AS sensitive, visibility
+)
+SELECT chirpupdate.*, users.is_chirpy_red FROM chirpupdate
+INNER JOIN users ON chirpupdate.user_id = users.id;
+
+-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors AS (
+	SELECT chirps.id, chirps.in_reply_to, 0 AS depth FROM chirps
+	WHERE chirps.id = sqlc.arg('id')
+	UNION ALL
+	SELECT chirps.id, chirps.in_reply_to, ancestors.depth + 1 FROM chirps
+	INNER JOIN ancestors ON chirps.id = ancestors.in_reply_to
+)
+SELECT chirps.id, chirps.body, chirps.user_id, chirps.created_at, chirps.updated_at, chirps.in_reply_to, chirps.rechirp_of, chirps.quote_of, chirps.publish_at, chirps.content_warning, (chirps.sensitive OR chirps.sensitive_by_moderator)::boolean AS sensitive, chirps.visibility, users.is_chirpy_red FROM ancestors
+INNER JOIN chirps ON ancestors.id = chirps.id
+INNER JOIN users ON chirps.user_id = users.id
+WHERE ancestors.depth > 0
+AND chirp_visible_to(chirps.id, sqlc.narg('viewer_id')::uuid)
+AND NOT chirp_muted_for(chirps.id, sqlc.narg('viewer_id')::uuid)
+ORDER BY ancestors.depth DESC;
+
+-- name: GetChirpDescendants :many
+-- Paths are built from fixed width timestamps and ids so sorting them walks
+-- the reply tree depth first, oldest reply first at every level. A page goes
+-- on from the last path served, and branches that sort wholly before it are
+-- never walked
+WITH RECURSIVE descendants AS (
+	SELECT chirps.id, 1 AS depth, (TO_CHAR(chirps.created_at, 'YYYYMMDDHH24MISSUS') || chirps.id::text)::text AS path FROM chirps
+	WHERE chirps.in_reply_to = sqlc.arg('id')::uuid
+	UNION ALL
+	SELECT chirps.id, descendants.depth + 1, (descendants.path || '/' || TO_CHAR(chirps.created_at, 'YYYYMMDDHH24MISSUS') || chirps.id::text)::text FROM chirps
+	INNER JOIN descendants ON chirps.in_reply_to = descendants.id
+	WHERE descendants.depth < sqlc.arg('max_depth')::int
+	AND (
+		sqlc.narg('cursor_path')::text IS NULL
+		OR descendants.path COLLATE "C" >= LEFT(sqlc.narg('cursor_path')::text, LENGTH(descendants.path))
+	)
+)
+SELECT chirps.id, chirps.body, chirps.user_id, chirps.created_at, chirps.updated_at, chirps.in_reply_to, chirps.rechirp_of, chirps.quote_of, chirps.publish_at, chirps.content_warning, (chirps.sensitive OR chirps.sensitive_by_moderator)::boolean AS sensitive, chirps.visibility, users.is_chirpy_red, descendants.depth::int AS depth, descendants.path FROM descendants
+INNER JOIN chirps ON descendants.id = chirps.id
+INNER JOIN users ON chirps.user_id = users.id
+WHERE chirp_visible_to(chirps.id, sqlc.narg('viewer_id')::uuid)
+AND NOT chirp_muted_for(chirps.id, sqlc.narg('viewer_id')::uuid)
+AND (NOT sqlc.arg('hide_sensitive')::boolean OR NOT chirp_sensitive(chirps.id))
+AND (
+	sqlc.narg('cursor_path')::text IS NULL
+	OR descendants.path COLLATE "C" > sqlc.narg('cursor_path')::text
+)
+ORDER BY descendants.path COLLATE "C"
+LIMIT sqlc.arg('row_limit');
+
+-- name: GetChirpsByIDs :many
+SELECT chirps.id, chirps.body, chirps.user_id, chirps.created_at, chirps.updated_at, chirps.in_reply_to, chirps.rechirp_of, chirps.quote_of, chirps.publish_at, chirps.content_warning, (chirps.sensitive OR chirps.sensitive_by_moderator)::boolean AS sensitive, chirps.visibility, users.is_chirpy_red FROM chirps
+INNER JOIN users ON chirps.user_id = users.id
+WHERE chirps.id = ANY(sqlc.arg('ids')::uuid[])
+AND chirp_visible_to(chirps.id, sqlc.narg('viewer_id')::uuid);
+
+-- name: CreateRechirp :one
+WITH chirpinsert AS (
+	INSERT INTO chirps (id, body, user_id, created_at, updated_at, rechirp_of)
+	VALUES (
+		GEN_RANDOM_UUID(),
+		'',
+		sqlc.arg('user_id'),
+		NOW(),
+		NOW(),
+		sqlc.arg('rechirp_of')::uuid
+	)
+	ON CONFLICT (user_id, rechirp_of) WHERE rechirp_of IS NOT NULL DO NOTHING
+	RETURNING id, body, user_id, created_at, updated_at, in_reply_to, rechirp_of, quote_of, publish_at, content_warning, (sensitive OR sensitive_by_moderator)::boolean AS sensitive, visibility
+)
+SELECT chirpinsert.*, users.is_chirpy_red FROM chirpinsert
+INNER JOIN users ON chirpinsert.user_id = users.id;
+
+-- name: DeleteRechirp :execrows
+DELETE FROM chirps WHERE user_id = sqlc.arg('user_id') AND rechirp_of = sqlc.arg('rechirp_of')::uuid;
+
+-- name: GetRechirpCounts :one
+SELECT
+	(SELECT COUNT(*) FROM chirps WHERE chirps.rechirp_of = sqlc.arg('id')::uuid AND chirps.deleted_at IS NULL) AS rechirp_count,
+	(SELECT COUNT(*) FROM chirps WHERE chirps.quote_of = sqlc.arg('id')::uuid AND chirps.deleted_at IS NULL) AS quote_count;
+
+-- name: ListHashtagChirps :many
+SELECT chirps.id, chirps.body, chirps.user_id, chirps.created_at, chirps.updated_at, chirps.in_reply_to, chirps.rechirp_of, chirps.quote_of, chirps.publish_at, chirps.content_warning, (chirps.sensitive OR chirps.sensitive_by_moderator)::boolean AS sensitive, chirps.visibility, users.is_chirpy_red FROM chirps
+INNER JOIN users ON chirps.user_id = users.id
+WHERE EXISTS (
+	SELECT 1 FROM chirp_hashtags
+	WHERE chirp_hashtags.chirp_id = chirps.id AND chirp_hashtags.tag = sqlc.arg('tag')
+)
+AND chirp_visible_to(chirps.id, sqlc.narg('viewer_id')::uuid)
+AND NOT chirp_muted_for(chirps.id, sqlc.narg('viewer_id')::uuid)
+AND (chirps.visibility <> 'unlisted' OR chirps.user_id = sqlc.narg('viewer_id')::uuid)
+AND (NOT sqlc.arg('hide_sensitive')::boolean OR NOT chirp_sensitive(chirps.id))
+AND (
+	sqlc.narg('cursor_time')::timestamp IS NULL
+	OR (chirps.created_at, chirps.id) < (sqlc.narg('cursor_time')::timestamp, sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('row_limit');
+
+
+-- name: PublishDueChirps :many
+-- SKIP LOCKED lets several schedulers run at once, each due chirp is claimed
+-- by exactly one of them and clearing publish_at means it is never claimed again
+WITH chirppublish AS (
+	UPDATE chirps SET created_at = chirps.publish_at, updated_at = chirps.publish_at, publish_at = NULL
+	WHERE chirps.id IN (
+		SELECT duechirps.id FROM chirps AS duechirps
+		WHERE duechirps.publish_at <= NOW() AND duechirps.deleted_at IS NULL
+		ORDER BY duechirps.publish_at ASC
+		LIMIT sqlc.arg('row_limit')
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING id, body, user_id, created_at, updated_at, in_reply_to, rechirp_of, quote_of, publish_at, content_warning, (sensitive OR sensitive_by_moderator)::boolean AS sensitive, visibility
+)
+SELECT chirppublish.*, users.is_chirpy_red, users.protected FROM chirppublish
+INNER JOIN users ON chirppublish.user_id = users.id;
+
+-- name: ListScheduledChirps :many
+SELECT chirps.id, chirps.body, chirps.user_id, chirps.created_at, chirps.updated_at, chirps.in_reply_to, chirps.rechirp_of, chirps.quote_of, chirps.publish_at, chirps.content_warning, (chirps.sensitive OR chirps.sensitive_by_moderator)::boolean AS sensitive, chirps.visibility, users.is_chirpy_red FROM chirps
+INNER JOIN users ON chirps.user_id = users.id
+WHERE chirps.user_id = sqlc.arg('user_id') AND chirps.publish_at IS NOT NULL AND chirps.deleted_at IS NULL
+AND (
+	sqlc.narg('cursor_time')::timestamp IS NULL
+	OR (chirps.publish_at, chirps.id) > (sqlc.narg('cursor_time')::timestamp, sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY chirps.publish_at ASC, chirps.id ASC
+LIMIT sqlc.arg('row_limit');
+
+-- name: RescheduleChirp :one
+WITH chirpreschedule AS (
+	UPDATE chirps SET publish_at = sqlc.arg('publish_at')::timestamp
+	WHERE chirps.id = sqlc.arg('id') AND chirps.user_id = sqlc.arg('user_id') AND chirps.publish_at IS NOT NULL AND chirps.deleted_at IS NULL
+	RETURNING id, body, user_id, created_at, updated_at, in_reply_to, rechirp_of, quote_of, publish_at, content_warning, (sensitive OR sensitive_by_moderator)::boolean AS sensitive, visibility
+)
+SELECT chirpreschedule.*, users.is_chirpy_red FROM chirpreschedule
+INNER JOIN users ON chirpreschedule.user_id = users.id;
+
+-- name: CancelScheduledChirp :execrows
+DELETE FROM chirps WHERE id = sqlc.arg('id') AND user_id = sqlc.arg('user_id') AND publish_at IS NOT NULL AND deleted_at IS NULL;
+
+-- name: ListTrashedChirps :many
+SELECT chirps.id, chirps.body, chirps.user_id, chirps.created_at, chirps.updated_at, chirps.in_reply_to, chirps.rechirp_of, chirps.quote_of, chirps.publish_at, chirps.content_warning, (chirps.sensitive OR chirps.sensitive_by_moderator)::boolean AS sensitive, chirps.visibility, chirps.deleted_at::timestamp AS deleted_at, users.is_chirpy_red FROM chirps
+INNER JOIN users ON chirps.user_id = users.id
+WHERE chirps.user_id = sqlc.arg('user_id') AND chirps.deleted_at >= sqlc.arg('deleted_since')::timestamp
+AND (
+	sqlc.narg('cursor_time')::timestamp IS NULL
+	OR (chirps.deleted_at, chirps.id) < (sqlc.narg('cursor_time')::timestamp, sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY chirps.deleted_at DESC, chirps.id DESC
+LIMIT sqlc.arg('row_limit');
+
+-- name: RestoreChirp :one
+WITH chirprestore AS (
+	UPDATE chirps SET deleted_at = NULL
+	WHERE chirps.id = sqlc.arg('id') AND chirps.user_id = sqlc.arg('user_id')
+	AND chirps.deleted_at >= sqlc.arg('deleted_since')::timestamp
+	RETURNING id, body, user_id, created_at, updated_at, in_reply_to, rechirp_of, quote_of, publish_at, content_warning, (sensitive OR sensitive_by_moderator)::boolean AS sensitive, visibility
+)
+SELECT chirprestore.*, users.is_chirpy_red FROM chirprestore
+INNER JOIN users ON chirprestore.user_id = users.id;
+
+-- name: ClaimPurgeableChirps :many
+-- Locked so two instances purging at once never work on the same chirps
+SELECT id FROM chirps
+WHERE deleted_at < sqlc.arg('deleted_before')::timestamp
+ORDER BY deleted_at ASC
+LIMIT sqlc.arg('row_limit')
+FOR UPDATE SKIP LOCKED;
+
+-- name: PurgeChirps :exec
+DELETE FROM chirps WHERE id = ANY(sqlc.arg('ids')::uuid[]);
+
+-- name: GetChirpForModeration :one
+-- Moderators see chirps in any state, deleted and scheduled ones included
+SELECT chirps.id, chirps.body, chirps.user_id, chirps.created_at, chirps.updated_at, chirps.in_reply_to, chirps.rechirp_of, chirps.quote_of, chirps.publish_at, chirps.content_warning, (chirps.sensitive OR chirps.sensitive_by_moderator)::boolean AS sensitive, chirps.visibility, chirps.deleted_at, users.is_chirpy_red FROM chirps
+INNER JOIN users ON chirps.user_id = users.id
+WHERE chirps.id = $1;
+
+-- name: ListPinnedChirps :many
+SELECT chirps.id, chirps.body, chirps.user_id, chirps.created_at, chirps.updated_at, chirps.in_reply_to, chirps.rechirp_of, chirps.quote_of, chirps.publish_at, chirps.content_warning, (chirps.sensitive OR chirps.sensitive_by_moderator)::boolean AS sensitive, chirps.visibility, users.is_chirpy_red FROM chirps
+INNER JOIN users ON chirps.user_id = users.id
+WHERE chirps.user_id = sqlc.arg('user_id') AND chirps.pinned_at IS NOT NULL
+AND chirp_visible_to(chirps.id, sqlc.narg('viewer_id')::uuid)
+AND NOT chirp_muted_for(chirps.id, sqlc.narg('viewer_id')::uuid)
+AND (NOT sqlc.arg('hide_sensitive')::boolean OR NOT chirp_sensitive(chirps.id))
+ORDER BY chirps.pinned_at DESC, chirps.id DESC;
+
+-- name: PinChirp :exec
+UPDATE chirps SET pinned_at = NOW() WHERE id = $1 AND pinned_at IS NULL;
+
+-- name: UnpinChirp :exec
+UPDATE chirps SET pinned_at = NULL WHERE id = $1;
+
+-- name: SetChirpSensitivity :execrows
+UPDATE chirps SET content_warning = sqlc.narg('content_warning'), sensitive = sqlc.arg('sensitive')
+WHERE id = sqlc.arg('id') AND user_id = sqlc.arg('user_id') AND deleted_at IS NULL;
+
+-- name: SetChirpSensitiveByModerator :execrows
+UPDATE chirps SET sensitive_by_moderator = $2 WHERE id = $1;
+
+-- name: GetChirpAuthor :one
+SELECT users.id, users.protected FROM chirps
+INNER JOIN users ON chirps.user_id = users.id
+WHERE chirps.id = $1;
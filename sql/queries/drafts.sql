@@ -0,0 +1,29 @@
+-- name: CreateDraft :one
+-- A user already at the draft limit inserts nothing, which comes back as no
+-- rows. Callers hold the user's row lock so the count can't go stale
+INSERT INTO drafts (id, user_id, body, created_at, updated_at)
+SELECT GEN_RANDOM_UUID(), sqlc.arg('user_id'), sqlc.arg('body'), NOW(), NOW()
+WHERE (SELECT COUNT(*) FROM drafts WHERE drafts.user_id = sqlc.arg('user_id')) < sqlc.arg('max_drafts')::int
+RETURNING *;
+
+-- name: GetDraft :one
+SELECT * FROM drafts WHERE id = $1 AND user_id = $2;
+
+-- name: ListDrafts :many
+-- Most recently touched first, that is the draft another device was just on
+SELECT * FROM drafts
+WHERE user_id = sqlc.arg('user_id')
+AND (
+	sqlc.narg('cursor_time')::timestamp IS NULL
+	OR (updated_at, id) < (sqlc.narg('cursor_time')::timestamp, sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY updated_at DESC, id DESC
+LIMIT sqlc.arg('row_limit');
+
+-- name: UpdateDraft :one
+UPDATE drafts SET body = $3, updated_at = NOW()
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteDraft :execrows
+DELETE FROM drafts WHERE id = $1 AND user_id = $2;
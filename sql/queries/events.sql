@@ -0,0 +1,19 @@
+-- name: CreateEvent :exec
+INSERT INTO events (id, user_id, type, payload, created_at)
+VALUES (
+	GEN_RANDOM_UUID(),
+	$1,
+	$2,
+	$3,
+	NOW()
+);
+
+-- name: ListEvents :many
+SELECT * FROM events
+WHERE user_id = sqlc.arg('user_id')
+AND (
+	sqlc.narg('cursor_time')::timestamp IS NULL
+	OR (created_at, id) < (sqlc.narg('cursor_time')::timestamp, sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('row_limit');
@@ -0,0 +1,38 @@
+-- name: CreateFollowRequest :execrows
+-- Nothing is created when the requester already follows the target or has
+-- already asked to
+INSERT INTO follow_requests (requester_id, target_id, created_at)
+SELECT sqlc.arg('requester_id'), sqlc.arg('target_id'), NOW()
+WHERE NOT EXISTS (
+	SELECT 1 FROM follows
+	WHERE follows.follower_id = sqlc.arg('requester_id') AND follows.followee_id = sqlc.arg('target_id')
+) ON CONFLICT DO NOTHING;
+
+-- name: DeleteFollowRequest :execrows
+DELETE FROM follow_requests WHERE requester_id = $1 AND target_id = $2;
+
+-- name: ApproveAllFollowRequests :many
+WITH approved AS (
+	DELETE FROM follow_requests WHERE target_id = $1
+	RETURNING requester_id, target_id
+)
+INSERT INTO follows (follower_id, followee_id, created_at)
+SELECT approved.requester_id, approved.target_id, NOW() FROM approved
+ON CONFLICT DO NOTHING
+RETURNING follower_id;
+
+-- name: DeleteFollowRequestsBetween :exec
+DELETE FROM follow_requests
+WHERE (requester_id = sqlc.arg('user_id') AND target_id = sqlc.arg('other_id'))
+OR (requester_id = sqlc.arg('other_id') AND target_id = sqlc.arg('user_id'));
+
+-- name: ListFollowRequests :many
+SELECT users.id, users.handle, users.is_chirpy_red, follow_requests.created_at AS requested_at FROM follow_requests
+INNER JOIN users ON follow_requests.requester_id = users.id
+WHERE follow_requests.target_id = sqlc.arg('user_id')
+AND (
+	sqlc.narg('cursor_time')::timestamp IS NULL
+	OR (follow_requests.created_at, users.id) < (sqlc.narg('cursor_time')::timestamp, sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY follow_requests.created_at DESC, users.id DESC
+LIMIT sqlc.arg('row_limit');
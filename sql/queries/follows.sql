@@ -0,0 +1,42 @@
+-- name: CreateFollow :execrows
+INSERT INTO follows (follower_id, followee_id, created_at)
+VALUES (
+	$1,
+	$2,
+	NOW()
+) ON CONFLICT DO NOTHING;
+
+-- name: DeleteFollow :execrows
+DELETE FROM follows WHERE follower_id = $1 AND followee_id = $2;
+
+-- name: GetFollowCounts :one
+SELECT
+	(SELECT COUNT(*) FROM follows AS followers WHERE followers.followee_id = sqlc.arg('user_id')::uuid) AS follower_count,
+	(SELECT COUNT(*) FROM follows AS following WHERE following.follower_id = sqlc.arg('user_id')::uuid) AS following_count;
+
+-- name: ListFollowers :many
+SELECT users.id, users.handle, users.is_chirpy_red, follows.created_at AS followed_at FROM follows
+INNER JOIN users ON follows.follower_id = users.id
+WHERE follows.followee_id = sqlc.arg('user_id')
+AND (
+	sqlc.narg('cursor_time')::timestamp IS NULL
+	OR (follows.created_at, users.id) < (sqlc.narg('cursor_time')::timestamp, sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY follows.created_at DESC, users.id DESC
+LIMIT sqlc.arg('row_limit');
+
+-- name: ListFollowing :many
+SELECT users.id, users.handle, users.is_chirpy_red, follows.created_at AS followed_at FROM follows
+INNER JOIN users ON follows.followee_id = users.id
+WHERE follows.follower_id = sqlc.arg('user_id')
+AND (
+	sqlc.narg('cursor_time')::timestamp IS NULL
+	OR (follows.created_at, users.id) < (sqlc.narg('cursor_time')::timestamp, sqlc.narg('cursor_id')::uuid)
+)
+ORDER BY follows.created_at DESC, users.id DESC
+LIMIT sqlc.arg('row_limit');
+
+-- name: DeleteFollowsBetween :exec
+DELETE FROM follows
+WHERE (follower_id = sqlc.arg('user_id') AND followee_id = sqlc.arg('other_id'))
+OR (follower_id = sqlc.arg('other_id') AND followee_id = sqlc.arg('user_id'));
@@ -0,0 +1,91 @@
+-- name: CreateMedia :one
+INSERT INTO media (id, user_id, storage_key, content_type, size_bytes, created_at, status)
+VALUES (
+	$1,
+	$2,
+	$3,
+	$4,
+	$5,
+	NOW(),
+	'processing'
+) RETURNING *;
+
+-- name: MarkMediaReady :exec
+UPDATE media SET status = 'ready', storage_key = $2, content_type = $3, size_bytes = $4, width = $5, height = $6, blurhash = $7
+WHERE id = $1;
+
+-- name: MarkMediaFailed :exec
+UPDATE media SET status = 'failed' WHERE id = $1;
+
+-- name: FailStaleMedia :exec
+-- Jobs only live in memory, so media still processing long after upload was
+-- lost to a restart
+UPDATE media SET status = 'failed'
+WHERE status = 'processing' AND created_at < NOW() - INTERVAL '10 minutes';
+
+-- name: GetMedia :one
+SELECT * FROM media WHERE id = $1;
+
+-- name: DeleteMedia :exec
+DELETE FROM media WHERE id = $1;
+
+-- name: CreateMediaVariants :exec
+INSERT INTO media_variants (media_id, name, storage_key, content_type, width, height)
+SELECT sqlc.arg('media_id'), UNNEST(sqlc.arg('names')::text[]), UNNEST(sqlc.arg('storage_keys')::text[]),
+UNNEST(sqlc.arg('content_types')::text[]), UNNEST(sqlc.arg('widths')::int[]), UNNEST(sqlc.arg('heights')::int[]);
+
+-- name: GetAttachableMedia :many
+-- Media can only go on a chirp by the user who uploaded it and only once,
+-- uploads that failed processing can never be attached
+SELECT media.* FROM media
+WHERE media.id = ANY(sqlc.arg('ids')::uuid[]) AND media.user_id = sqlc.arg('user_id')
+AND media.status <> 'failed'
+AND NOT EXISTS (SELECT 1 FROM chirp_media WHERE chirp_media.media_id = media.id);
+
+-- name: CreateChirpMedia :exec
+INSERT INTO chirp_media (chirp_id, media_id, position)
+SELECT sqlc.arg('chirp_id'), UNNEST(sqlc.arg('media_ids')::uuid[]), UNNEST(sqlc.arg('positions')::int[]);
+
+-- name: GetChirpMedia :many
+SELECT chirp_media.chirp_id, chirp_media.position, media.* FROM chirp_media
+INNER JOIN media ON chirp_media.media_id = media.id
+WHERE chirp_media.chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+ORDER BY chirp_media.chirp_id, chirp_media.position ASC;
+
+-- name: GetMediaVariants :many
+SELECT * FROM media_variants
+WHERE media_id = ANY(sqlc.arg('media_ids')::uuid[])
+ORDER BY media_id, width ASC;
+
+-- name: GetStorageKeysForChirps :many
+SELECT media.storage_key FROM media
+INNER JOIN chirp_media ON media.id = chirp_media.media_id
+WHERE chirp_media.chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+UNION ALL
+SELECT media_variants.storage_key FROM media_variants
+INNER JOIN chirp_media ON media_variants.media_id = chirp_media.media_id
+WHERE chirp_media.chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[]);
+
+-- name: DeleteMediaForChirps :exec
+-- Attached media has no use once its chirp is gone, leaving it would make it
+-- attachable again
+DELETE FROM media
+WHERE id IN (SELECT chirp_media.media_id FROM chirp_media WHERE chirp_media.chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[]));
+
+-- name: GetReadableMediaFile :one
+-- Stored files are readable by their uploader, anyone else can only read
+-- them while they are attached to a chirp they can see
+WITH stored AS (
+	SELECT media.id AS media_id, media.storage_key, media.content_type FROM media
+	UNION ALL
+	SELECT media_variants.media_id, media_variants.storage_key, media_variants.content_type FROM media_variants
+)
+SELECT stored.content_type FROM stored
+INNER JOIN media ON stored.media_id = media.id
+LEFT JOIN chirp_media ON media.id = chirp_media.media_id
+WHERE stored.storage_key = sqlc.arg('storage_key')::text
+AND media.status = 'ready'
+AND (
+	media.user_id = sqlc.narg('viewer_id')::uuid
+	OR (chirp_media.chirp_id IS NOT NULL AND chirp_visible_to(chirp_media.chirp_id, sqlc.narg('viewer_id')::uuid))
+);
@@ -0,0 +1,21 @@
+-- name: CreateMute :one
+-- Muting the same thing twice creates nothing, which comes back as no rows
+INSERT INTO mutes (id, user_id, kind, muted_user_id, term, created_at, expires_at)
+VALUES (
+	GEN_RANDOM_UUID(),
+	$1,
+	$2,
+	$3,
+	$4,
+	NOW(),
+	$5
+) ON CONFLICT DO NOTHING
+RETURNING *;
+
+-- name: DeleteMute :execrows
+DELETE FROM mutes WHERE id = $1 AND user_id = $2;
+
+-- name: GetActiveMutes :many
+SELECT * FROM mutes
+WHERE user_id = $1 AND (expires_at IS NULL OR expires_at > NOW())
+ORDER BY created_at DESC, id DESC;
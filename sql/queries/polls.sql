@@ -0,0 +1,52 @@
+-- name: CreatePoll :one
+INSERT INTO polls (id, chirp_id, closes_at, created_at)
+VALUES (
+	GEN_RANDOM_UUID(),
+	$1,
+	$2,
+	NOW()
+) RETURNING *;
+
+-- name: CreatePollOptions :exec
+INSERT INTO poll_options (poll_id, position, text)
+SELECT sqlc.arg('poll_id'), UNNEST(sqlc.arg('positions')::int[]), UNNEST(sqlc.arg('texts')::text[]);
+
+-- name: GetPollsForChirps :many
+SELECT polls.id, polls.chirp_id, polls.closes_at, poll_options.position, poll_options.text FROM polls
+INNER JOIN poll_options ON polls.id = poll_options.poll_id
+WHERE polls.chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+ORDER BY polls.id, poll_options.position ASC;
+
+-- name: GetPollTallies :many
+SELECT poll_id, position, COUNT(*) AS vote_count FROM poll_votes
+WHERE poll_id = ANY(sqlc.arg('poll_ids')::uuid[])
+GROUP BY poll_id, position;
+
+-- name: GetViewerVotes :many
+SELECT poll_id, position FROM poll_votes
+WHERE poll_id = ANY(sqlc.arg('poll_ids')::uuid[]) AND user_id = sqlc.arg('user_id');
+
+-- name: GetPollByChirp :one
+SELECT * FROM polls WHERE chirp_id = $1;
+
+-- name: CreateVote :execrows
+-- The closing check is repeated here so a vote racing the deadline loses
+INSERT INTO poll_votes (poll_id, user_id, position, created_at)
+SELECT polls.id, sqlc.arg('user_id'), sqlc.arg('position'), NOW() FROM polls
+WHERE polls.id = sqlc.arg('poll_id') AND polls.closes_at > NOW()
+ON CONFLICT DO NOTHING;
+
+-- name: ClaimClosingPolls :many
+-- SKIP LOCKED keeps several instances from closing the same poll twice.
+-- Polls on chirps that are not out yet or sit in the trash are left alone,
+-- no one is told a poll closed that nobody could see
+SELECT polls.id, polls.chirp_id, chirps.user_id FROM polls
+INNER JOIN chirps ON polls.chirp_id = chirps.id
+WHERE polls.closes_at <= NOW() AND polls.closed_at IS NULL
+AND chirps.publish_at IS NULL AND chirps.deleted_at IS NULL
+ORDER BY polls.closes_at ASC
+LIMIT sqlc.arg('row_limit')
+FOR UPDATE OF polls SKIP LOCKED;
+
+-- name: MarkPollClosed :exec
+UPDATE polls SET closed_at = NOW() WHERE id = $1;
@@ -0,0 +1,95 @@
+-- name: ClaimPendingFanouts :many
+-- SKIP LOCKED keeps several instances from fanning out the same chirp twice
+SELECT id, user_id, created_at FROM chirps
+WHERE fanned_out_at IS NULL AND publish_at IS NULL AND deleted_at IS NULL
+ORDER BY created_at ASC
+LIMIT sqlc.arg('row_limit')
+FOR UPDATE SKIP LOCKED;
+
+-- name: CountFollowers :one
+SELECT COUNT(*) FROM follows WHERE followee_id = $1;
+
+-- name: FanOutChirp :exec
+INSERT INTO timeline_entries (user_id, chirp_id, created_at)
+SELECT follows.follower_id, sqlc.arg('chirp_id')::uuid, sqlc.arg('created_at')::timestamp FROM follows
+WHERE follows.followee_id = sqlc.arg('user_id')
+ON CONFLICT DO NOTHING;
+
+-- name: MarkChirpFannedOut :exec
+UPDATE chirps SET fanned_out_at = NOW(), fanout_on_read = $2 WHERE id = $1;
+
+-- name: BackfillTimeline :exec
+-- A new follow brings the followee's latest chirps into the follower's
+-- timeline rather than leaving it empty until they next post
+INSERT INTO timeline_entries (user_id, chirp_id, created_at)
+SELECT sqlc.arg('user_id')::uuid, recentchirps.id, recentchirps.created_at FROM (
+	SELECT chirps.id, chirps.created_at FROM chirps
+	WHERE chirps.user_id = sqlc.arg('author_id') AND chirps.fanned_out_at IS NOT NULL AND NOT chirps.fanout_on_read
+	ORDER BY chirps.created_at DESC
+	LIMIT sqlc.arg('row_limit')
+) AS recentchirps
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteTimelineAuthor :exec
+DELETE FROM timeline_entries
+WHERE timeline_entries.user_id = sqlc.arg('user_id')
+AND timeline_entries.chirp_id IN (SELECT chirps.id FROM chirps WHERE chirps.user_id = sqlc.arg('author_id'));
+
+-- name: ListTimeline :many
+-- A timeline is what was written to it, the chirps of followed accounts
+-- that are read instead, and the user's own chirps. Each part is filtered
+-- and cut to a page on its own, so a page only ever reads a page's worth of
+-- rows from each
+WITH timeline AS (
+	(
+		SELECT chirps.id, chirps.created_at FROM timeline_entries
+		INNER JOIN chirps ON timeline_entries.chirp_id = chirps.id
+		WHERE timeline_entries.user_id = sqlc.arg('user_id')
+		AND (
+			sqlc.narg('cursor_time')::timestamp IS NULL
+			OR (timeline_entries.created_at, timeline_entries.chirp_id) < (sqlc.narg('cursor_time')::timestamp, sqlc.narg('cursor_id')::uuid)
+		)
+		AND chirps.publish_at IS NULL
+		AND chirp_visible_to(chirps.id, sqlc.arg('user_id'))
+		AND NOT chirp_muted_for(chirps.id, sqlc.arg('user_id'))
+		AND (NOT sqlc.arg('hide_sensitive')::boolean OR NOT chirp_sensitive(chirps.id))
+		ORDER BY timeline_entries.created_at DESC, timeline_entries.chirp_id DESC
+		LIMIT sqlc.arg('row_limit')
+	)
+	UNION
+	(
+		SELECT chirps.id, chirps.created_at FROM follows
+		INNER JOIN chirps ON follows.followee_id = chirps.user_id
+		WHERE follows.follower_id = sqlc.arg('user_id') AND chirps.fanout_on_read
+		AND (
+			sqlc.narg('cursor_time')::timestamp IS NULL
+			OR (chirps.created_at, chirps.id) < (sqlc.narg('cursor_time')::timestamp, sqlc.narg('cursor_id')::uuid)
+		)
+		AND chirps.publish_at IS NULL
+		AND chirp_visible_to(chirps.id, sqlc.arg('user_id'))
+		AND NOT chirp_muted_for(chirps.id, sqlc.arg('user_id'))
+		AND (NOT sqlc.arg('hide_sensitive')::boolean OR NOT chirp_sensitive(chirps.id))
+		ORDER BY chirps.created_at DESC, chirps.id DESC
+		LIMIT sqlc.arg('row_limit')
+	)
+	UNION
+	(
+		SELECT chirps.id, chirps.created_at FROM chirps
+		WHERE chirps.user_id = sqlc.arg('user_id')
+		AND (
+			sqlc.narg('cursor_time')::timestamp IS NULL
+			OR (chirps.created_at, chirps.id) < (sqlc.narg('cursor_time')::timestamp, sqlc.narg('cursor_id')::uuid)
+		)
+		AND chirps.publish_at IS NULL
+		AND chirp_visible_to(chirps.id, sqlc.arg('user_id'))
+		AND NOT chirp_muted_for(chirps.id, sqlc.arg('user_id'))
+		AND (NOT sqlc.arg('hide_sensitive')::boolean OR NOT chirp_sensitive(chirps.id))
+		ORDER BY chirps.created_at DESC, chirps.id DESC
+		LIMIT sqlc.arg('row_limit')
+	)
+)
+SELECT chirps.id, chirps.body, chirps.user_id, chirps.created_at, chirps.updated_at, chirps.in_reply_to, chirps.rechirp_of, chirps.quote_of, chirps.publish_at, chirps.content_warning, (chirps.sensitive OR chirps.sensitive_by_moderator)::boolean AS sensitive, chirps.visibility, users.is_chirpy_red FROM timeline
+INNER JOIN chirps ON timeline.id = chirps.id
+INNER JOIN users ON chirps.user_id = users.id
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('row_limit');
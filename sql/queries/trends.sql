@@ -0,0 +1,20 @@
+-- name: GetRecentHashtags :many
+SELECT chirp_hashtags.tag, chirp_hashtags.chirp_id, chirps.created_at FROM chirp_hashtags
+INNER JOIN chirps ON chirp_hashtags.chirp_id = chirps.id
+INNER JOIN users ON chirps.user_id = users.id
+WHERE chirps.created_at >= $1 AND chirps.publish_at IS NULL AND chirps.deleted_at IS NULL AND chirps.visibility = 'public'
+AND NOT users.protected
+ORDER BY chirps.created_at ASC;
+
+-- name: GetTrendExclusions :many
+SELECT * FROM trend_exclusions ORDER BY tag ASC;
+
+-- name: CreateTrendExclusion :exec
+INSERT INTO trend_exclusions (tag, created_at)
+VALUES (
+	$1,
+	NOW()
+) ON CONFLICT DO NOTHING;
+
+-- name: DeleteTrendExclusion :exec
+DELETE FROM trend_exclusions WHERE tag = $1;
@@ -1,12 +1,15 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+-- A handle that is already taken creates nothing, which comes back as no rows
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, handle)
 VALUES (
 	GEN_RANDOM_UUID(),
 	NOW(),
 	NOW(),
 	$1,
-	$2
-) RETURNING id, created_at, updated_at, email, is_chirpy_red;
+	$2,
+	$3
+) ON CONFLICT (handle) DO NOTHING
+RETURNING id, created_at, updated_at, email, is_chirpy_red, handle;
 
 -- name: DeleteUsers :exec
 DELETE FROM users;
@@ -15,7 +18,38 @@ DELETE FROM users;
 SELECT * FROM users WHERE email = $1;
 
 -- name: UpdateUserDetails :one
-UPDATE users SET email = $1, hashed_password = $2, updated_at = NOW() WHERE id = $3 RETURNING id, created_at, updated_at, email, is_chirpy_red;
+UPDATE users SET email = $1, hashed_password = $2, updated_at = NOW() WHERE id = $3 RETURNING id, created_at, updated_at, email, is_chirpy_red, handle;
 
 -- name: UpdateRedUser :exec
 UPDATE users SET is_chirpy_red = TRUE, updated_at = NOW() WHERE id = $1;
+
+-- name: GetUserByID :one
+SELECT id, created_at, updated_at, email, is_chirpy_red, protected, handle FROM users WHERE id = $1;
+
+-- name: IsModerator :one
+SELECT is_moderator FROM users WHERE id = $1;
+
+-- name: SetModerator :execrows
+UPDATE users SET is_moderator = $2, updated_at = NOW() WHERE id = $1;
+
+-- name: LockUser :one
+-- Serialises changes that have to respect a per user limit
+SELECT is_chirpy_red FROM users WHERE id = $1 FOR UPDATE;
+
+-- name: GetSensitiveContent :one
+SELECT sensitive_content FROM users WHERE id = $1;
+
+-- name: SetSensitiveContent :execrows
+UPDATE users SET sensitive_content = $2, updated_at = NOW() WHERE id = $1;
+
+-- name: GetPreferences :one
+SELECT sensitive_content, protected FROM users WHERE id = $1;
+
+-- name: SetProtected :execrows
+UPDATE users SET protected = $2, updated_at = NOW() WHERE id = $1;
+
+-- name: SetHandle :execrows
+-- A handle another user has creates nothing, which comes back as no rows
+UPDATE users SET handle = sqlc.arg('handle'), updated_at = NOW()
+WHERE users.id = sqlc.arg('id')
+AND NOT EXISTS (SELECT 1 FROM users AS others WHERE others.handle = sqlc.arg('handle') AND others.id <> sqlc.arg('id')::uuid);
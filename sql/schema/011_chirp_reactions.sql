@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE chirp_reactions (
+	chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	reaction TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (chirp_id, user_id, reaction)
+);
+CREATE INDEX chirp_reactions_user_id_reaction_created_at_idx ON chirp_reactions (user_id, reaction, created_at, chirp_id);
+
+-- +goose Down
+DROP TABLE chirp_reactions;
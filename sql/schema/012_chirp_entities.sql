@@ -0,0 +1,22 @@
+-- +goose Up
+CREATE TABLE chirp_hashtags (
+	chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+	tag TEXT NOT NULL,
+	start_index INTEGER NOT NULL,
+	end_index INTEGER NOT NULL,
+	PRIMARY KEY (chirp_id, start_index)
+);
+CREATE INDEX chirp_hashtags_tag_idx ON chirp_hashtags (tag, chirp_id);
+
+CREATE TABLE chirp_mentions (
+	chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	start_index INTEGER NOT NULL,
+	end_index INTEGER NOT NULL,
+	PRIMARY KEY (chirp_id, start_index)
+);
+CREATE INDEX chirp_mentions_user_id_idx ON chirp_mentions (user_id, chirp_id);
+
+-- +goose Down
+DROP TABLE chirp_mentions;
+DROP TABLE chirp_hashtags;
@@ -0,0 +1,8 @@
+-- +goose Up
+CREATE TABLE trend_exclusions (
+	tag TEXT PRIMARY KEY,
+	created_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE trend_exclusions;
@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE media (
+	id UUID PRIMARY KEY,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	storage_key TEXT UNIQUE NOT NULL,
+	content_type TEXT NOT NULL,
+	size_bytes BIGINT NOT NULL,
+	created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE chirp_media (
+	chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+	media_id UUID UNIQUE NOT NULL REFERENCES media(id) ON DELETE CASCADE,
+	position INTEGER NOT NULL,
+	PRIMARY KEY (chirp_id, media_id)
+);
+
+-- +goose Down
+DROP TABLE chirp_media;
+DROP TABLE media;
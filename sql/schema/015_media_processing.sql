@@ -0,0 +1,22 @@
+-- +goose Up
+ALTER TABLE media ADD COLUMN status TEXT NOT NULL DEFAULT 'ready';
+ALTER TABLE media ADD COLUMN width INTEGER;
+ALTER TABLE media ADD COLUMN height INTEGER;
+ALTER TABLE media ADD COLUMN blurhash TEXT;
+
+CREATE TABLE media_variants (
+	media_id UUID NOT NULL REFERENCES media(id) ON DELETE CASCADE,
+	name TEXT NOT NULL,
+	storage_key TEXT UNIQUE NOT NULL,
+	content_type TEXT NOT NULL,
+	width INTEGER NOT NULL,
+	height INTEGER NOT NULL,
+	PRIMARY KEY (media_id, name)
+);
+
+-- +goose Down
+DROP TABLE media_variants;
+ALTER TABLE media DROP COLUMN blurhash;
+ALTER TABLE media DROP COLUMN height;
+ALTER TABLE media DROP COLUMN width;
+ALTER TABLE media DROP COLUMN status;
@@ -0,0 +1,21 @@
+-- +goose Up
+ALTER TABLE chirps ADD COLUMN publish_at TIMESTAMP;
+CREATE INDEX chirps_publish_at_idx ON chirps (publish_at) WHERE publish_at IS NOT NULL;
+
+-- Every read path asks this one question, so later rules about who may see
+-- a chirp are added here instead of to each query
+-- +goose StatementBegin
+CREATE FUNCTION chirp_visible_to(target_id UUID, viewer_id UUID) RETURNS BOOLEAN
+LANGUAGE sql STABLE AS $$
+	SELECT EXISTS (
+		SELECT 1 FROM chirps
+		WHERE chirps.id = target_id
+		AND (chirps.publish_at IS NULL OR chirps.user_id = viewer_id)
+	);
+$$;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION chirp_visible_to(UUID, UUID);
+DROP INDEX chirps_publish_at_idx;
+ALTER TABLE chirps DROP COLUMN publish_at;
@@ -0,0 +1,37 @@
+-- +goose Up
+ALTER TABLE chirps ADD COLUMN deleted_at TIMESTAMP;
+CREATE INDEX chirps_deleted_at_idx ON chirps (deleted_at) WHERE deleted_at IS NOT NULL;
+ALTER TABLE users ADD COLUMN is_moderator BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Deleted chirps are hidden from everyone, their author included, and a bare
+-- rechirp goes with its original since there is nothing else to show
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION chirp_visible_to(target_id UUID, viewer_id UUID) RETURNS BOOLEAN
+LANGUAGE sql STABLE AS $$
+	SELECT EXISTS (
+		SELECT 1 FROM chirps
+		WHERE chirps.id = target_id
+		AND chirps.deleted_at IS NULL
+		AND (chirps.publish_at IS NULL OR chirps.user_id = viewer_id)
+		AND (
+			chirps.rechirp_of IS NULL
+			OR EXISTS (SELECT 1 FROM chirps AS originals WHERE originals.id = chirps.rechirp_of AND originals.deleted_at IS NULL)
+		)
+	);
+$$;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION chirp_visible_to(target_id UUID, viewer_id UUID) RETURNS BOOLEAN
+LANGUAGE sql STABLE AS $$
+	SELECT EXISTS (
+		SELECT 1 FROM chirps
+		WHERE chirps.id = target_id
+		AND (chirps.publish_at IS NULL OR chirps.user_id = viewer_id)
+	);
+$$;
+-- +goose StatementEnd
+ALTER TABLE users DROP COLUMN is_moderator;
+DROP INDEX chirps_deleted_at_idx;
+ALTER TABLE chirps DROP COLUMN deleted_at;
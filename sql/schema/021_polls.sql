@@ -0,0 +1,43 @@
+-- +goose Up
+CREATE TABLE polls (
+	id UUID PRIMARY KEY,
+	chirp_id UUID UNIQUE NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+	closes_at TIMESTAMP NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	-- Set once the closing has been handled, closes_at alone says when the
+	-- poll stops taking votes
+	closed_at TIMESTAMP
+);
+CREATE INDEX polls_closes_at_idx ON polls (closes_at) WHERE closed_at IS NULL;
+
+CREATE TABLE poll_options (
+	poll_id UUID NOT NULL REFERENCES polls(id) ON DELETE CASCADE,
+	position INTEGER NOT NULL,
+	text TEXT NOT NULL,
+	PRIMARY KEY (poll_id, position)
+);
+
+CREATE TABLE poll_votes (
+	poll_id UUID NOT NULL REFERENCES polls(id) ON DELETE CASCADE,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	position INTEGER NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (poll_id, user_id),
+	FOREIGN KEY (poll_id, position) REFERENCES poll_options(poll_id, position)
+);
+
+-- Things that happened that a user should hear about
+CREATE TABLE events (
+	id UUID PRIMARY KEY,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	type TEXT NOT NULL,
+	payload JSONB NOT NULL,
+	created_at TIMESTAMP NOT NULL
+);
+CREATE INDEX events_user_id_created_at_idx ON events (user_id, created_at, id);
+
+-- +goose Down
+DROP TABLE events;
+DROP TABLE poll_votes;
+DROP TABLE poll_options;
+DROP TABLE polls;
@@ -0,0 +1,48 @@
+-- +goose Up
+ALTER TABLE chirps ADD COLUMN visibility TEXT NOT NULL DEFAULT 'public'
+CHECK (visibility IN ('public', 'unlisted', 'followers', 'mentioned'));
+
+-- Unlisted chirps can be read by anyone who has them and are only kept out
+-- of listings. Without a follow graph a followers only chirp is seen by
+-- nobody but its author
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION chirp_visible_to(target_id UUID, viewer_id UUID) RETURNS BOOLEAN
+LANGUAGE sql STABLE AS $$
+	SELECT EXISTS (
+		SELECT 1 FROM chirps
+		WHERE chirps.id = target_id
+		AND chirps.deleted_at IS NULL
+		AND (chirps.publish_at IS NULL OR chirps.user_id = viewer_id)
+		AND (
+			chirps.visibility IN ('public', 'unlisted')
+			OR chirps.user_id = viewer_id
+			OR (
+				chirps.visibility = 'mentioned'
+				AND EXISTS (SELECT 1 FROM chirp_mentions WHERE chirp_mentions.chirp_id = chirps.id AND chirp_mentions.user_id = viewer_id)
+			)
+		)
+		AND (
+			chirps.rechirp_of IS NULL
+			OR EXISTS (SELECT 1 FROM chirps AS originals WHERE originals.id = chirps.rechirp_of AND originals.deleted_at IS NULL)
+		)
+	);
+$$;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION chirp_visible_to(target_id UUID, viewer_id UUID) RETURNS BOOLEAN
+LANGUAGE sql STABLE AS $$
+	SELECT EXISTS (
+		SELECT 1 FROM chirps
+		WHERE chirps.id = target_id
+		AND chirps.deleted_at IS NULL
+		AND (chirps.publish_at IS NULL OR chirps.user_id = viewer_id)
+		AND (
+			chirps.rechirp_of IS NULL
+			OR EXISTS (SELECT 1 FROM chirps AS originals WHERE originals.id = chirps.rechirp_of AND originals.deleted_at IS NULL)
+		)
+	);
+$$;
+-- +goose StatementEnd
+ALTER TABLE chirps DROP COLUMN visibility;
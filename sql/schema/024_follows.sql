@@ -0,0 +1,65 @@
+-- +goose Up
+CREATE TABLE follows (
+	follower_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	followee_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	created_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (follower_id, followee_id),
+	CHECK (follower_id <> followee_id)
+);
+CREATE INDEX follows_follower_id_created_at_idx ON follows (follower_id, created_at DESC);
+CREATE INDEX follows_followee_id_created_at_idx ON follows (followee_id, created_at DESC);
+
+-- Followers only chirps can now be read by their author's followers
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION chirp_visible_to(target_id UUID, viewer_id UUID) RETURNS BOOLEAN
+LANGUAGE sql STABLE AS $$
+	SELECT EXISTS (
+		SELECT 1 FROM chirps
+		WHERE chirps.id = target_id
+		AND chirps.deleted_at IS NULL
+		AND (chirps.publish_at IS NULL OR chirps.user_id = viewer_id)
+		AND (
+			chirps.visibility IN ('public', 'unlisted')
+			OR chirps.user_id = viewer_id
+			OR (
+				chirps.visibility = 'followers'
+				AND EXISTS (SELECT 1 FROM follows WHERE follows.follower_id = viewer_id AND follows.followee_id = chirps.user_id)
+			)
+			OR (
+				chirps.visibility = 'mentioned'
+				AND EXISTS (SELECT 1 FROM chirp_mentions WHERE chirp_mentions.chirp_id = chirps.id AND chirp_mentions.user_id = viewer_id)
+			)
+		)
+		AND (
+			chirps.rechirp_of IS NULL
+			OR EXISTS (SELECT 1 FROM chirps AS originals WHERE originals.id = chirps.rechirp_of AND originals.deleted_at IS NULL)
+		)
+	);
+$$;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION chirp_visible_to(target_id UUID, viewer_id UUID) RETURNS BOOLEAN
+LANGUAGE sql STABLE AS $$
+	SELECT EXISTS (
+		SELECT 1 FROM chirps
+		WHERE chirps.id = target_id
+		AND chirps.deleted_at IS NULL
+		AND (chirps.publish_at IS NULL OR chirps.user_id = viewer_id)
+		AND (
+			chirps.visibility IN ('public', 'unlisted')
+			OR chirps.user_id = viewer_id
+			OR (
+				chirps.visibility = 'mentioned'
+				AND EXISTS (SELECT 1 FROM chirp_mentions WHERE chirp_mentions.chirp_id = chirps.id AND chirp_mentions.user_id = viewer_id)
+			)
+		)
+		AND (
+			chirps.rechirp_of IS NULL
+			OR EXISTS (SELECT 1 FROM chirps AS originals WHERE originals.id = chirps.rechirp_of AND originals.deleted_at IS NULL)
+		)
+	);
+$$;
+-- +goose StatementEnd
+DROP TABLE follows;
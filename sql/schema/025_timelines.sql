@@ -0,0 +1,27 @@
+-- +goose Up
+CREATE TABLE timeline_entries (
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+	created_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (user_id, chirp_id)
+);
+CREATE INDEX timeline_entries_user_id_created_at_idx ON timeline_entries (user_id, created_at DESC, chirp_id DESC);
+
+-- fanned_out_at is NULL until a chirp has been written to its author's
+-- followers' timelines. fanout_on_read marks chirps by accounts with too many
+-- followers to write to, which timelines read from their author instead
+ALTER TABLE chirps ADD COLUMN fanned_out_at TIMESTAMP;
+ALTER TABLE chirps ADD COLUMN fanout_on_read BOOLEAN NOT NULL DEFAULT FALSE;
+CREATE INDEX chirps_fanout_pending_idx ON chirps (created_at) WHERE fanned_out_at IS NULL;
+
+INSERT INTO timeline_entries (user_id, chirp_id, created_at)
+SELECT follows.follower_id, chirps.id, chirps.created_at FROM chirps
+INNER JOIN follows ON chirps.user_id = follows.followee_id
+WHERE chirps.publish_at IS NULL AND chirps.deleted_at IS NULL;
+UPDATE chirps SET fanned_out_at = NOW() WHERE publish_at IS NULL AND deleted_at IS NULL;
+
+-- +goose Down
+DROP INDEX chirps_fanout_pending_idx;
+ALTER TABLE chirps DROP COLUMN fanout_on_read;
+ALTER TABLE chirps DROP COLUMN fanned_out_at;
+DROP TABLE timeline_entries;
@@ -0,0 +1,82 @@
+-- +goose Up
+CREATE TABLE blocks (
+	blocker_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	blocked_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	created_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (blocker_id, blocked_id),
+	CHECK (blocker_id <> blocked_id)
+);
+CREATE INDEX blocks_blocker_id_created_at_idx ON blocks (blocker_id, created_at DESC);
+CREATE INDEX blocks_blocked_id_idx ON blocks (blocked_id);
+
+-- A block hides both users' chirps from each other, rechirps of them included
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION chirp_visible_to(target_id UUID, viewer_id UUID) RETURNS BOOLEAN
+LANGUAGE sql STABLE AS $$
+	SELECT EXISTS (
+		SELECT 1 FROM chirps
+		WHERE chirps.id = target_id
+		AND chirps.deleted_at IS NULL
+		AND (chirps.publish_at IS NULL OR chirps.user_id = viewer_id)
+		AND (
+			chirps.visibility IN ('public', 'unlisted')
+			OR chirps.user_id = viewer_id
+			OR (
+				chirps.visibility = 'followers'
+				AND EXISTS (SELECT 1 FROM follows WHERE follows.follower_id = viewer_id AND follows.followee_id = chirps.user_id)
+			)
+			OR (
+				chirps.visibility = 'mentioned'
+				AND EXISTS (SELECT 1 FROM chirp_mentions WHERE chirp_mentions.chirp_id = chirps.id AND chirp_mentions.user_id = viewer_id)
+			)
+		)
+		AND NOT EXISTS (
+			SELECT 1 FROM blocks
+			WHERE (blocks.blocker_id = viewer_id AND blocks.blocked_id = chirps.user_id)
+			OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = viewer_id)
+		)
+		AND (
+			chirps.rechirp_of IS NULL
+			OR EXISTS (
+				SELECT 1 FROM chirps AS originals
+				WHERE originals.id = chirps.rechirp_of AND originals.deleted_at IS NULL
+				AND NOT EXISTS (
+					SELECT 1 FROM blocks
+					WHERE (blocks.blocker_id = viewer_id AND blocks.blocked_id = originals.user_id)
+					OR (blocks.blocker_id = originals.user_id AND blocks.blocked_id = viewer_id)
+				)
+			)
+		)
+	);
+$$;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION chirp_visible_to(target_id UUID, viewer_id UUID) RETURNS BOOLEAN
+LANGUAGE sql STABLE AS $$
+	SELECT EXISTS (
+		SELECT 1 FROM chirps
+		WHERE chirps.id = target_id
+		AND chirps.deleted_at IS NULL
+		AND (chirps.publish_at IS NULL OR chirps.user_id = viewer_id)
+		AND (
+			chirps.visibility IN ('public', 'unlisted')
+			OR chirps.user_id = viewer_id
+			OR (
+				chirps.visibility = 'followers'
+				AND EXISTS (SELECT 1 FROM follows WHERE follows.follower_id = viewer_id AND follows.followee_id = chirps.user_id)
+			)
+			OR (
+				chirps.visibility = 'mentioned'
+				AND EXISTS (SELECT 1 FROM chirp_mentions WHERE chirp_mentions.chirp_id = chirps.id AND chirp_mentions.user_id = viewer_id)
+			)
+		)
+		AND (
+			chirps.rechirp_of IS NULL
+			OR EXISTS (SELECT 1 FROM chirps AS originals WHERE originals.id = chirps.rechirp_of AND originals.deleted_at IS NULL)
+		)
+	);
+$$;
+-- +goose StatementEnd
+DROP TABLE blocks;
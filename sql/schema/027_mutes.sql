@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE mutes (
+	id UUID PRIMARY KEY,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	kind TEXT NOT NULL CHECK (kind IN ('account', 'keyword', 'hashtag')),
+	muted_user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+	term TEXT,
+	created_at TIMESTAMP NOT NULL,
+	expires_at TIMESTAMP,
+	CHECK ((kind = 'account') = (muted_user_id IS NOT NULL)),
+	CHECK ((kind = 'account') = (term IS NULL))
+);
+CREATE UNIQUE INDEX mutes_account_idx ON mutes (user_id, muted_user_id) WHERE kind = 'account';
+CREATE UNIQUE INDEX mutes_term_idx ON mutes (user_id, kind, term) WHERE kind <> 'account';
+
+-- +goose Down
+DROP TABLE mutes;
@@ -0,0 +1,108 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN protected BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE follow_requests (
+	requester_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	target_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	created_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (requester_id, target_id),
+	CHECK (requester_id <> target_id)
+);
+CREATE INDEX follow_requests_target_id_created_at_idx ON follow_requests (target_id, created_at DESC);
+
+-- A protected account's chirps, and rechirps of them, are only readable by
+-- the followers it approved
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION chirp_visible_to(target_id UUID, viewer_id UUID) RETURNS BOOLEAN
+LANGUAGE sql STABLE AS $$
+	SELECT EXISTS (
+		SELECT 1 FROM chirps
+		WHERE chirps.id = target_id
+		AND chirps.deleted_at IS NULL
+		AND (chirps.publish_at IS NULL OR chirps.user_id = viewer_id)
+		AND (
+			chirps.visibility IN ('public', 'unlisted')
+			OR chirps.user_id = viewer_id
+			OR (
+				chirps.visibility = 'followers'
+				AND EXISTS (SELECT 1 FROM follows WHERE follows.follower_id = viewer_id AND follows.followee_id = chirps.user_id)
+			)
+			OR (
+				chirps.visibility = 'mentioned'
+				AND EXISTS (SELECT 1 FROM chirp_mentions WHERE chirp_mentions.chirp_id = chirps.id AND chirp_mentions.user_id = viewer_id)
+			)
+		)
+		AND (
+			chirps.user_id = viewer_id
+			OR NOT EXISTS (SELECT 1 FROM users WHERE users.id = chirps.user_id AND users.protected)
+			OR EXISTS (SELECT 1 FROM follows WHERE follows.follower_id = viewer_id AND follows.followee_id = chirps.user_id)
+		)
+		AND NOT EXISTS (
+			SELECT 1 FROM blocks
+			WHERE (blocks.blocker_id = viewer_id AND blocks.blocked_id = chirps.user_id)
+			OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = viewer_id)
+		)
+		AND (
+			chirps.rechirp_of IS NULL
+			OR EXISTS (
+				SELECT 1 FROM chirps AS originals
+				WHERE originals.id = chirps.rechirp_of AND originals.deleted_at IS NULL
+				AND (
+					originals.user_id = viewer_id
+					OR NOT EXISTS (SELECT 1 FROM users WHERE users.id = originals.user_id AND users.protected)
+					OR EXISTS (SELECT 1 FROM follows WHERE follows.follower_id = viewer_id AND follows.followee_id = originals.user_id)
+				)
+				AND NOT EXISTS (
+					SELECT 1 FROM blocks
+					WHERE (blocks.blocker_id = viewer_id AND blocks.blocked_id = originals.user_id)
+					OR (blocks.blocker_id = originals.user_id AND blocks.blocked_id = viewer_id)
+				)
+			)
+		)
+	);
+$$;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION chirp_visible_to(target_id UUID, viewer_id UUID) RETURNS BOOLEAN
+LANGUAGE sql STABLE AS $$
+	SELECT EXISTS (
+		SELECT 1 FROM chirps
+		WHERE chirps.id = target_id
+		AND chirps.deleted_at IS NULL
+		AND (chirps.publish_at IS NULL OR chirps.user_id = viewer_id)
+		AND (
+			chirps.visibility IN ('public', 'unlisted')
+			OR chirps.user_id = viewer_id
+			OR (
+				chirps.visibility = 'followers'
+				AND EXISTS (SELECT 1 FROM follows WHERE follows.follower_id = viewer_id AND follows.followee_id = chirps.user_id)
+			)
+			OR (
+				chirps.visibility = 'mentioned'
+				AND EXISTS (SELECT 1 FROM chirp_mentions WHERE chirp_mentions.chirp_id = chirps.id AND chirp_mentions.user_id = viewer_id)
+			)
+		)
+		AND NOT EXISTS (
+			SELECT 1 FROM blocks
+			WHERE (blocks.blocker_id = viewer_id AND blocks.blocked_id = chirps.user_id)
+			OR (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = viewer_id)
+		)
+		AND (
+			chirps.rechirp_of IS NULL
+			OR EXISTS (
+				SELECT 1 FROM chirps AS originals
+				WHERE originals.id = chirps.rechirp_of AND originals.deleted_at IS NULL
+				AND NOT EXISTS (
+					SELECT 1 FROM blocks
+					WHERE (blocks.blocker_id = viewer_id AND blocks.blocked_id = originals.user_id)
+					OR (blocks.blocker_id = originals.user_id AND blocks.blocked_id = viewer_id)
+				)
+			)
+		)
+	);
+$$;
+-- +goose StatementEnd
+DROP TABLE follow_requests;
+ALTER TABLE users DROP COLUMN protected;
@@ -0,0 +1,11 @@
+-- +goose Up
+-- Mentions name users by a public handle, emails are only used to log in.
+-- Existing users get a placeholder they can change
+ALTER TABLE users ADD COLUMN handle TEXT;
+UPDATE users SET handle = 'user_' || SUBSTRING(REPLACE(id::text, '-', '') FROM 1 FOR 16);
+ALTER TABLE users ALTER COLUMN handle SET NOT NULL;
+ALTER TABLE users ADD CONSTRAINT users_handle_key UNIQUE (handle);
+ALTER TABLE users ADD CONSTRAINT users_handle_check CHECK (handle ~ '^[a-z0-9_]{1,30}$');
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN handle;
@@ -0,0 +1,17 @@
+-- +goose Up
+-- A chirp counts as sensitive for viewers filtering sensitive content when it,
+-- or the chirp it rechirps or quotes, is flagged or carries a warning
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION chirp_sensitive(target_id UUID) RETURNS BOOLEAN
+LANGUAGE sql STABLE AS $$
+	SELECT EXISTS (
+		SELECT 1 FROM chirps
+		INNER JOIN chirps AS flagged ON flagged.id IN (chirps.id, chirps.rechirp_of, chirps.quote_of)
+		WHERE chirps.id = target_id
+		AND (flagged.sensitive OR flagged.sensitive_by_moderator OR flagged.content_warning IS NOT NULL)
+	);
+$$;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION chirp_sensitive(UUID);
@@ -0,0 +1,39 @@
+-- +goose Up
+-- Keyword mutes match whole words ignoring case and differences in
+-- whitespace, only edges of the term that are word characters need a boundary
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION mute_pattern(term TEXT) RETURNS TEXT
+LANGUAGE sql IMMUTABLE AS $$
+	SELECT CASE WHEN term ~ '^[[:alnum:]_]' THEN '\m' ELSE '' END
+		|| REGEXP_REPLACE(REGEXP_REPLACE(term, '([^[:alnum:]_ ])', '\\\1', 'g'), ' ', '\\s+', 'g')
+		|| CASE WHEN term ~ '[[:alnum:]_]$' THEN '\M' ELSE '' END;
+$$;
+-- +goose StatementEnd
+
+-- A chirp is muted for a viewer when it, or the chirp it rechirps or quotes,
+-- falls under one of their active mutes. The viewer's own chirps never are
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION chirp_muted_for(target_id UUID, viewer_id UUID) RETURNS BOOLEAN
+LANGUAGE sql STABLE AS $$
+	SELECT EXISTS (
+		SELECT 1 FROM chirps
+		INNER JOIN chirps AS shown ON shown.id IN (chirps.id, chirps.rechirp_of, chirps.quote_of)
+		INNER JOIN mutes ON mutes.user_id = viewer_id
+		WHERE chirps.id = target_id
+		AND chirps.user_id <> viewer_id AND shown.user_id <> viewer_id
+		AND (mutes.expires_at IS NULL OR mutes.expires_at > NOW())
+		AND (
+			(mutes.kind = 'account' AND shown.user_id = mutes.muted_user_id)
+			OR (mutes.kind = 'keyword' AND shown.body ~* mute_pattern(mutes.term))
+			OR (mutes.kind = 'hashtag' AND EXISTS (
+				SELECT 1 FROM chirp_hashtags
+				WHERE chirp_hashtags.chirp_id = shown.id AND chirp_hashtags.tag = mutes.term
+			))
+		)
+	);
+$$;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION chirp_muted_for(UUID, UUID);
+DROP FUNCTION mute_pattern(TEXT);
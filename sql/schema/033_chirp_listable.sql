@@ -0,0 +1,44 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION chirp_has_media(target_id UUID) RETURNS BOOLEAN
+LANGUAGE sql STABLE AS $$
+	SELECT EXISTS (SELECT 1 FROM chirp_media WHERE chirp_media.chirp_id = target_id);
+$$;
+-- +goose StatementEnd
+
+-- Every filter the chirp listings share, so the query for each sort order
+-- only adds its ordering and cursor. It is kept to a single expression with
+-- no subqueries, which lets Postgres inline it into the calling query where
+-- the author and time conditions can still use the chirps indexes
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION chirp_listable(
+	chirp chirps,
+	author_is_chirpy_red BOOLEAN,
+	viewer_id UUID,
+	author_ids UUID[],
+	since TIMESTAMP,
+	until TIMESTAMP,
+	is_chirpy_red BOOLEAN,
+	has_media BOOLEAN,
+	exclude_pinned BOOLEAN,
+	hide_sensitive BOOLEAN
+) RETURNS BOOLEAN
+LANGUAGE sql STABLE AS $$
+	SELECT (CARDINALITY(author_ids) = 0 OR (chirp).user_id = ANY(author_ids))
+		AND chirp_visible_to((chirp).id, viewer_id)
+		AND NOT chirp_muted_for((chirp).id, viewer_id)
+		-- Unlisted chirps still show on their author's profile, a listing of
+		-- that one author, but not when they are one of several asked for
+		AND ((chirp).visibility <> 'unlisted' OR CARDINALITY(author_ids) = 1 OR (chirp).user_id = viewer_id)
+		AND (since IS NULL OR (chirp).created_at >= since)
+		AND (until IS NULL OR (chirp).created_at < until)
+		AND (is_chirpy_red IS NULL OR author_is_chirpy_red = is_chirpy_red)
+		AND (NOT exclude_pinned OR (chirp).pinned_at IS NULL)
+		AND (NOT hide_sensitive OR NOT chirp_sensitive((chirp).id))
+		AND (has_media IS NULL OR has_media = chirp_has_media((chirp).id));
+$$;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION chirp_listable(chirps, BOOLEAN, UUID, UUID[], TIMESTAMP, TIMESTAMP, BOOLEAN, BOOLEAN, BOOLEAN, BOOLEAN);
+DROP FUNCTION chirp_has_media(UUID);
@@ -0,0 +1,203 @@
+package state
+
+import (
+	"net/http"
+	"time"
+	"encoding/json"
+	"database/sql"
+	"github.com/junwei890/chirpy/internal/database"
+	"github.com/junwei890/chirpy/internal/auth"
+	"github.com/junwei890/chirpy/internal/pagination"
+	"github.com/google/uuid"
+)
+
+type blockPayload struct {
+	ID uuid.UUID `json:"id"`
+	Handle string `json:"handle"`
+	BlockedAt time.Time `json:"blocked_at"`
+}
+
+// PutBlock blocks a user, which also ends any follow or follow request
+// between the two users and takes each user's chirps off the other's timeline
+func (a *APIConfig) PutBlock(writer http.ResponseWriter, req *http.Request) {
+	jwtToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	userID, err := auth.ValidateJWT(jwtToken, a.SecretKey)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+
+	blockedID, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	if blockedID == userID {
+		ErrorResponseWriter(writer, BadRequest)
+		return
+	}
+	if _, err := a.PtrToQueries.GetUserByID(req.Context(), blockedID); err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+
+	tx, err := a.PtrToDB.BeginTx(req.Context(), nil)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	defer tx.Rollback()
+	queriesInTx := a.PtrToQueries.WithTx(tx)
+	createBlockParams := database.CreateBlockParams{
+		BlockerID: userID,
+		BlockedID: blockedID,
+	}
+	if _, err := queriesInTx.CreateBlock(req.Context(), createBlockParams); err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	deleteFollowsBetweenParams := database.DeleteFollowsBetweenParams{
+		UserID: userID,
+		OtherID: blockedID,
+	}
+	if err := queriesInTx.DeleteFollowsBetween(req.Context(), deleteFollowsBetweenParams); err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	deleteFollowRequestsBetweenParams := database.DeleteFollowRequestsBetweenParams{
+		UserID: userID,
+		OtherID: blockedID,
+	}
+	if err := queriesInTx.DeleteFollowRequestsBetween(req.Context(), deleteFollowRequestsBetweenParams); err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	for _, deleteTimelineAuthorParams := range []database.DeleteTimelineAuthorParams{
+		{UserID: userID, AuthorID: blockedID},
+		{UserID: blockedID, AuthorID: userID},
+	} {
+		if err := queriesInTx.DeleteTimelineAuthor(req.Context(), deleteTimelineAuthorParams); err != nil {
+			ErrorResponseWriter(writer, DatabaseError)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteBlock lifts a block, follows it ended are not restored
+func (a *APIConfig) DeleteBlock(writer http.ResponseWriter, req *http.Request) {
+	jwtToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	userID, err := auth.ValidateJWT(jwtToken, a.SecretKey)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+
+	blockedID, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+
+	deleteBlockParams := database.DeleteBlockParams{
+		BlockerID: userID,
+		BlockedID: blockedID,
+	}
+	deletedRows, err := a.PtrToQueries.DeleteBlock(req.Context(), deleteBlockParams)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	if deletedRows == 0 {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (a *APIConfig) GetBlocks(writer http.ResponseWriter, req *http.Request) {
+	type validResponse struct {
+		Blocks []blockPayload `json:"blocks"`
+		NextCursor *string `json:"next_cursor"`
+	}
+
+	jwtToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	userID, err := auth.ValidateJWT(jwtToken, a.SecretKey)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	limit, err := pagination.ParseLimit(req.URL.Query().Get("limit"))
+	if err != nil {
+		InvalidParameterResponseWriter(writer, "limit")
+		return
+	}
+
+	// One extra row is fetched to tell whether another page exists
+	listBlocksParams := database.ListBlocksParams{
+		UserID: userID,
+		RowLimit: limit + 1,
+	}
+	if encodedCursor := req.URL.Query().Get("cursor"); encodedCursor != "" {
+		cursor, err := pagination.DecodeCursor(encodedCursor)
+		if err != nil {
+			InvalidParameterResponseWriter(writer, "cursor")
+			return
+		}
+		listBlocksParams.CursorTime = sql.NullTime{Time: cursor.Time, Valid: true}
+		listBlocksParams.CursorID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+	sliceOfBlocks, err := a.PtrToQueries.ListBlocks(req.Context(), listBlocksParams)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+
+	formattedResponse := validResponse{
+		Blocks: []blockPayload{},
+	}
+	for index, block := range sliceOfBlocks {
+		if index == int(limit) {
+			lastBlock := sliceOfBlocks[index-1]
+			nextCursor := pagination.EncodeCursor(pagination.Cursor{
+				Time: lastBlock.BlockedAt,
+				ID: lastBlock.ID,
+			})
+			formattedResponse.NextCursor = &nextCursor
+			writer.Header().Set("Link", pagination.NextLink(req.URL.Path, req.URL.Query(), "cursor", nextCursor))
+			break
+		}
+		formattedResponse.Blocks = append(formattedResponse.Blocks, blockPayload{
+			ID: block.ID,
+			Handle: block.Handle,
+			BlockedAt: block.BlockedAt,
+		})
+	}
+
+	blocksInBytes, err := json.Marshal(formattedResponse)
+	if err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	if _, err := writer.Write(blocksInBytes); err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+	}
+}
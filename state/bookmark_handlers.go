@@ -0,0 +1,191 @@
+package state
+
+import (
+	"net/http"
+	"encoding/json"
+	"database/sql"
+	"github.com/junwei890/chirpy/internal/database"
+	"github.com/junwei890/chirpy/internal/auth"
+	"github.com/junwei890/chirpy/internal/pagination"
+	"github.com/google/uuid"
+)
+
+func (a *APIConfig) PutBookmark(writer http.ResponseWriter, req *http.Request) {
+	jwtToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	userID, err := auth.ValidateJWT(jwtToken, a.SecretKey)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+
+	chirpID := req.PathValue("chirpID")
+	if chirpID == "" {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	parsedChirpID, err := uuid.Parse(chirpID)
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	getOneChirpParams := database.GetOneChirpParams{
+		ID: parsedChirpID,
+		ViewerID: uuid.NullUUID{UUID: userID, Valid: true},
+	}
+	if _, err := a.PtrToQueries.GetOneChirp(req.Context(), getOneChirpParams); err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+
+	createBookmarkParams := database.CreateBookmarkParams{
+		UserID: userID,
+		ChirpID: parsedChirpID,
+	}
+	if err := a.PtrToQueries.CreateBookmark(req.Context(), createBookmarkParams); err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (a *APIConfig) DeleteBookmark(writer http.ResponseWriter, req *http.Request) {
+	jwtToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	userID, err := auth.ValidateJWT(jwtToken, a.SecretKey)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+
+	chirpID := req.PathValue("chirpID")
+	if chirpID == "" {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	parsedChirpID, err := uuid.Parse(chirpID)
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+
+	deleteBookmarkParams := database.DeleteBookmarkParams{
+		UserID: userID,
+		ChirpID: parsedChirpID,
+	}
+	if err := a.PtrToQueries.DeleteBookmark(req.Context(), deleteBookmarkParams); err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// GetBookmarks only ever lists the caller's own bookmarks, there is no way
+// to ask for anyone else's
+func (a *APIConfig) GetBookmarks(writer http.ResponseWriter, req *http.Request) {
+	type validResponse struct {
+		Chirps []chirpPayload `json:"chirps"`
+		NextCursor *string `json:"next_cursor"`
+	}
+
+	jwtToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	userID, err := auth.ValidateJWT(jwtToken, a.SecretKey)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	limit, err := pagination.ParseLimit(req.URL.Query().Get("limit"))
+	if err != nil {
+		InvalidParameterResponseWriter(writer, "limit")
+		return
+	}
+	sensitiveContent, err := a.sensitiveContentFor(req.Context(), uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+
+	// One extra row is fetched to tell whether another page exists
+	listBookmarkedChirpsParams := database.ListBookmarkedChirpsParams{
+		HideSensitive: sensitiveContent == sensitiveFilter,
+		UserID: userID,
+		RowLimit: limit + 1,
+	}
+	if encodedCursor := req.URL.Query().Get("cursor"); encodedCursor != "" {
+		cursor, err := pagination.DecodeCursor(encodedCursor)
+		if err != nil {
+			InvalidParameterResponseWriter(writer, "cursor")
+			return
+		}
+		listBookmarkedChirpsParams.CursorTime = sql.NullTime{Time: cursor.Time, Valid: true}
+		listBookmarkedChirpsParams.CursorID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+	sliceOfChirps, err := a.PtrToQueries.ListBookmarkedChirps(req.Context(), listBookmarkedChirpsParams)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+
+	formattedResponse := validResponse{
+		Chirps: []chirpPayload{},
+	}
+	for index, chirp := range sliceOfChirps {
+		if index == int(limit) {
+			lastChirp := sliceOfChirps[index-1]
+			nextCursor := pagination.EncodeCursor(pagination.Cursor{
+				Time: lastChirp.BookmarkedAt,
+				ID: lastChirp.ID,
+			})
+			formattedResponse.NextCursor = &nextCursor
+			writer.Header().Set("Link", pagination.NextLink(req.URL.Path, req.URL.Query(), "cursor", nextCursor))
+			break
+		}
+		formattedChirp := chirpPayload{
+			ID: chirp.ID,
+			Body: chirp.Body,
+			UserID: chirp.UserID,
+			ChirpyRed: chirp.IsChirpyRed,
+			CreatedAt: chirp.CreatedAt,
+			UpdatedAt: chirp.UpdatedAt,
+			Edited: isEdited(chirp.CreatedAt, chirp.UpdatedAt),
+			InReplyTo: nullUUIDToPointer(chirp.InReplyTo),
+			RechirpOf: nullUUIDToPointer(chirp.RechirpOf),
+			QuoteOf: nullUUIDToPointer(chirp.QuoteOf),
+			PublishAt: nullTimeToPointer(chirp.PublishAt),
+			ContentWarning: nullStringToPointer(chirp.ContentWarning),
+			Sensitive: chirp.Sensitive,
+			Visibility: chirp.Visibility,
+		}
+		formattedResponse.Chirps = append(formattedResponse.Chirps, formattedChirp)
+	}
+
+	chirpsToDecorate := []*chirpPayload{}
+	for index := range formattedResponse.Chirps {
+		chirpsToDecorate = append(chirpsToDecorate, &formattedResponse.Chirps[index])
+	}
+	if err := a.decorateChirps(req.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirpsToDecorate); err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+
+	chirpsInBytes, err := json.Marshal(formattedResponse)
+	if err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	if _, err := writer.Write(chirpsInBytes); err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+	}
+}
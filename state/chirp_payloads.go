@@ -0,0 +1,275 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	"github.com/junwei890/chirpy/internal/database"
+	"github.com/junwei890/chirpy/internal/length"
+	"github.com/google/uuid"
+)
+
+// chirpPayload is the shape every endpoint uses when it returns a chirp
+type chirpPayload struct {
+	ID uuid.UUID `json:"id"`
+	Body string `json:"body"`
+	UserID uuid.UUID `json:"user_id"`
+	ChirpyRed bool `json:"is_chirpy_red"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Edited bool `json:"edited"`
+	InReplyTo *uuid.UUID `json:"in_reply_to"`
+	RechirpOf *uuid.UUID `json:"rechirp_of"`
+	QuoteOf *uuid.UUID `json:"quote_of"`
+	ContentWarning *string `json:"content_warning"`
+	Sensitive bool `json:"sensitive"`
+	Visibility string `json:"visibility"`
+	Collapsed bool `json:"collapsed,omitempty"`
+	PublishAt *time.Time `json:"publish_at,omitempty"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	Pinned bool `json:"pinned,omitempty"`
+	Original *chirpPayload `json:"original,omitempty"`
+	Reactions map[string]int64 `json:"reactions"`
+	ViewerReactions []string `json:"viewer_reactions"`
+	Entities []entityPayload `json:"entities"`
+	Media []mediaPayload `json:"media"`
+	Poll *pollPayload `json:"poll,omitempty"`
+}
+
+// Who can read a chirp. Unlisted chirps are readable by anyone but left out
+// of listings, mentioned chirps only by the users they mention
+const (
+	visibilityPublic = "public"
+	visibilityUnlisted = "unlisted"
+	visibilityFollowers = "followers"
+	visibilityMentioned = "mentioned"
+)
+
+var chirpVisibilities = map[string]struct{}{
+	visibilityPublic: {},
+	visibilityUnlisted: {},
+	visibilityFollowers: {},
+	visibilityMentioned: {},
+}
+
+// isShareable is whether a chirp with the given visibility can be rechirped
+// or quoted, which would otherwise show it to people it was not meant for
+func isShareable(visibility string) bool {
+	return visibility == visibilityPublic || visibility == visibilityUnlisted
+}
+
+// sharesProtectedChirp is whether userID rechirping or quoting chirpID would
+// pass a protected account's chirp on past its followers, which only the
+// account itself may do
+func (a *APIConfig) sharesProtectedChirp(ctx context.Context, chirpID, userID uuid.UUID) (bool, error) {
+	author, err := a.PtrToQueries.GetChirpAuthor(ctx, chirpID)
+	if err != nil {
+		return false, err
+	}
+	return author.Protected && author.ID != userID, nil
+}
+
+// longChirpError is what validateChirp fails with, carrying the numbers a
+// client needs to show how far over the limit a chirp is
+type longChirpError struct {
+	Length int
+	Limit int
+}
+
+func (e *longChirpError) Error() string {
+	return fmt.Sprintf("chirp is %d long, the limit is %d", e.Length, e.Limit)
+}
+
+// chirpLengthLimit is how long a chirp by a user on the given tier can be
+func (a *APIConfig) chirpLengthLimit(isChirpyRed bool) int {
+	if isChirpyRed {
+		return a.MaxChirpLengthChirpyRed
+	}
+	return a.MaxChirpLength
+}
+
+// validateChirp runs the checks every chirp body goes through before it is
+// stored and returns the body normalized with profanities censored
+func validateChirp(body string, limit int) (string, error) {
+	profanities := map[string]struct{}{
+		"kerfuffle": {},
+		"sharbert": {},
+		"fornax": {},
+	}
+
+	body = length.Normalize(body)
+	if chirpLength := length.Count(body); chirpLength > limit {
+		return "", &longChirpError{Length: chirpLength, Limit: limit}
+	}
+	chirpInSlice := strings.Split(body, " ")
+	for index, word := range chirpInSlice {
+		if _, ok := profanities[word]; ok {
+			chirpInSlice[index] = "****"
+		}
+	}
+	return strings.Join(chirpInSlice, " "), nil
+}
+
+// isEdited relies on updated_at only moving when a chirp's body is edited
+func isEdited(createdAt, updatedAt time.Time) bool {
+	return updatedAt.After(createdAt)
+}
+
+func nullUUIDToPointer(nullUUID uuid.NullUUID) *uuid.UUID {
+	if !nullUUID.Valid {
+		return nil
+	}
+	return &nullUUID.UUID
+}
+
+func nullStringToPointer(nullString sql.NullString) *string {
+	if !nullString.Valid {
+		return nil
+	}
+	return &nullString.String
+}
+
+func nullTimeToPointer(nullTime sql.NullTime) *time.Time {
+	if !nullTime.Valid {
+		return nil
+	}
+	return &nullTime.Time
+}
+
+// decorateChirps fills in everything on a chirp payload that does not come
+// from the chirp's own row, one query per kind of data for the whole slice,
+// and applies the viewer's sensitive content preference. viewerID is the
+// user the payloads are being shown to, if any
+func (a *APIConfig) decorateChirps(ctx context.Context, viewerID uuid.NullUUID, chirps []*chirpPayload) error {
+	if err := a.attachOriginals(ctx, viewerID, chirps); err != nil {
+		return err
+	}
+	chirpsWithOriginals := append([]*chirpPayload{}, chirps...)
+	for _, chirp := range chirps {
+		if chirp.Original != nil {
+			chirpsWithOriginals = append(chirpsWithOriginals, chirp.Original)
+		}
+	}
+	if err := a.attachReactions(ctx, viewerID, chirpsWithOriginals); err != nil {
+		return err
+	}
+	if err := a.attachEntities(ctx, chirpsWithOriginals); err != nil {
+		return err
+	}
+	if err := a.attachMedia(ctx, chirpsWithOriginals); err != nil {
+		return err
+	}
+	if err := a.attachPolls(ctx, viewerID, chirpsWithOriginals); err != nil {
+		return err
+	}
+	// Sensitive chirps that get through a filtering viewer's listings, like a
+	// chirp fetched by ID, are collapsed for them too
+	sensitiveContent, err := a.sensitiveContentFor(ctx, viewerID)
+	if err != nil {
+		return err
+	}
+	if sensitiveContent != sensitiveShow {
+		collapseSensitive(chirps)
+	}
+	return nil
+}
+
+func (a *APIConfig) attachOriginals(ctx context.Context, viewerID uuid.NullUUID, chirps []*chirpPayload) error {
+	originalIDs := []uuid.UUID{}
+	for _, chirp := range chirps {
+		if chirp.RechirpOf != nil {
+			originalIDs = append(originalIDs, *chirp.RechirpOf)
+		}
+		if chirp.QuoteOf != nil {
+			originalIDs = append(originalIDs, *chirp.QuoteOf)
+		}
+	}
+	if len(originalIDs) == 0 {
+		return nil
+	}
+
+	getChirpsByIDsParams := database.GetChirpsByIDsParams{
+		Ids: originalIDs,
+		ViewerID: viewerID,
+	}
+	sliceOfOriginals, err := a.PtrToQueries.GetChirpsByIDs(ctx, getChirpsByIDsParams)
+	if err != nil {
+		return err
+	}
+	originals := map[uuid.UUID]*chirpPayload{}
+	for _, original := range sliceOfOriginals {
+		originals[original.ID] = &chirpPayload{
+			ID: original.ID,
+			Body: original.Body,
+			UserID: original.UserID,
+			ChirpyRed: original.IsChirpyRed,
+			CreatedAt: original.CreatedAt,
+			UpdatedAt: original.UpdatedAt,
+			Edited: isEdited(original.CreatedAt, original.UpdatedAt),
+			InReplyTo: nullUUIDToPointer(original.InReplyTo),
+			RechirpOf: nullUUIDToPointer(original.RechirpOf),
+			QuoteOf: nullUUIDToPointer(original.QuoteOf),
+			PublishAt: nullTimeToPointer(original.PublishAt),
+			ContentWarning: nullStringToPointer(original.ContentWarning),
+			Sensitive: original.Sensitive,
+			Visibility: original.Visibility,
+		}
+	}
+	for _, chirp := range chirps {
+		if chirp.RechirpOf != nil {
+			chirp.Original = originals[*chirp.RechirpOf]
+		}
+		if chirp.QuoteOf != nil {
+			chirp.Original = originals[*chirp.QuoteOf]
+		}
+	}
+	return nil
+}
+
+func (a *APIConfig) attachReactions(ctx context.Context, viewerID uuid.NullUUID, chirps []*chirpPayload) error {
+	chirpIDs := []uuid.UUID{}
+	for _, chirp := range chirps {
+		chirpIDs = append(chirpIDs, chirp.ID)
+	}
+
+	sliceOfCounts, err := a.PtrToQueries.GetReactionCounts(ctx, chirpIDs)
+	if err != nil {
+		return err
+	}
+	reactionCounts := map[uuid.UUID]map[string]int64{}
+	for _, count := range sliceOfCounts {
+		if _, ok := reactionCounts[count.ChirpID]; !ok {
+			reactionCounts[count.ChirpID] = map[string]int64{}
+		}
+		reactionCounts[count.ChirpID][count.Reaction] = count.ReactionCount
+	}
+
+	viewerReactions := map[uuid.UUID][]string{}
+	if viewerID.Valid {
+		getViewerReactionsParams := database.GetViewerReactionsParams{
+			ChirpIds: chirpIDs,
+			UserID: viewerID.UUID,
+		}
+		sliceOfReactions, err := a.PtrToQueries.GetViewerReactions(ctx, getViewerReactionsParams)
+		if err != nil {
+			return err
+		}
+		for _, reaction := range sliceOfReactions {
+			viewerReactions[reaction.ChirpID] = append(viewerReactions[reaction.ChirpID], reaction.Reaction)
+		}
+	}
+
+	for _, chirp := range chirps {
+		chirp.Reactions = map[string]int64{}
+		if counts, ok := reactionCounts[chirp.ID]; ok {
+			chirp.Reactions = counts
+		}
+		chirp.ViewerReactions = []string{}
+		if reactions, ok := viewerReactions[chirp.ID]; ok {
+			chirp.ViewerReactions = reactions
+		}
+	}
+	return nil
+}
@@ -0,0 +1,395 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+	"encoding/json"
+	"github.com/junwei890/chirpy/internal/database"
+	"github.com/junwei890/chirpy/internal/auth"
+	"github.com/junwei890/chirpy/internal/pagination"
+	"github.com/google/uuid"
+)
+
+// Drafts skip chirp validation so they can be worked on past the chirp
+// limit, these only stop them from being used as free storage
+const (
+	maxDraftLength = 10000
+	maxDrafts = 100
+)
+
+type draftPayload struct {
+	ID uuid.UUID `json:"id"`
+	Body string `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func formatDraft(draft database.Draft) draftPayload {
+	return draftPayload{
+		ID: draft.ID,
+		Body: draft.Body,
+		CreatedAt: draft.CreatedAt,
+		UpdatedAt: draft.UpdatedAt,
+	}
+}
+
+func (a *APIConfig) PostDrafts(writer http.ResponseWriter, req *http.Request) {
+	type requestBody struct {
+		Body string `json:"body"`
+	}
+
+	jwtToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	userID, err := auth.ValidateJWT(jwtToken, a.SecretKey)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+
+	dataReceivedInBytes, err := io.ReadAll(req.Body)
+	if err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+		return
+	}
+	dataReceived := &requestBody{}
+	if err := json.Unmarshal(dataReceivedInBytes, dataReceived); err != nil {
+		ErrorResponseWriter(writer, BadRequest)
+		return
+	}
+	if len(dataReceived.Body) > maxDraftLength {
+		ErrorResponseWriter(writer, DraftTooLong)
+		return
+	}
+
+	createdDraft, err := a.createDraft(req.Context(), userID, dataReceived.Body)
+	if err != nil {
+		ErrorResponseWriter(writer, responseErrorFor(err))
+		return
+	}
+
+	draftInBytes, err := json.Marshal(formatDraft(createdDraft))
+	if err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusCreated)
+	if _, err := writer.Write(draftInBytes); err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+	}
+}
+
+// createDraft locks the user's row while the insert counts their drafts, so
+// two drafts racing each other can't both squeeze under the limit
+func (a *APIConfig) createDraft(ctx context.Context, userID uuid.UUID, body string) (database.Draft, error) {
+	tx, err := a.PtrToDB.BeginTx(ctx, nil)
+	if err != nil {
+		return database.Draft{}, err
+	}
+	defer tx.Rollback()
+	queriesInTx := a.PtrToQueries.WithTx(tx)
+
+	if _, err := queriesInTx.LockUser(ctx, userID); err != nil {
+		return database.Draft{}, err
+	}
+	createDraftParams := database.CreateDraftParams{
+		UserID: userID,
+		Body: body,
+		MaxDrafts: maxDrafts,
+	}
+	createdDraft, err := queriesInTx.CreateDraft(ctx, createDraftParams)
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.Draft{}, DraftLimitReached
+	}
+	if err != nil {
+		return database.Draft{}, err
+	}
+	return createdDraft, tx.Commit()
+}
+
+func (a *APIConfig) GetDrafts(writer http.ResponseWriter, req *http.Request) {
+	type validResponse struct {
+		Drafts []draftPayload `json:"drafts"`
+		NextCursor *string `json:"next_cursor"`
+	}
+
+	jwtToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	userID, err := auth.ValidateJWT(jwtToken, a.SecretKey)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	limit, err := pagination.ParseLimit(req.URL.Query().Get("limit"))
+	if err != nil {
+		InvalidParameterResponseWriter(writer, "limit")
+		return
+	}
+
+	// One extra row is fetched to tell whether another page exists
+	listDraftsParams := database.ListDraftsParams{
+		UserID: userID,
+		RowLimit: limit + 1,
+	}
+	if encodedCursor := req.URL.Query().Get("cursor"); encodedCursor != "" {
+		cursor, err := pagination.DecodeCursor(encodedCursor)
+		if err != nil {
+			InvalidParameterResponseWriter(writer, "cursor")
+			return
+		}
+		listDraftsParams.CursorTime = sql.NullTime{Time: cursor.Time, Valid: true}
+		listDraftsParams.CursorID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+	sliceOfDrafts, err := a.PtrToQueries.ListDrafts(req.Context(), listDraftsParams)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+
+	formattedResponse := validResponse{
+		Drafts: []draftPayload{},
+	}
+	for index, draft := range sliceOfDrafts {
+		if index == int(limit) {
+			lastDraft := sliceOfDrafts[index-1]
+			nextCursor := pagination.EncodeCursor(pagination.Cursor{
+				Time: lastDraft.UpdatedAt,
+				ID: lastDraft.ID,
+			})
+			formattedResponse.NextCursor = &nextCursor
+			writer.Header().Set("Link", pagination.NextLink(req.URL.Path, req.URL.Query(), "cursor", nextCursor))
+			break
+		}
+		formattedResponse.Drafts = append(formattedResponse.Drafts, formatDraft(draft))
+	}
+
+	draftsInBytes, err := json.Marshal(formattedResponse)
+	if err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	if _, err := writer.Write(draftsInBytes); err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+	}
+}
+
+func (a *APIConfig) GetDraft(writer http.ResponseWriter, req *http.Request) {
+	jwtToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	userID, err := auth.ValidateJWT(jwtToken, a.SecretKey)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	draftID, err := uuid.Parse(req.PathValue("draftID"))
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+
+	// Someone else's draft is reported as missing rather than forbidden
+	getDraftParams := database.GetDraftParams{
+		ID: draftID,
+		UserID: userID,
+	}
+	draft, err := a.PtrToQueries.GetDraft(req.Context(), getDraftParams)
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+
+	draftInBytes, err := json.Marshal(formatDraft(draft))
+	if err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	if _, err := writer.Write(draftInBytes); err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+	}
+}
+
+func (a *APIConfig) PutDraft(writer http.ResponseWriter, req *http.Request) {
+	type requestBody struct {
+		Body string `json:"body"`
+	}
+
+	jwtToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	userID, err := auth.ValidateJWT(jwtToken, a.SecretKey)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	draftID, err := uuid.Parse(req.PathValue("draftID"))
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+
+	dataReceivedInBytes, err := io.ReadAll(req.Body)
+	if err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+		return
+	}
+	dataReceived := &requestBody{}
+	if err := json.Unmarshal(dataReceivedInBytes, dataReceived); err != nil {
+		ErrorResponseWriter(writer, BadRequest)
+		return
+	}
+	if len(dataReceived.Body) > maxDraftLength {
+		ErrorResponseWriter(writer, DraftTooLong)
+		return
+	}
+
+	updateDraftParams := database.UpdateDraftParams{
+		ID: draftID,
+		UserID: userID,
+		Body: dataReceived.Body,
+	}
+	updatedDraft, err := a.PtrToQueries.UpdateDraft(req.Context(), updateDraftParams)
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+
+	draftInBytes, err := json.Marshal(formatDraft(updatedDraft))
+	if err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	if _, err := writer.Write(draftInBytes); err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+	}
+}
+
+func (a *APIConfig) DeleteDraft(writer http.ResponseWriter, req *http.Request) {
+	jwtToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	userID, err := auth.ValidateJWT(jwtToken, a.SecretKey)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	draftID, err := uuid.Parse(req.PathValue("draftID"))
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+
+	deleteDraftParams := database.DeleteDraftParams{
+		ID: draftID,
+		UserID: userID,
+	}
+	deletedRows, err := a.PtrToQueries.DeleteDraft(req.Context(), deleteDraftParams)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	if deletedRows == 0 {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// PostDraftPublish turns a draft into a chirp. The request body is optional
+// and takes the same fields as PostChirps apart from body, which comes from
+// the draft. The draft is only removed if the chirp is created.
+func (a *APIConfig) PostDraftPublish(writer http.ResponseWriter, req *http.Request) {
+	jwtToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	userID, err := auth.ValidateJWT(jwtToken, a.SecretKey)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	draftID, err := uuid.Parse(req.PathValue("draftID"))
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+
+	dataReceivedInBytes, err := io.ReadAll(req.Body)
+	if err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+		return
+	}
+	dataReceived := &chirpInput{}
+	if len(dataReceivedInBytes) > 0 {
+		if err := json.Unmarshal(dataReceivedInBytes, dataReceived); err != nil {
+			ErrorResponseWriter(writer, BadRequest)
+			return
+		}
+	}
+
+	getDraftParams := database.GetDraftParams{
+		ID: draftID,
+		UserID: userID,
+	}
+	draft, err := a.PtrToQueries.GetDraft(req.Context(), getDraftParams)
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	dataReceived.Body = draft.Body
+
+	// Publishing from two devices at once must not make two chirps, only
+	// the transaction that actually deletes the draft gets to commit
+	createdChirp, err := a.createChirp(req.Context(), userID, *dataReceived, func(queries *database.Queries) error {
+		deleteDraftParams := database.DeleteDraftParams{
+			ID: draftID,
+			UserID: userID,
+		}
+		deletedRows, err := queries.DeleteDraft(req.Context(), deleteDraftParams)
+		if err != nil {
+			return err
+		}
+		if deletedRows == 0 {
+			return NotFound
+		}
+		return nil
+	})
+	if err != nil {
+		chirpErrorResponseWriter(writer, err)
+		return
+	}
+
+	chirpInBytes, err := json.Marshal(createdChirp)
+	if err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusCreated)
+	if _, err := writer.Write(chirpInBytes); err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+	}
+}
@@ -0,0 +1,235 @@
+package state
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"encoding/json"
+	"database/sql"
+	"github.com/junwei890/chirpy/internal/database"
+	"github.com/junwei890/chirpy/internal/entities"
+	"github.com/junwei890/chirpy/internal/pagination"
+	"github.com/google/uuid"
+)
+
+type entityPayload struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+	Start int32 `json:"start"`
+	End int32 `json:"end"`
+	UserID *uuid.UUID `json:"user_id,omitempty"`
+}
+
+// storeChirpEntities replaces whatever entities a chirp had with the ones in
+// body, callers run it in the same transaction that writes the body
+func storeChirpEntities(ctx context.Context, queries *database.Queries, chirpID uuid.UUID, body string) error {
+	if err := queries.DeleteChirpHashtags(ctx, chirpID); err != nil {
+		return err
+	}
+	if err := queries.DeleteChirpMentions(ctx, chirpID); err != nil {
+		return err
+	}
+
+	createChirpHashtagsParams := database.CreateChirpHashtagsParams{
+		ChirpID: chirpID,
+		Tags: []string{},
+		StartIndexes: []int32{},
+		EndIndexes: []int32{},
+	}
+	createChirpMentionsParams := database.CreateChirpMentionsParams{
+		ChirpID: chirpID,
+		Handles: []string{},
+		StartIndexes: []int32{},
+		EndIndexes: []int32{},
+	}
+	for _, entity := range entities.Extract(body) {
+		switch entity.Type {
+		case entities.Hashtag:
+			createChirpHashtagsParams.Tags = append(createChirpHashtagsParams.Tags, entity.Text)
+			createChirpHashtagsParams.StartIndexes = append(createChirpHashtagsParams.StartIndexes, int32(entity.Start))
+			createChirpHashtagsParams.EndIndexes = append(createChirpHashtagsParams.EndIndexes, int32(entity.End))
+		case entities.Mention:
+			createChirpMentionsParams.Handles = append(createChirpMentionsParams.Handles, entity.Text)
+			createChirpMentionsParams.StartIndexes = append(createChirpMentionsParams.StartIndexes, int32(entity.Start))
+			createChirpMentionsParams.EndIndexes = append(createChirpMentionsParams.EndIndexes, int32(entity.End))
+		}
+	}
+
+	if len(createChirpHashtagsParams.Tags) > 0 {
+		if err := queries.CreateChirpHashtags(ctx, createChirpHashtagsParams); err != nil {
+			return err
+		}
+	}
+	if len(createChirpMentionsParams.Handles) > 0 {
+		if err := queries.CreateChirpMentions(ctx, createChirpMentionsParams); err != nil {
+			return err
+		}
+		// Users who blocked the author can't be mentioned by them
+		blockedMentions, err := queries.CountBlockedMentions(ctx, chirpID)
+		if err != nil {
+			return err
+		}
+		if blockedMentions > 0 {
+			return Blocked
+		}
+	}
+	return nil
+}
+
+func (a *APIConfig) attachEntities(ctx context.Context, chirps []*chirpPayload) error {
+	chirpIDs := []uuid.UUID{}
+	for _, chirp := range chirps {
+		chirpIDs = append(chirpIDs, chirp.ID)
+	}
+
+	sliceOfHashtags, err := a.PtrToQueries.GetChirpHashtags(ctx, chirpIDs)
+	if err != nil {
+		return err
+	}
+	sliceOfMentions, err := a.PtrToQueries.GetChirpMentions(ctx, chirpIDs)
+	if err != nil {
+		return err
+	}
+
+	chirpEntities := map[uuid.UUID][]entityPayload{}
+	for _, hashtag := range sliceOfHashtags {
+		chirpEntities[hashtag.ChirpID] = append(chirpEntities[hashtag.ChirpID], entityPayload{
+			Type: entities.Hashtag,
+			Text: hashtag.Tag,
+			Start: hashtag.StartIndex,
+			End: hashtag.EndIndex,
+		})
+	}
+	for _, mention := range sliceOfMentions {
+		mentionedUserID := mention.UserID
+		chirpEntities[mention.ChirpID] = append(chirpEntities[mention.ChirpID], entityPayload{
+			Type: entities.Mention,
+			Start: mention.StartIndex,
+			End: mention.EndIndex,
+			UserID: &mentionedUserID,
+		})
+	}
+
+	for _, chirp := range chirps {
+		chirp.Entities = []entityPayload{}
+		if foundEntities, ok := chirpEntities[chirp.ID]; ok {
+			chirp.Entities = foundEntities
+		}
+		// Mentions only store offsets, the handle is read back out of the body
+		bodyInRunes := []rune(chirp.Body)
+		for index, entity := range chirp.Entities {
+			if entity.Type == entities.Mention && int(entity.End) <= len(bodyInRunes) {
+				chirp.Entities[index].Text = string(bodyInRunes[entity.Start+1 : entity.End])
+			}
+		}
+		sort.Slice(chirp.Entities, func(i, j int) bool {
+			return chirp.Entities[i].Start < chirp.Entities[j].Start
+		})
+	}
+	return nil
+}
+
+func (a *APIConfig) GetHashtagChirps(writer http.ResponseWriter, req *http.Request) {
+	type validResponse struct {
+		Chirps []chirpPayload `json:"chirps"`
+		NextCursor *string `json:"next_cursor"`
+	}
+
+	viewerID, err := a.optionalUserID(req)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+
+	tag := strings.ToLower(strings.TrimPrefix(req.PathValue("tag"), "#"))
+	if tag == "" {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	limit, err := pagination.ParseLimit(req.URL.Query().Get("limit"))
+	if err != nil {
+		InvalidParameterResponseWriter(writer, "limit")
+		return
+	}
+	sensitiveContent, err := a.sensitiveContentFor(req.Context(), viewerID)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+
+	// One extra row is fetched to tell whether another page exists
+	listHashtagChirpsParams := database.ListHashtagChirpsParams{
+		HideSensitive: sensitiveContent == sensitiveFilter,
+		Tag: tag,
+		ViewerID: viewerID,
+		RowLimit: limit + 1,
+	}
+	if encodedCursor := req.URL.Query().Get("cursor"); encodedCursor != "" {
+		cursor, err := pagination.DecodeCursor(encodedCursor)
+		if err != nil {
+			InvalidParameterResponseWriter(writer, "cursor")
+			return
+		}
+		listHashtagChirpsParams.CursorTime = sql.NullTime{Time: cursor.Time, Valid: true}
+		listHashtagChirpsParams.CursorID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+	sliceOfChirps, err := a.PtrToQueries.ListHashtagChirps(req.Context(), listHashtagChirpsParams)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+
+	formattedResponse := validResponse{
+		Chirps: []chirpPayload{},
+	}
+	for index, chirp := range sliceOfChirps {
+		if index == int(limit) {
+			lastChirp := sliceOfChirps[index-1]
+			nextCursor := pagination.EncodeCursor(pagination.Cursor{
+				Time: lastChirp.CreatedAt,
+				ID: lastChirp.ID,
+			})
+			formattedResponse.NextCursor = &nextCursor
+			writer.Header().Set("Link", pagination.NextLink(req.URL.Path, req.URL.Query(), "cursor", nextCursor))
+			break
+		}
+		formattedChirp := chirpPayload{
+			ID: chirp.ID,
+			Body: chirp.Body,
+			UserID: chirp.UserID,
+			ChirpyRed: chirp.IsChirpyRed,
+			CreatedAt: chirp.CreatedAt,
+			UpdatedAt: chirp.UpdatedAt,
+			Edited: isEdited(chirp.CreatedAt, chirp.UpdatedAt),
+			InReplyTo: nullUUIDToPointer(chirp.InReplyTo),
+			RechirpOf: nullUUIDToPointer(chirp.RechirpOf),
+			QuoteOf: nullUUIDToPointer(chirp.QuoteOf),
+			PublishAt: nullTimeToPointer(chirp.PublishAt),
+			ContentWarning: nullStringToPointer(chirp.ContentWarning),
+			Sensitive: chirp.Sensitive,
+			Visibility: chirp.Visibility,
+		}
+		formattedResponse.Chirps = append(formattedResponse.Chirps, formattedChirp)
+	}
+
+	chirpsToDecorate := []*chirpPayload{}
+	for index := range formattedResponse.Chirps {
+		chirpsToDecorate = append(chirpsToDecorate, &formattedResponse.Chirps[index])
+	}
+	if err := a.decorateChirps(req.Context(), viewerID, chirpsToDecorate); err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+
+	chirpsInBytes, err := json.Marshal(formattedResponse)
+	if err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	if _, err := writer.Write(chirpsInBytes); err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+	}
+}
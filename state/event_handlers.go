@@ -0,0 +1,95 @@
+package state
+
+import (
+	"net/http"
+	"time"
+	"encoding/json"
+	"database/sql"
+	"github.com/junwei890/chirpy/internal/database"
+	"github.com/junwei890/chirpy/internal/auth"
+	"github.com/junwei890/chirpy/internal/pagination"
+	"github.com/google/uuid"
+)
+
+type eventPayload struct {
+	ID uuid.UUID `json:"id"`
+	Type string `json:"type"`
+	Payload json.RawMessage `json:"payload"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (a *APIConfig) GetEvents(writer http.ResponseWriter, req *http.Request) {
+	type validResponse struct {
+		Events []eventPayload `json:"events"`
+		NextCursor *string `json:"next_cursor"`
+	}
+
+	jwtToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	userID, err := auth.ValidateJWT(jwtToken, a.SecretKey)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	limit, err := pagination.ParseLimit(req.URL.Query().Get("limit"))
+	if err != nil {
+		InvalidParameterResponseWriter(writer, "limit")
+		return
+	}
+
+	// One extra row is fetched to tell whether another page exists
+	listEventsParams := database.ListEventsParams{
+		UserID: userID,
+		RowLimit: limit + 1,
+	}
+	if encodedCursor := req.URL.Query().Get("cursor"); encodedCursor != "" {
+		cursor, err := pagination.DecodeCursor(encodedCursor)
+		if err != nil {
+			InvalidParameterResponseWriter(writer, "cursor")
+			return
+		}
+		listEventsParams.CursorTime = sql.NullTime{Time: cursor.Time, Valid: true}
+		listEventsParams.CursorID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+	sliceOfEvents, err := a.PtrToQueries.ListEvents(req.Context(), listEventsParams)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+
+	formattedResponse := validResponse{
+		Events: []eventPayload{},
+	}
+	for index, event := range sliceOfEvents {
+		if index == int(limit) {
+			lastEvent := sliceOfEvents[index-1]
+			nextCursor := pagination.EncodeCursor(pagination.Cursor{
+				Time: lastEvent.CreatedAt,
+				ID: lastEvent.ID,
+			})
+			formattedResponse.NextCursor = &nextCursor
+			writer.Header().Set("Link", pagination.NextLink(req.URL.Path, req.URL.Query(), "cursor", nextCursor))
+			break
+		}
+		formattedResponse.Events = append(formattedResponse.Events, eventPayload{
+			ID: event.ID,
+			Type: event.Type,
+			Payload: event.Payload,
+			CreatedAt: event.CreatedAt,
+		})
+	}
+
+	eventsInBytes, err := json.Marshal(formattedResponse)
+	if err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	if _, err := writer.Write(eventsInBytes); err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+	}
+}
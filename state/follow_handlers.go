@@ -0,0 +1,275 @@
+package state
+
+import (
+	"net/http"
+	"time"
+	"encoding/json"
+	"database/sql"
+	"github.com/junwei890/chirpy/internal/database"
+	"github.com/junwei890/chirpy/internal/auth"
+	"github.com/junwei890/chirpy/internal/pagination"
+	"github.com/google/uuid"
+)
+
+type followPayload struct {
+	ID uuid.UUID `json:"id"`
+	Handle string `json:"handle"`
+	ChirpyRed bool `json:"is_chirpy_red"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+func (a *APIConfig) PostFollow(writer http.ResponseWriter, req *http.Request) {
+	jwtToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	userID, err := auth.ValidateJWT(jwtToken, a.SecretKey)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+
+	followeeID, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	if followeeID == userID {
+		ErrorResponseWriter(writer, BadRequest)
+		return
+	}
+	followee, err := a.PtrToQueries.GetUserByID(req.Context(), followeeID)
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	isBlockedEitherWayParams := database.IsBlockedEitherWayParams{
+		UserID: userID,
+		OtherID: followeeID,
+	}
+	isBlocked, err := a.PtrToQueries.IsBlockedEitherWay(req.Context(), isBlockedEitherWayParams)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	if isBlocked {
+		ErrorResponseWriter(writer, Blocked)
+		return
+	}
+	// Following a protected account waits on its owner approving the request
+	if followee.Protected {
+		createFollowRequestParams := database.CreateFollowRequestParams{
+			RequesterID: userID,
+			TargetID: followeeID,
+		}
+		createdRows, err := a.PtrToQueries.CreateFollowRequest(req.Context(), createFollowRequestParams)
+		if err != nil {
+			ErrorResponseWriter(writer, DatabaseError)
+			return
+		}
+		if createdRows == 0 {
+			ErrorResponseWriter(writer, AlreadyExists)
+			return
+		}
+		writer.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	tx, err := a.PtrToDB.BeginTx(req.Context(), nil)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	defer tx.Rollback()
+	queriesInTx := a.PtrToQueries.WithTx(tx)
+	createFollowParams := database.CreateFollowParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	}
+	createdRows, err := queriesInTx.CreateFollow(req.Context(), createFollowParams)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	if createdRows == 0 {
+		ErrorResponseWriter(writer, AlreadyExists)
+		return
+	}
+	backfillTimelineParams := database.BackfillTimelineParams{
+		UserID: userID,
+		AuthorID: followeeID,
+		RowLimit: timelineBackfillSize,
+	}
+	if err := queriesInTx.BackfillTimeline(req.Context(), backfillTimelineParams); err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (a *APIConfig) DeleteFollow(writer http.ResponseWriter, req *http.Request) {
+	jwtToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	userID, err := auth.ValidateJWT(jwtToken, a.SecretKey)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+
+	followeeID, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+
+	tx, err := a.PtrToDB.BeginTx(req.Context(), nil)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	defer tx.Rollback()
+	queriesInTx := a.PtrToQueries.WithTx(tx)
+	deleteFollowParams := database.DeleteFollowParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	}
+	deletedRows, err := queriesInTx.DeleteFollow(req.Context(), deleteFollowParams)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	// Without a follow to end, a pending request is withdrawn instead
+	if deletedRows == 0 {
+		deleteFollowRequestParams := database.DeleteFollowRequestParams{
+			RequesterID: userID,
+			TargetID: followeeID,
+		}
+		deletedRows, err = queriesInTx.DeleteFollowRequest(req.Context(), deleteFollowRequestParams)
+		if err != nil {
+			ErrorResponseWriter(writer, DatabaseError)
+			return
+		}
+		if deletedRows == 0 {
+			ErrorResponseWriter(writer, NotFound)
+			return
+		}
+	}
+	deleteTimelineAuthorParams := database.DeleteTimelineAuthorParams{
+		UserID: userID,
+		AuthorID: followeeID,
+	}
+	if err := queriesInTx.DeleteTimelineAuthor(req.Context(), deleteTimelineAuthorParams); err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (a *APIConfig) GetFollowers(writer http.ResponseWriter, req *http.Request) {
+	a.listFollows(writer, req, func(listFollowsParams database.ListFollowersParams) ([]database.ListFollowersRow, error) {
+		return a.PtrToQueries.ListFollowers(req.Context(), listFollowsParams)
+	})
+}
+
+func (a *APIConfig) GetFollowing(writer http.ResponseWriter, req *http.Request) {
+	a.listFollows(writer, req, func(listFollowsParams database.ListFollowersParams) ([]database.ListFollowersRow, error) {
+		sliceOfFollowing, err := a.PtrToQueries.ListFollowing(req.Context(), database.ListFollowingParams(listFollowsParams))
+		if err != nil {
+			return nil, err
+		}
+		sliceOfFollows := []database.ListFollowersRow{}
+		for _, following := range sliceOfFollowing {
+			sliceOfFollows = append(sliceOfFollows, database.ListFollowersRow(following))
+		}
+		return sliceOfFollows, nil
+	})
+}
+
+// listFollows writes one page of either side of a user's follows, the two
+// lists only differing in the query that fetches them
+func (a *APIConfig) listFollows(writer http.ResponseWriter, req *http.Request, listFollows func(database.ListFollowersParams) ([]database.ListFollowersRow, error)) {
+	type validResponse struct {
+		Users []followPayload `json:"users"`
+		NextCursor *string `json:"next_cursor"`
+	}
+
+	userID, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	if _, err := a.PtrToQueries.GetUserByID(req.Context(), userID); err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	limit, err := pagination.ParseLimit(req.URL.Query().Get("limit"))
+	if err != nil {
+		InvalidParameterResponseWriter(writer, "limit")
+		return
+	}
+
+	// One extra row is fetched to tell whether another page exists
+	listFollowsParams := database.ListFollowersParams{
+		UserID: userID,
+		RowLimit: limit + 1,
+	}
+	if encodedCursor := req.URL.Query().Get("cursor"); encodedCursor != "" {
+		cursor, err := pagination.DecodeCursor(encodedCursor)
+		if err != nil {
+			InvalidParameterResponseWriter(writer, "cursor")
+			return
+		}
+		listFollowsParams.CursorTime = sql.NullTime{Time: cursor.Time, Valid: true}
+		listFollowsParams.CursorID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+	sliceOfFollows, err := listFollows(listFollowsParams)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+
+	formattedResponse := validResponse{
+		Users: []followPayload{},
+	}
+	for index, follow := range sliceOfFollows {
+		if index == int(limit) {
+			lastFollow := sliceOfFollows[index-1]
+			nextCursor := pagination.EncodeCursor(pagination.Cursor{
+				Time: lastFollow.FollowedAt,
+				ID: lastFollow.ID,
+			})
+			formattedResponse.NextCursor = &nextCursor
+			writer.Header().Set("Link", pagination.NextLink(req.URL.Path, req.URL.Query(), "cursor", nextCursor))
+			break
+		}
+		formattedResponse.Users = append(formattedResponse.Users, followPayload{
+			ID: follow.ID,
+			Handle: follow.Handle,
+			ChirpyRed: follow.IsChirpyRed,
+			FollowedAt: follow.FollowedAt,
+		})
+	}
+
+	followsInBytes, err := json.Marshal(formattedResponse)
+	if err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	if _, err := writer.Write(followsInBytes); err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+	}
+}
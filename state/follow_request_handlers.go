@@ -0,0 +1,233 @@
+package state
+
+import (
+	"context"
+	"net/http"
+	"time"
+	"encoding/json"
+	"database/sql"
+	"github.com/junwei890/chirpy/internal/database"
+	"github.com/junwei890/chirpy/internal/auth"
+	"github.com/junwei890/chirpy/internal/pagination"
+	"github.com/google/uuid"
+)
+
+type followRequestPayload struct {
+	ID uuid.UUID `json:"id"`
+	Handle string `json:"handle"`
+	ChirpyRed bool `json:"is_chirpy_red"`
+	RequestedAt time.Time `json:"requested_at"`
+}
+
+// setProtected turns protection on or off for userID. Followers from before
+// an account was protected stay approved, and turning it off approves every
+// request still pending
+func setProtected(ctx context.Context, queries *database.Queries, userID uuid.UUID, protected bool) error {
+	setProtectedParams := database.SetProtectedParams{
+		ID: userID,
+		Protected: protected,
+	}
+	updatedRows, err := queries.SetProtected(ctx, setProtectedParams)
+	if err != nil {
+		return err
+	}
+	if updatedRows == 0 {
+		return NotFound
+	}
+	if protected {
+		return nil
+	}
+
+	sliceOfFollowers, err := queries.ApproveAllFollowRequests(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, followerID := range sliceOfFollowers {
+		backfillTimelineParams := database.BackfillTimelineParams{
+			UserID: followerID,
+			AuthorID: userID,
+			RowLimit: timelineBackfillSize,
+		}
+		if err := queries.BackfillTimeline(ctx, backfillTimelineParams); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PostFollowRequestApprove turns a pending request to follow the user into
+// a follow
+func (a *APIConfig) PostFollowRequestApprove(writer http.ResponseWriter, req *http.Request) {
+	jwtToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	userID, err := auth.ValidateJWT(jwtToken, a.SecretKey)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+
+	requesterID, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+
+	tx, err := a.PtrToDB.BeginTx(req.Context(), nil)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	defer tx.Rollback()
+	queriesInTx := a.PtrToQueries.WithTx(tx)
+	deleteFollowRequestParams := database.DeleteFollowRequestParams{
+		RequesterID: requesterID,
+		TargetID: userID,
+	}
+	deletedRows, err := queriesInTx.DeleteFollowRequest(req.Context(), deleteFollowRequestParams)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	if deletedRows == 0 {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	createFollowParams := database.CreateFollowParams{
+		FollowerID: requesterID,
+		FolloweeID: userID,
+	}
+	if _, err := queriesInTx.CreateFollow(req.Context(), createFollowParams); err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	backfillTimelineParams := database.BackfillTimelineParams{
+		UserID: requesterID,
+		AuthorID: userID,
+		RowLimit: timelineBackfillSize,
+	}
+	if err := queriesInTx.BackfillTimeline(req.Context(), backfillTimelineParams); err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// PostFollowRequestReject drops a pending request to follow the user, the
+// requester is free to ask again
+func (a *APIConfig) PostFollowRequestReject(writer http.ResponseWriter, req *http.Request) {
+	jwtToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	userID, err := auth.ValidateJWT(jwtToken, a.SecretKey)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+
+	requesterID, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+
+	deleteFollowRequestParams := database.DeleteFollowRequestParams{
+		RequesterID: requesterID,
+		TargetID: userID,
+	}
+	deletedRows, err := a.PtrToQueries.DeleteFollowRequest(req.Context(), deleteFollowRequestParams)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	if deletedRows == 0 {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// GetFollowRequests lists the requests waiting on the user, newest first
+func (a *APIConfig) GetFollowRequests(writer http.ResponseWriter, req *http.Request) {
+	type validResponse struct {
+		Requests []followRequestPayload `json:"requests"`
+		NextCursor *string `json:"next_cursor"`
+	}
+
+	jwtToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	userID, err := auth.ValidateJWT(jwtToken, a.SecretKey)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	limit, err := pagination.ParseLimit(req.URL.Query().Get("limit"))
+	if err != nil {
+		InvalidParameterResponseWriter(writer, "limit")
+		return
+	}
+
+	// One extra row is fetched to tell whether another page exists
+	listFollowRequestsParams := database.ListFollowRequestsParams{
+		UserID: userID,
+		RowLimit: limit + 1,
+	}
+	if encodedCursor := req.URL.Query().Get("cursor"); encodedCursor != "" {
+		cursor, err := pagination.DecodeCursor(encodedCursor)
+		if err != nil {
+			InvalidParameterResponseWriter(writer, "cursor")
+			return
+		}
+		listFollowRequestsParams.CursorTime = sql.NullTime{Time: cursor.Time, Valid: true}
+		listFollowRequestsParams.CursorID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+	sliceOfRequests, err := a.PtrToQueries.ListFollowRequests(req.Context(), listFollowRequestsParams)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+
+	formattedResponse := validResponse{
+		Requests: []followRequestPayload{},
+	}
+	for index, request := range sliceOfRequests {
+		if index == int(limit) {
+			lastRequest := sliceOfRequests[index-1]
+			nextCursor := pagination.EncodeCursor(pagination.Cursor{
+				Time: lastRequest.RequestedAt,
+				ID: lastRequest.ID,
+			})
+			formattedResponse.NextCursor = &nextCursor
+			writer.Header().Set("Link", pagination.NextLink(req.URL.Path, req.URL.Query(), "cursor", nextCursor))
+			break
+		}
+		formattedResponse.Requests = append(formattedResponse.Requests, followRequestPayload{
+			ID: request.ID,
+			Handle: request.Handle,
+			ChirpyRed: request.IsChirpyRed,
+			RequestedAt: request.RequestedAt,
+		})
+	}
+
+	requestsInBytes, err := json.Marshal(formattedResponse)
+	if err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	if _, err := writer.Write(requestsInBytes); err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+	}
+}
@@ -4,6 +4,10 @@ import (
 	"net/http"
 	"encoding/json"
 	"log"
+	"fmt"
+	"errors"
+	"github.com/junwei890/chirpy/internal/auth"
+	"github.com/google/uuid"
 )
 
 type Error int
@@ -18,9 +22,36 @@ const (
 	UnauthorizedBadRT
 	UnauthorizedBadAPIKey
 	Forbidden
-	LongChirp
+	EditWindowClosed
+	AlreadyExists
+	MediaTooLarge
+	UnsupportedMedia
+	TooManyMedia
+	ImageTooLarge
+	ProcessingBusy
+	DraftTooLong
+	DraftLimitReached
+	PinLimitReached
+	PollClosed
+	Blocked
 )
 
+// Error also satisfies error so helpers shared between handlers can return
+// the response a failure should get
+func (e Error) Error() string {
+	return fmt.Sprintf("response error %d", int(e))
+}
+
+// responseErrorFor picks the response for an error from such a helper,
+// anything that is not an Error is treated as a database failure
+func responseErrorFor(err error) Error {
+	var responseError Error
+	if errors.As(err, &responseError) {
+		return responseError
+	}
+	return DatabaseError
+}
+
 func ErrorResponseWriter(writer http.ResponseWriter, error Error) {
 	type errorResponse struct {
 		Error string `json:"error"`	
@@ -56,9 +87,42 @@ func ErrorResponseWriter(writer http.ResponseWriter, error Error) {
 	case Forbidden:
 		errorMessage = "You're not allowed to use this endpoint"
 		statusCode = http.StatusForbidden
-	case LongChirp:
-		errorMessage = "Chirp is too long"
+	case EditWindowClosed:
+		errorMessage = "Chirp can no longer be edited"
+		statusCode = http.StatusForbidden
+	case AlreadyExists:
+		errorMessage = "Already exists"
+		statusCode = http.StatusConflict
+	case MediaTooLarge:
+		errorMessage = "File is too large"
+		statusCode = http.StatusRequestEntityTooLarge
+	case UnsupportedMedia:
+		errorMessage = "File type is not supported"
+		statusCode = http.StatusUnsupportedMediaType
+	case TooManyMedia:
+		errorMessage = "Too many media attachments"
 		statusCode = http.StatusBadRequest
+	case ImageTooLarge:
+		errorMessage = "Image dimensions are too large"
+		statusCode = http.StatusRequestEntityTooLarge
+	case ProcessingBusy:
+		errorMessage = "Too many uploads being processed, try again later"
+		statusCode = http.StatusServiceUnavailable
+	case DraftTooLong:
+		errorMessage = "Draft is too long"
+		statusCode = http.StatusBadRequest
+	case DraftLimitReached:
+		errorMessage = "Draft limit reached"
+		statusCode = http.StatusBadRequest
+	case PinLimitReached:
+		errorMessage = "Pin limit reached"
+		statusCode = http.StatusConflict
+	case PollClosed:
+		errorMessage = "Poll is closed"
+		statusCode = http.StatusForbidden
+	case Blocked:
+		errorMessage = "You can't interact with this user"
+		statusCode = http.StatusForbidden
 	}
 
 	errorResponseStruct := &errorResponse{
@@ -72,3 +136,71 @@ func ErrorResponseWriter(writer http.ResponseWriter, error Error) {
 	writer.WriteHeader(statusCode)
 	writer.Write(errorResponseInBytes)
 }
+
+func InvalidParameterResponseWriter(writer http.ResponseWriter, parameter string) {
+	type errorResponse struct {
+		Error string `json:"error"`
+		Parameter string `json:"parameter"`
+	}
+
+	errorResponseStruct := &errorResponse{
+		Error: fmt.Sprintf("Invalid query parameter: %s", parameter),
+		Parameter: parameter,
+	}
+	errorResponseInBytes, err := json.Marshal(errorResponseStruct)
+	if err != nil {
+		log.Println(err)
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusBadRequest)
+	writer.Write(errorResponseInBytes)
+}
+
+func LongChirpResponseWriter(writer http.ResponseWriter, chirpLength, limit int) {
+	type errorResponse struct {
+		Error string `json:"error"`
+		Length int `json:"length"`
+		Limit int `json:"limit"`
+	}
+
+	errorResponseStruct := &errorResponse{
+		Error: fmt.Sprintf("Chirp is too long, %d of %d", chirpLength, limit),
+		Length: chirpLength,
+		Limit: limit,
+	}
+	errorResponseInBytes, err := json.Marshal(errorResponseStruct)
+	if err != nil {
+		log.Println(err)
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusBadRequest)
+	writer.Write(errorResponseInBytes)
+}
+
+// chirpErrorResponseWriter responds to an error from createChirp, which is
+// either a too long chirp or anything responseErrorFor handles
+func chirpErrorResponseWriter(writer http.ResponseWriter, err error) {
+	var longChirp *longChirpError
+	if errors.As(err, &longChirp) {
+		LongChirpResponseWriter(writer, longChirp.Length, longChirp.Limit)
+		return
+	}
+	ErrorResponseWriter(writer, responseErrorFor(err))
+}
+
+// optionalUserID is for endpoints that anyone can call but that show more to
+// a logged in user, no Authorization header means an anonymous viewer
+func (a *APIConfig) optionalUserID(req *http.Request) (uuid.NullUUID, error) {
+	if req.Header.Get("Authorization") == "" {
+		return uuid.NullUUID{}, nil
+	}
+	jwtToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		return uuid.NullUUID{}, err
+	}
+	userID, err := auth.ValidateJWT(jwtToken, a.SecretKey)
+	if err != nil {
+		return uuid.NullUUID{}, err
+	}
+	return uuid.NullUUID{UUID: userID, Valid: true}, nil
+}
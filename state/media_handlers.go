@@ -0,0 +1,361 @@
+package state
+
+import (
+	"context"
+	"bytes"
+	"database/sql"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+	"encoding/json"
+	"github.com/junwei890/chirpy/internal/database"
+	"github.com/junwei890/chirpy/internal/auth"
+	"github.com/junwei890/chirpy/internal/media"
+	"github.com/google/uuid"
+)
+
+const mediaReady = "ready"
+
+type variantPayload struct {
+	URL string `json:"url"`
+	Width int32 `json:"width"`
+	Height int32 `json:"height"`
+}
+
+type mediaPayload struct {
+	ID uuid.UUID `json:"id"`
+	Status string `json:"status"`
+	URL *string `json:"url"`
+	ContentType string `json:"content_type"`
+	SizeBytes int64 `json:"size_bytes"`
+	Width *int32 `json:"width"`
+	Height *int32 `json:"height"`
+	Blurhash *string `json:"blurhash"`
+	Variants map[string]variantPayload `json:"variants"`
+}
+
+// formatMedia only hands out URLs once processing is done, before that
+// nothing has been written to storage
+func (a *APIConfig) formatMedia(storedMedia database.Medium, variants []database.MediaVariant) mediaPayload {
+	formattedMedia := mediaPayload{
+		ID: storedMedia.ID,
+		Status: storedMedia.Status,
+		ContentType: storedMedia.ContentType,
+		SizeBytes: storedMedia.SizeBytes,
+		Variants: map[string]variantPayload{},
+	}
+	if storedMedia.Status != mediaReady {
+		return formattedMedia
+	}
+
+	url := a.Storage.URL(storedMedia.StorageKey)
+	formattedMedia.URL = &url
+	if storedMedia.Width.Valid && storedMedia.Height.Valid {
+		formattedMedia.Width = &storedMedia.Width.Int32
+		formattedMedia.Height = &storedMedia.Height.Int32
+	}
+	if storedMedia.Blurhash.Valid {
+		formattedMedia.Blurhash = &storedMedia.Blurhash.String
+	}
+	for _, variant := range variants {
+		formattedMedia.Variants[variant.Name] = variantPayload{
+			URL: a.Storage.URL(variant.StorageKey),
+			Width: variant.Width,
+			Height: variant.Height,
+		}
+	}
+	return formattedMedia
+}
+
+func (a *APIConfig) PostMedia(writer http.ResponseWriter, req *http.Request) {
+	type validResponse struct {
+		mediaPayload
+		CreatedAt time.Time `json:"created_at"`
+	}
+
+	jwtToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	userID, err := auth.ValidateJWT(jwtToken, a.SecretKey)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+
+	// Room is left for the multipart framing around the file itself
+	req.Body = http.MaxBytesReader(writer, req.Body, media.MaxUploadBytes + 1<<20)
+	uploadedFile, _, err := req.FormFile("file")
+	if err != nil {
+		ErrorResponseWriter(writer, BadRequest)
+		return
+	}
+	defer uploadedFile.Close()
+	fileInBytes, err := io.ReadAll(io.LimitReader(uploadedFile, media.MaxUploadBytes + 1))
+	if err != nil {
+		ErrorResponseWriter(writer, BadRequest)
+		return
+	}
+	if len(fileInBytes) > media.MaxUploadBytes {
+		ErrorResponseWriter(writer, MediaTooLarge)
+		return
+	}
+
+	// The client's Content-Type is not trusted, the bytes decide
+	contentType := http.DetectContentType(fileInBytes)
+	extension, ok := media.ExtensionFor(contentType)
+	if !ok {
+		ErrorResponseWriter(writer, UnsupportedMedia)
+		return
+	}
+	if err := media.CheckDimensions(fileInBytes); err != nil {
+		if errors.Is(err, media.ErrDimensionsTooLarge) {
+			ErrorResponseWriter(writer, ImageTooLarge)
+			return
+		}
+		ErrorResponseWriter(writer, UnsupportedMedia)
+		return
+	}
+
+	// The upload itself is never stored, only what processing re-encodes
+	// from it, so the row is created first and filled in by the worker
+	mediaID := uuid.New()
+	createMediaParams := database.CreateMediaParams{
+		ID: mediaID,
+		UserID: userID,
+		StorageKey: mediaID.String() + extension,
+		ContentType: contentType,
+		SizeBytes: int64(len(fileInBytes)),
+	}
+	createdMedia, err := a.PtrToQueries.CreateMedia(req.Context(), createMediaParams)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	submitted := a.MediaWorkers.Submit(func(ctx context.Context) {
+		if err := a.processMedia(ctx, mediaID, fileInBytes); err != nil {
+			log.Printf("processing media %s: %v", mediaID, err)
+		}
+	})
+	if !submitted {
+		if err := a.PtrToQueries.DeleteMedia(req.Context(), mediaID); err != nil {
+			log.Println(err)
+		}
+		ErrorResponseWriter(writer, ProcessingBusy)
+		return
+	}
+
+	formattedMedia := validResponse{
+		mediaPayload: a.formatMedia(createdMedia, nil),
+		CreatedAt: createdMedia.CreatedAt,
+	}
+	mediaInBytes, err := json.Marshal(formattedMedia)
+	if err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusAccepted)
+	if _, err := writer.Write(mediaInBytes); err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+	}
+}
+
+// processMedia runs on the media worker pool. Anything that goes wrong marks
+// the media as failed so it can never be attached to a chirp.
+func (a *APIConfig) processMedia(ctx context.Context, mediaID uuid.UUID, data []byte) error {
+	processed, err := media.Process(data)
+	if err != nil {
+		return errors.Join(err, a.PtrToQueries.MarkMediaFailed(ctx, mediaID))
+	}
+
+	savedKeys := []string{}
+	cleanUp := func(cause error) error {
+		for _, key := range savedKeys {
+			a.Storage.Delete(ctx, key)
+		}
+		return errors.Join(cause, a.PtrToQueries.MarkMediaFailed(ctx, mediaID))
+	}
+
+	originalKey := mediaID.String() + processed.Original.Extension
+	if err := a.Storage.Save(ctx, originalKey, bytes.NewReader(processed.Original.Data)); err != nil {
+		return cleanUp(err)
+	}
+	savedKeys = append(savedKeys, originalKey)
+	createMediaVariantsParams := database.CreateMediaVariantsParams{
+		MediaID: mediaID,
+	}
+	for _, thumbnail := range processed.Thumbnails {
+		key := mediaID.String() + "_" + thumbnail.Name + thumbnail.Extension
+		if err := a.Storage.Save(ctx, key, bytes.NewReader(thumbnail.Data)); err != nil {
+			return cleanUp(err)
+		}
+		savedKeys = append(savedKeys, key)
+		createMediaVariantsParams.Names = append(createMediaVariantsParams.Names, thumbnail.Name)
+		createMediaVariantsParams.StorageKeys = append(createMediaVariantsParams.StorageKeys, key)
+		createMediaVariantsParams.ContentTypes = append(createMediaVariantsParams.ContentTypes, thumbnail.ContentType)
+		createMediaVariantsParams.Widths = append(createMediaVariantsParams.Widths, int32(thumbnail.Width))
+		createMediaVariantsParams.Heights = append(createMediaVariantsParams.Heights, int32(thumbnail.Height))
+	}
+
+	tx, err := a.PtrToDB.BeginTx(ctx, nil)
+	if err != nil {
+		return cleanUp(err)
+	}
+	defer tx.Rollback()
+	queriesInTx := a.PtrToQueries.WithTx(tx)
+
+	markMediaReadyParams := database.MarkMediaReadyParams{
+		ID: mediaID,
+		StorageKey: originalKey,
+		ContentType: processed.Original.ContentType,
+		SizeBytes: int64(len(processed.Original.Data)),
+		Width: sql.NullInt32{Int32: int32(processed.Original.Width), Valid: true},
+		Height: sql.NullInt32{Int32: int32(processed.Original.Height), Valid: true},
+		Blurhash: sql.NullString{String: processed.Blurhash, Valid: true},
+	}
+	if err := queriesInTx.MarkMediaReady(ctx, markMediaReadyParams); err != nil {
+		return cleanUp(err)
+	}
+	if err := queriesInTx.CreateMediaVariants(ctx, createMediaVariantsParams); err != nil {
+		return cleanUp(err)
+	}
+	if err := tx.Commit(); err != nil {
+		return cleanUp(err)
+	}
+	return nil
+}
+
+func (a *APIConfig) GetMedia(writer http.ResponseWriter, req *http.Request) {
+	jwtToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	userID, err := auth.ValidateJWT(jwtToken, a.SecretKey)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	mediaID, err := uuid.Parse(req.PathValue("mediaID"))
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+
+	// Unattached uploads are private, so they are only visible to the uploader
+	storedMedia, err := a.PtrToQueries.GetMedia(req.Context(), mediaID)
+	if err != nil || storedMedia.UserID != userID {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	variants, err := a.PtrToQueries.GetMediaVariants(req.Context(), []uuid.UUID{mediaID})
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+
+	mediaInBytes, err := json.Marshal(a.formatMedia(storedMedia, variants))
+	if err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	if _, err := writer.Write(mediaInBytes); err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+	}
+}
+
+// GetMediaFile serves stored bytes in place of a plain file server, so media
+// on chirps the viewer cannot see stays unreadable even with its URL
+func (a *APIConfig) GetMediaFile(writer http.ResponseWriter, req *http.Request) {
+	viewerID, err := a.optionalUserID(req)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	storageKey := req.PathValue("storageKey")
+	if storageKey == "" {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+
+	getReadableMediaFileParams := database.GetReadableMediaFileParams{
+		StorageKey: storageKey,
+		ViewerID: viewerID,
+	}
+	contentType, err := a.PtrToQueries.GetReadableMediaFile(req.Context(), getReadableMediaFileParams)
+	if errors.Is(err, sql.ErrNoRows) {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	file, err := a.Storage.Open(req.Context(), storageKey)
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	defer file.Close()
+
+	// Who may read a file depends on the viewer, so shared caches must not
+	// keep it
+	writer.Header().Set("Content-Type", contentType)
+	writer.Header().Set("Cache-Control", "private")
+	http.ServeContent(writer, req, storageKey, time.Time{}, file)
+}
+
+func (a *APIConfig) attachMedia(ctx context.Context, chirps []*chirpPayload) error {
+	chirpIDs := []uuid.UUID{}
+	for _, chirp := range chirps {
+		chirpIDs = append(chirpIDs, chirp.ID)
+	}
+
+	sliceOfMedia, err := a.PtrToQueries.GetChirpMedia(ctx, chirpIDs)
+	if err != nil {
+		return err
+	}
+	mediaIDs := []uuid.UUID{}
+	for _, chirpMedia := range sliceOfMedia {
+		mediaIDs = append(mediaIDs, chirpMedia.ID)
+	}
+	variants, err := a.PtrToQueries.GetMediaVariants(ctx, mediaIDs)
+	if err != nil {
+		return err
+	}
+	variantsByMedia := map[uuid.UUID][]database.MediaVariant{}
+	for _, variant := range variants {
+		variantsByMedia[variant.MediaID] = append(variantsByMedia[variant.MediaID], variant)
+	}
+
+	mediaByChirp := map[uuid.UUID][]mediaPayload{}
+	for _, chirpMedia := range sliceOfMedia {
+		storedMedia := database.Medium{
+			ID: chirpMedia.ID,
+			UserID: chirpMedia.UserID,
+			StorageKey: chirpMedia.StorageKey,
+			ContentType: chirpMedia.ContentType,
+			SizeBytes: chirpMedia.SizeBytes,
+			CreatedAt: chirpMedia.CreatedAt,
+			Status: chirpMedia.Status,
+			Width: chirpMedia.Width,
+			Height: chirpMedia.Height,
+			Blurhash: chirpMedia.Blurhash,
+		}
+		mediaByChirp[chirpMedia.ChirpID] = append(mediaByChirp[chirpMedia.ChirpID], a.formatMedia(storedMedia, variantsByMedia[chirpMedia.ID]))
+	}
+
+	for _, chirp := range chirps {
+		chirp.Media = []mediaPayload{}
+		if attachedMedia, ok := mediaByChirp[chirp.ID]; ok {
+			chirp.Media = attachedMedia
+		}
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package state
+
+import (
+	"net/http"
+	"encoding/json"
+	"github.com/junwei890/chirpy/internal/database"
+	"github.com/junwei890/chirpy/internal/auth"
+	"github.com/google/uuid"
+)
+
+// moderatorID authenticates the caller and checks they are a moderator,
+// writing the error response itself when they are not
+func (a *APIConfig) moderatorID(writer http.ResponseWriter, req *http.Request) (uuid.UUID, bool) {
+	jwtToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return uuid.UUID{}, false
+	}
+	userID, err := auth.ValidateJWT(jwtToken, a.SecretKey)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return uuid.UUID{}, false
+	}
+	isModerator, err := a.PtrToQueries.IsModerator(req.Context(), userID)
+	if err != nil || !isModerator {
+		ErrorResponseWriter(writer, Forbidden)
+		return uuid.UUID{}, false
+	}
+	return userID, true
+}
+
+func (a *APIConfig) GetModerationChirp(writer http.ResponseWriter, req *http.Request) {
+	moderatorID, ok := a.moderatorID(writer, req)
+	if !ok {
+		return
+	}
+
+	chirpID := req.PathValue("chirpID")
+	if chirpID == "" {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	parsedChirpID, err := uuid.Parse(chirpID)
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	chirpToGet, err := a.PtrToQueries.GetChirpForModeration(req.Context(), parsedChirpID)
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+
+	formattedChirp := chirpPayload{
+		ID: chirpToGet.ID,
+		Body: chirpToGet.Body,
+		UserID: chirpToGet.UserID,
+		ChirpyRed: chirpToGet.IsChirpyRed,
+		CreatedAt: chirpToGet.CreatedAt,
+		UpdatedAt: chirpToGet.UpdatedAt,
+		Edited: isEdited(chirpToGet.CreatedAt, chirpToGet.UpdatedAt),
+		InReplyTo: nullUUIDToPointer(chirpToGet.InReplyTo),
+		RechirpOf: nullUUIDToPointer(chirpToGet.RechirpOf),
+		QuoteOf: nullUUIDToPointer(chirpToGet.QuoteOf),
+		PublishAt: nullTimeToPointer(chirpToGet.PublishAt),
+		ContentWarning: nullStringToPointer(chirpToGet.ContentWarning),
+		Sensitive: chirpToGet.Sensitive,
+		Visibility: chirpToGet.Visibility,
+		DeletedAt: nullTimeToPointer(chirpToGet.DeletedAt),
+	}
+	if err := a.decorateChirps(req.Context(), uuid.NullUUID{UUID: moderatorID, Valid: true}, []*chirpPayload{&formattedChirp}); err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+
+	chirpInBytes, err := json.Marshal(formattedChirp)
+	if err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	if _, err := writer.Write(chirpInBytes); err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+	}
+}
+
+func (a *APIConfig) PutModerator(writer http.ResponseWriter, req *http.Request) {
+	a.setModerator(writer, req, true)
+}
+
+func (a *APIConfig) DeleteModerator(writer http.ResponseWriter, req *http.Request) {
+	a.setModerator(writer, req, false)
+}
+
+func (a *APIConfig) setModerator(writer http.ResponseWriter, req *http.Request, isModerator bool) {
+	if !a.checkAdminKey(req) {
+		ErrorResponseWriter(writer, UnauthorizedBadAPIKey)
+		return
+	}
+	userID, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+
+	setModeratorParams := database.SetModeratorParams{
+		ID: userID,
+		IsModerator: isModerator,
+	}
+	updatedRows, err := a.PtrToQueries.SetModerator(req.Context(), setModeratorParams)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	if updatedRows == 0 {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (a *APIConfig) PutModerationSensitive(writer http.ResponseWriter, req *http.Request) {
+	a.setSensitiveByModerator(writer, req, true)
+}
+
+func (a *APIConfig) DeleteModerationSensitive(writer http.ResponseWriter, req *http.Request) {
+	a.setSensitiveByModerator(writer, req, false)
+}
+
+// setSensitiveByModerator keeps its own flag apart from the author's, so an
+// author can't clear a flag a moderator put on their chirp
+func (a *APIConfig) setSensitiveByModerator(writer http.ResponseWriter, req *http.Request, sensitive bool) {
+	if _, ok := a.moderatorID(writer, req); !ok {
+		return
+	}
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+
+	setChirpSensitiveByModeratorParams := database.SetChirpSensitiveByModeratorParams{
+		ID: chirpID,
+		SensitiveByModerator: sensitive,
+	}
+	updatedRows, err := a.PtrToQueries.SetChirpSensitiveByModerator(req.Context(), setChirpSensitiveByModeratorParams)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	if updatedRows == 0 {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,202 @@
+package state
+
+import (
+	"net/http"
+	"time"
+	"io"
+	"encoding/json"
+	"database/sql"
+	"errors"
+	"github.com/junwei890/chirpy/internal/database"
+	"github.com/junwei890/chirpy/internal/auth"
+	"github.com/junwei890/chirpy/internal/mutes"
+	"github.com/google/uuid"
+)
+
+type mutePayload struct {
+	ID uuid.UUID `json:"id"`
+	Kind string `json:"kind"`
+	UserID *uuid.UUID `json:"user_id,omitempty"`
+	Term *string `json:"term,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func formatMute(mute database.Mute) mutePayload {
+	return mutePayload{
+		ID: mute.ID,
+		Kind: mute.Kind,
+		UserID: nullUUIDToPointer(mute.MutedUserID),
+		Term: nullStringToPointer(mute.Term),
+		CreatedAt: mute.CreatedAt,
+		ExpiresAt: nullTimeToPointer(mute.ExpiresAt),
+	}
+}
+
+// PostMutes adds a mute rule for an account, a keyword or phrase, or a
+// hashtag. Nothing is sent to the muted user and nothing they can read
+// changes, the rule only filters what the muting user is served
+func (a *APIConfig) PostMutes(writer http.ResponseWriter, req *http.Request) {
+	type requestBody struct {
+		Kind string `json:"kind"`
+		UserID *uuid.UUID `json:"user_id"`
+		Term *string `json:"term"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+
+	jwtToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	userID, err := auth.ValidateJWT(jwtToken, a.SecretKey)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+
+	data, err := io.ReadAll(req.Body)
+	if err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+		return
+	}
+	dataReceived := requestBody{}
+	if err := json.Unmarshal(data, &dataReceived); err != nil {
+		ErrorResponseWriter(writer, BadRequest)
+		return
+	}
+
+	createMuteParams := database.CreateMuteParams{
+		UserID: userID,
+		Kind: dataReceived.Kind,
+	}
+	switch dataReceived.Kind {
+	case mutes.Account:
+		if dataReceived.UserID == nil || dataReceived.Term != nil || *dataReceived.UserID == userID {
+			ErrorResponseWriter(writer, BadRequest)
+			return
+		}
+		if _, err := a.PtrToQueries.GetUserByID(req.Context(), *dataReceived.UserID); err != nil {
+			ErrorResponseWriter(writer, NotFound)
+			return
+		}
+		createMuteParams.MutedUserID = uuid.NullUUID{UUID: *dataReceived.UserID, Valid: true}
+	case mutes.Keyword, mutes.Hashtag:
+		if dataReceived.Term == nil || dataReceived.UserID != nil {
+			ErrorResponseWriter(writer, BadRequest)
+			return
+		}
+		term, err := mutes.NormalizeTerm(dataReceived.Kind, *dataReceived.Term)
+		if err != nil {
+			ErrorResponseWriter(writer, BadRequest)
+			return
+		}
+		createMuteParams.Term = sql.NullString{String: term, Valid: true}
+	default:
+		ErrorResponseWriter(writer, BadRequest)
+		return
+	}
+	if dataReceived.ExpiresAt != nil {
+		if !dataReceived.ExpiresAt.After(time.Now()) {
+			ErrorResponseWriter(writer, BadRequest)
+			return
+		}
+		createMuteParams.ExpiresAt = sql.NullTime{Time: *dataReceived.ExpiresAt, Valid: true}
+	}
+
+	mute, err := a.PtrToQueries.CreateMute(req.Context(), createMuteParams)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ErrorResponseWriter(writer, AlreadyExists)
+			return
+		}
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+
+	muteInBytes, err := json.Marshal(formatMute(mute))
+	if err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusCreated)
+	if _, err := writer.Write(muteInBytes); err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+	}
+}
+
+// GetMutes lists the user's mute rules that have not expired
+func (a *APIConfig) GetMutes(writer http.ResponseWriter, req *http.Request) {
+	type validResponse struct {
+		Mutes []mutePayload `json:"mutes"`
+	}
+
+	jwtToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	userID, err := auth.ValidateJWT(jwtToken, a.SecretKey)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+
+	sliceOfMutes, err := a.PtrToQueries.GetActiveMutes(req.Context(), userID)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	formattedResponse := validResponse{
+		Mutes: []mutePayload{},
+	}
+	for _, mute := range sliceOfMutes {
+		formattedResponse.Mutes = append(formattedResponse.Mutes, formatMute(mute))
+	}
+
+	mutesInBytes, err := json.Marshal(formattedResponse)
+	if err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	if _, err := writer.Write(mutesInBytes); err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+	}
+}
+
+func (a *APIConfig) DeleteMute(writer http.ResponseWriter, req *http.Request) {
+	jwtToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	userID, err := auth.ValidateJWT(jwtToken, a.SecretKey)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+
+	muteID, err := uuid.Parse(req.PathValue("muteID"))
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+
+	deleteMuteParams := database.DeleteMuteParams{
+		ID: muteID,
+		UserID: userID,
+	}
+	deletedRows, err := a.PtrToQueries.DeleteMute(req.Context(), deleteMuteParams)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	if deletedRows == 0 {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
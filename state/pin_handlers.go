@@ -0,0 +1,144 @@
+package state
+
+import (
+	"context"
+	"net/http"
+	"github.com/junwei890/chirpy/internal/database"
+	"github.com/junwei890/chirpy/internal/auth"
+	"github.com/google/uuid"
+)
+
+// pinLimit is how many chirps a user can have pinned at once
+func (a *APIConfig) pinLimit(isChirpyRed bool) int {
+	if isChirpyRed {
+		return a.MaxPinsChirpyRed
+	}
+	return a.MaxPins
+}
+
+func (a *APIConfig) PutPin(writer http.ResponseWriter, req *http.Request) {
+	jwtToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	userID, err := auth.ValidateJWT(jwtToken, a.SecretKey)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+
+	chirpID := req.PathValue("chirpID")
+	if chirpID == "" {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	parsedChirpID, err := uuid.Parse(chirpID)
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	getOneChirpParams := database.GetOneChirpParams{
+		ID: parsedChirpID,
+		ViewerID: uuid.NullUUID{UUID: userID, Valid: true},
+	}
+	chirpToPin, err := a.PtrToQueries.GetOneChirp(req.Context(), getOneChirpParams)
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	if chirpToPin.UserID != userID {
+		ErrorResponseWriter(writer, Forbidden)
+		return
+	}
+	// Only chirps that are out and written by the user can go on their profile
+	if chirpToPin.RechirpOf.Valid || chirpToPin.PublishAt.Valid {
+		ErrorResponseWriter(writer, BadRequest)
+		return
+	}
+
+	if err := a.pinChirp(req.Context(), userID, parsedChirpID); err != nil {
+		ErrorResponseWriter(writer, responseErrorFor(err))
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// pinChirp locks the user's row while it counts their pins, so two pins
+// racing each other can't both squeeze under the limit
+func (a *APIConfig) pinChirp(ctx context.Context, userID, chirpID uuid.UUID) error {
+	tx, err := a.PtrToDB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	queriesInTx := a.PtrToQueries.WithTx(tx)
+
+	isChirpyRed, err := queriesInTx.LockUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	pinnedChirps, err := queriesInTx.ListPinnedChirps(ctx, database.ListPinnedChirpsParams{
+		UserID: userID,
+		ViewerID: uuid.NullUUID{UUID: userID, Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	for _, pinnedChirp := range pinnedChirps {
+		// Pinning an already pinned chirp changes nothing
+		if pinnedChirp.ID == chirpID {
+			return nil
+		}
+	}
+	if len(pinnedChirps) >= a.pinLimit(isChirpyRed) {
+		return PinLimitReached
+	}
+	if err := queriesInTx.PinChirp(ctx, chirpID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (a *APIConfig) DeletePin(writer http.ResponseWriter, req *http.Request) {
+	jwtToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	userID, err := auth.ValidateJWT(jwtToken, a.SecretKey)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+
+	chirpID := req.PathValue("chirpID")
+	if chirpID == "" {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	parsedChirpID, err := uuid.Parse(chirpID)
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	getOneChirpParams := database.GetOneChirpParams{
+		ID: parsedChirpID,
+		ViewerID: uuid.NullUUID{UUID: userID, Valid: true},
+	}
+	chirpToUnpin, err := a.PtrToQueries.GetOneChirp(req.Context(), getOneChirpParams)
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	if chirpToUnpin.UserID != userID {
+		ErrorResponseWriter(writer, Forbidden)
+		return
+	}
+
+	if err := a.PtrToQueries.UnpinChirp(req.Context(), parsedChirpID); err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,314 @@
+package state
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"time"
+	"encoding/json"
+	"github.com/junwei890/chirpy/internal/database"
+	"github.com/junwei890/chirpy/internal/auth"
+	"github.com/junwei890/chirpy/internal/polls"
+	"github.com/google/uuid"
+)
+
+const pollClosedEvent = "poll_closed"
+const pollCloseBatchSize = 100
+
+type pollInput struct {
+	Options []string `json:"options"`
+	ClosesAt time.Time `json:"closes_at"`
+}
+
+type pollOptionPayload struct {
+	Position int32 `json:"position"`
+	Text string `json:"text"`
+	Votes *int64 `json:"votes"`
+}
+
+// pollPayload leaves Votes and TotalVotes null until the viewer may see them
+type pollPayload struct {
+	ID uuid.UUID `json:"id"`
+	ClosesAt time.Time `json:"closes_at"`
+	Closed bool `json:"closed"`
+	Options []pollOptionPayload `json:"options"`
+	TotalVotes *int64 `json:"total_votes"`
+	ViewerVote *int32 `json:"viewer_vote"`
+}
+
+// loadPolls builds the payloads for every poll on chirpIDs keyed by chirp,
+// with tallies filled in only where viewerID is allowed to see them
+func (a *APIConfig) loadPolls(ctx context.Context, queries *database.Queries, viewerID uuid.NullUUID, chirpIDs []uuid.UUID, forceTallies bool) (map[uuid.UUID]*pollPayload, error) {
+	sliceOfPollOptions, err := queries.GetPollsForChirps(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+	pollsByChirp := map[uuid.UUID]*pollPayload{}
+	pollIDs := []uuid.UUID{}
+	for _, pollOption := range sliceOfPollOptions {
+		poll, ok := pollsByChirp[pollOption.ChirpID]
+		if !ok {
+			poll = &pollPayload{
+				ID: pollOption.ID,
+				ClosesAt: pollOption.ClosesAt,
+				Closed: !time.Now().UTC().Before(pollOption.ClosesAt),
+				Options: []pollOptionPayload{},
+			}
+			pollsByChirp[pollOption.ChirpID] = poll
+			pollIDs = append(pollIDs, pollOption.ID)
+		}
+		poll.Options = append(poll.Options, pollOptionPayload{
+			Position: pollOption.Position,
+			Text: pollOption.Text,
+		})
+	}
+	if len(pollIDs) == 0 {
+		return pollsByChirp, nil
+	}
+
+	viewerVotes := map[uuid.UUID]int32{}
+	if viewerID.Valid {
+		getViewerVotesParams := database.GetViewerVotesParams{
+			PollIds: pollIDs,
+			UserID: viewerID.UUID,
+		}
+		sliceOfVotes, err := queries.GetViewerVotes(ctx, getViewerVotesParams)
+		if err != nil {
+			return nil, err
+		}
+		for _, vote := range sliceOfVotes {
+			viewerVotes[vote.PollID] = vote.Position
+		}
+	}
+	sliceOfTallies, err := queries.GetPollTallies(ctx, pollIDs)
+	if err != nil {
+		return nil, err
+	}
+	tallies := map[uuid.UUID]map[int32]int64{}
+	for _, tally := range sliceOfTallies {
+		if _, ok := tallies[tally.PollID]; !ok {
+			tallies[tally.PollID] = map[int32]int64{}
+		}
+		tallies[tally.PollID][tally.Position] = tally.VoteCount
+	}
+
+	for _, poll := range pollsByChirp {
+		viewerVote, hasVoted := viewerVotes[poll.ID]
+		if hasVoted {
+			poll.ViewerVote = &viewerVote
+		}
+		if !forceTallies && !polls.ShowTallies(hasVoted, poll.ClosesAt, time.Now().UTC()) {
+			continue
+		}
+		totalVotes := int64(0)
+		for index := range poll.Options {
+			votes := tallies[poll.ID][poll.Options[index].Position]
+			poll.Options[index].Votes = &votes
+			totalVotes += votes
+		}
+		poll.TotalVotes = &totalVotes
+	}
+	return pollsByChirp, nil
+}
+
+func (a *APIConfig) attachPolls(ctx context.Context, viewerID uuid.NullUUID, chirps []*chirpPayload) error {
+	chirpIDs := []uuid.UUID{}
+	for _, chirp := range chirps {
+		chirpIDs = append(chirpIDs, chirp.ID)
+	}
+	pollsByChirp, err := a.loadPolls(ctx, a.PtrToQueries, viewerID, chirpIDs, false)
+	if err != nil {
+		return err
+	}
+	for _, chirp := range chirps {
+		chirp.Poll = pollsByChirp[chirp.ID]
+	}
+	return nil
+}
+
+func (a *APIConfig) PostPollVote(writer http.ResponseWriter, req *http.Request) {
+	type requestBody struct {
+		Option *int32 `json:"option"`
+	}
+
+	jwtToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	userID, err := auth.ValidateJWT(jwtToken, a.SecretKey)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+
+	chirpID := req.PathValue("chirpID")
+	if chirpID == "" {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	parsedChirpID, err := uuid.Parse(chirpID)
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+
+	dataReceivedInBytes, err := io.ReadAll(req.Body)
+	if err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+		return
+	}
+	dataReceived := &requestBody{}
+	if err := json.Unmarshal(dataReceivedInBytes, dataReceived); err != nil || dataReceived.Option == nil {
+		ErrorResponseWriter(writer, BadRequest)
+		return
+	}
+
+	viewerID := uuid.NullUUID{UUID: userID, Valid: true}
+	getOneChirpParams := database.GetOneChirpParams{
+		ID: parsedChirpID,
+		ViewerID: viewerID,
+	}
+	if _, err := a.PtrToQueries.GetOneChirp(req.Context(), getOneChirpParams); err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	pollsByChirp, err := a.loadPolls(req.Context(), a.PtrToQueries, viewerID, []uuid.UUID{parsedChirpID}, false)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	poll, ok := pollsByChirp[parsedChirpID]
+	if !ok {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	if poll.Closed {
+		ErrorResponseWriter(writer, PollClosed)
+		return
+	}
+	validOption := false
+	for _, option := range poll.Options {
+		validOption = validOption || option.Position == *dataReceived.Option
+	}
+	if !validOption {
+		ErrorResponseWriter(writer, BadRequest)
+		return
+	}
+
+	createVoteParams := database.CreateVoteParams{
+		UserID: userID,
+		Position: *dataReceived.Option,
+		PollID: poll.ID,
+	}
+	createdRows, err := a.PtrToQueries.CreateVote(req.Context(), createVoteParams)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	if createdRows == 0 {
+		ErrorResponseWriter(writer, AlreadyExists)
+		return
+	}
+
+	// Voting is what unlocks the tallies, so they come back with the response
+	pollsByChirp, err = a.loadPolls(req.Context(), a.PtrToQueries, viewerID, []uuid.UUID{parsedChirpID}, false)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	pollInBytes, err := json.Marshal(pollsByChirp[parsedChirpID])
+	if err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusCreated)
+	if _, err := writer.Write(pollInBytes); err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+	}
+}
+
+// RunPollCloser sends authors the results of their polls as the polls close,
+// until ctx is done
+func (a *APIConfig) RunPollCloser(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := a.closePolls(ctx); err != nil {
+			log.Println(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (a *APIConfig) closePolls(ctx context.Context) error {
+	for {
+		closedCount, err := a.closePollBatch(ctx)
+		if err != nil {
+			return err
+		}
+		if closedCount < pollCloseBatchSize {
+			return nil
+		}
+	}
+}
+
+// closePollBatch records the result event and marks the poll closed in one
+// transaction, so an author gets exactly one event per poll
+func (a *APIConfig) closePollBatch(ctx context.Context) (int, error) {
+	type pollClosedPayload struct {
+		ChirpID uuid.UUID `json:"chirp_id"`
+		Poll *pollPayload `json:"poll"`
+	}
+
+	tx, err := a.PtrToDB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	queriesInTx := a.PtrToQueries.WithTx(tx)
+
+	closingPolls, err := queriesInTx.ClaimClosingPolls(ctx, pollCloseBatchSize)
+	if err != nil || len(closingPolls) == 0 {
+		return 0, err
+	}
+	chirpIDs := []uuid.UUID{}
+	for _, closingPoll := range closingPolls {
+		chirpIDs = append(chirpIDs, closingPoll.ChirpID)
+	}
+	pollsByChirp, err := a.loadPolls(ctx, queriesInTx, uuid.NullUUID{}, chirpIDs, true)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, closingPoll := range closingPolls {
+		eventPayloadInBytes, err := json.Marshal(pollClosedPayload{
+			ChirpID: closingPoll.ChirpID,
+			Poll: pollsByChirp[closingPoll.ChirpID],
+		})
+		if err != nil {
+			return 0, err
+		}
+		createEventParams := database.CreateEventParams{
+			UserID: closingPoll.UserID,
+			Type: pollClosedEvent,
+			Payload: eventPayloadInBytes,
+		}
+		if err := queriesInTx.CreateEvent(ctx, createEventParams); err != nil {
+			return 0, err
+		}
+		if err := queriesInTx.MarkPollClosed(ctx, closingPoll.ID); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(closingPolls), nil
+}
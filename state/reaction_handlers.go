@@ -0,0 +1,224 @@
+package state
+
+import (
+	"net/http"
+	"encoding/json"
+	"database/sql"
+	"github.com/junwei890/chirpy/internal/database"
+	"github.com/junwei890/chirpy/internal/auth"
+	"github.com/junwei890/chirpy/internal/pagination"
+	"github.com/google/uuid"
+)
+
+const likeReaction = "like"
+
+var allowedReactions = map[string]struct{}{
+	likeReaction: {},
+	"heart": {},
+	"laugh": {},
+	"wow": {},
+	"sad": {},
+	"angry": {},
+}
+
+func (a *APIConfig) PutReaction(writer http.ResponseWriter, req *http.Request) {
+	jwtToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	userID, err := auth.ValidateJWT(jwtToken, a.SecretKey)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+
+	chirpID := req.PathValue("chirpID")
+	if chirpID == "" {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	parsedChirpID, err := uuid.Parse(chirpID)
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	reaction := req.PathValue("reaction")
+	if _, ok := allowedReactions[reaction]; !ok {
+		ErrorResponseWriter(writer, BadRequest)
+		return
+	}
+	getOneChirpParams := database.GetOneChirpParams{
+		ID: parsedChirpID,
+		ViewerID: uuid.NullUUID{UUID: userID, Valid: true},
+	}
+	if _, err := a.PtrToQueries.GetOneChirp(req.Context(), getOneChirpParams); err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+
+	createReactionParams := database.CreateReactionParams{
+		ChirpID: parsedChirpID,
+		UserID: userID,
+		Reaction: reaction,
+	}
+	if err := a.PtrToQueries.CreateReaction(req.Context(), createReactionParams); err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (a *APIConfig) DeleteReaction(writer http.ResponseWriter, req *http.Request) {
+	jwtToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	userID, err := auth.ValidateJWT(jwtToken, a.SecretKey)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+
+	chirpID := req.PathValue("chirpID")
+	if chirpID == "" {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	parsedChirpID, err := uuid.Parse(chirpID)
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	reaction := req.PathValue("reaction")
+	if _, ok := allowedReactions[reaction]; !ok {
+		ErrorResponseWriter(writer, BadRequest)
+		return
+	}
+
+	deleteReactionParams := database.DeleteReactionParams{
+		ChirpID: parsedChirpID,
+		UserID: userID,
+		Reaction: reaction,
+	}
+	if err := a.PtrToQueries.DeleteReaction(req.Context(), deleteReactionParams); err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (a *APIConfig) GetUserLikes(writer http.ResponseWriter, req *http.Request) {
+	type validResponse struct {
+		Chirps []chirpPayload `json:"chirps"`
+		NextCursor *string `json:"next_cursor"`
+	}
+
+	viewerID, err := a.optionalUserID(req)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+
+	userID := req.PathValue("userID")
+	if userID == "" {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	parsedUserID, err := uuid.Parse(userID)
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	if _, err := a.PtrToQueries.GetUserByID(req.Context(), parsedUserID); err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	limit, err := pagination.ParseLimit(req.URL.Query().Get("limit"))
+	if err != nil {
+		InvalidParameterResponseWriter(writer, "limit")
+		return
+	}
+	sensitiveContent, err := a.sensitiveContentFor(req.Context(), viewerID)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+
+	// One extra row is fetched to tell whether another page exists
+	listReactedChirpsParams := database.ListReactedChirpsParams{
+		HideSensitive: sensitiveContent == sensitiveFilter,
+		UserID: parsedUserID,
+		Reaction: likeReaction,
+		ViewerID: viewerID,
+		RowLimit: limit + 1,
+	}
+	if encodedCursor := req.URL.Query().Get("cursor"); encodedCursor != "" {
+		cursor, err := pagination.DecodeCursor(encodedCursor)
+		if err != nil {
+			InvalidParameterResponseWriter(writer, "cursor")
+			return
+		}
+		listReactedChirpsParams.CursorTime = sql.NullTime{Time: cursor.Time, Valid: true}
+		listReactedChirpsParams.CursorID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+	sliceOfChirps, err := a.PtrToQueries.ListReactedChirps(req.Context(), listReactedChirpsParams)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+
+	formattedResponse := validResponse{
+		Chirps: []chirpPayload{},
+	}
+	for index, chirp := range sliceOfChirps {
+		if index == int(limit) {
+			lastChirp := sliceOfChirps[index-1]
+			nextCursor := pagination.EncodeCursor(pagination.Cursor{
+				Time: lastChirp.ReactedAt,
+				ID: lastChirp.ID,
+			})
+			formattedResponse.NextCursor = &nextCursor
+			writer.Header().Set("Link", pagination.NextLink(req.URL.Path, req.URL.Query(), "cursor", nextCursor))
+			break
+		}
+		formattedChirp := chirpPayload{
+			ID: chirp.ID,
+			Body: chirp.Body,
+			UserID: chirp.UserID,
+			ChirpyRed: chirp.IsChirpyRed,
+			CreatedAt: chirp.CreatedAt,
+			UpdatedAt: chirp.UpdatedAt,
+			Edited: isEdited(chirp.CreatedAt, chirp.UpdatedAt),
+			InReplyTo: nullUUIDToPointer(chirp.InReplyTo),
+			RechirpOf: nullUUIDToPointer(chirp.RechirpOf),
+			QuoteOf: nullUUIDToPointer(chirp.QuoteOf),
+			PublishAt: nullTimeToPointer(chirp.PublishAt),
+			ContentWarning: nullStringToPointer(chirp.ContentWarning),
+			Sensitive: chirp.Sensitive,
+			Visibility: chirp.Visibility,
+		}
+		formattedResponse.Chirps = append(formattedResponse.Chirps, formattedChirp)
+	}
+
+	chirpsToDecorate := []*chirpPayload{}
+	for index := range formattedResponse.Chirps {
+		chirpsToDecorate = append(chirpsToDecorate, &formattedResponse.Chirps[index])
+	}
+	if err := a.decorateChirps(req.Context(), viewerID, chirpsToDecorate); err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+
+	chirpsInBytes, err := json.Marshal(formattedResponse)
+	if err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	if _, err := writer.Write(chirpsInBytes); err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+	}
+}
@@ -0,0 +1,148 @@
+package state
+
+import (
+	"net/http"
+	"errors"
+	"encoding/json"
+	"database/sql"
+	"github.com/junwei890/chirpy/internal/database"
+	"github.com/junwei890/chirpy/internal/auth"
+	"github.com/google/uuid"
+)
+
+func (a *APIConfig) PostRechirp(writer http.ResponseWriter, req *http.Request) {
+	jwtToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	userID, err := auth.ValidateJWT(jwtToken, a.SecretKey)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+
+	chirpID := req.PathValue("chirpID")
+	if chirpID == "" {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	parsedChirpID, err := uuid.Parse(chirpID)
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	getOneChirpParams := database.GetOneChirpParams{
+		ID: parsedChirpID,
+		ViewerID: uuid.NullUUID{UUID: userID, Valid: true},
+	}
+	chirpToRechirp, err := a.PtrToQueries.GetOneChirp(req.Context(), getOneChirpParams)
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	// Only its author can see a scheduled chirp, so nobody can repost it yet,
+	// and chirps meant for a few people can't be passed on to everyone
+	if chirpToRechirp.PublishAt.Valid || !isShareable(chirpToRechirp.Visibility) {
+		ErrorResponseWriter(writer, BadRequest)
+		return
+	}
+	// Rechirping a bare rechirp reposts the chirp it reposted
+	if chirpToRechirp.RechirpOf.Valid {
+		chirpToRechirp.ID = chirpToRechirp.RechirpOf.UUID
+	}
+	sharesProtected, err := a.sharesProtectedChirp(req.Context(), chirpToRechirp.ID, userID)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	if sharesProtected {
+		ErrorResponseWriter(writer, BadRequest)
+		return
+	}
+
+	createRechirpParams := database.CreateRechirpParams{
+		UserID: userID,
+		RechirpOf: chirpToRechirp.ID,
+	}
+	createdRechirp, err := a.PtrToQueries.CreateRechirp(req.Context(), createRechirpParams)
+	if errors.Is(err, sql.ErrNoRows) {
+		ErrorResponseWriter(writer, AlreadyExists)
+		return
+	}
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	a.wakeTimelineFanout()
+	formattedRechirp := chirpPayload{
+		ID: createdRechirp.ID,
+		Body: createdRechirp.Body,
+		UserID: createdRechirp.UserID,
+		ChirpyRed: createdRechirp.IsChirpyRed,
+		CreatedAt: createdRechirp.CreatedAt,
+		UpdatedAt: createdRechirp.UpdatedAt,
+		Edited: isEdited(createdRechirp.CreatedAt, createdRechirp.UpdatedAt),
+		InReplyTo: nullUUIDToPointer(createdRechirp.InReplyTo),
+		RechirpOf: nullUUIDToPointer(createdRechirp.RechirpOf),
+		QuoteOf: nullUUIDToPointer(createdRechirp.QuoteOf),
+		PublishAt: nullTimeToPointer(createdRechirp.PublishAt),
+		ContentWarning: nullStringToPointer(createdRechirp.ContentWarning),
+		Sensitive: createdRechirp.Sensitive,
+		Visibility: createdRechirp.Visibility,
+	}
+	if err := a.decorateChirps(req.Context(), uuid.NullUUID{UUID: userID, Valid: true}, []*chirpPayload{&formattedRechirp}); err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+
+	rechirpInBytes, err := json.Marshal(formattedRechirp)
+	if err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusCreated)
+	if _, err := writer.Write(rechirpInBytes); err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+	}
+}
+
+func (a *APIConfig) DeleteRechirp(writer http.ResponseWriter, req *http.Request) {
+	jwtToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	userID, err := auth.ValidateJWT(jwtToken, a.SecretKey)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+
+	chirpID := req.PathValue("chirpID")
+	if chirpID == "" {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	parsedChirpID, err := uuid.Parse(chirpID)
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+
+	deleteRechirpParams := database.DeleteRechirpParams{
+		UserID: userID,
+		RechirpOf: parsedChirpID,
+	}
+	deletedRows, err := a.PtrToQueries.DeleteRechirp(req.Context(), deleteRechirpParams)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	if deletedRows == 0 {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
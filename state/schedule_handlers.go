@@ -0,0 +1,351 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"log"
+	"net/http"
+	"time"
+	"encoding/json"
+	"github.com/junwei890/chirpy/internal/database"
+	"github.com/junwei890/chirpy/internal/auth"
+	"github.com/junwei890/chirpy/internal/pagination"
+	"github.com/junwei890/chirpy/internal/polls"
+	"github.com/google/uuid"
+)
+
+const maxScheduleAhead = 365 * 24 * time.Hour
+const publishBatchSize = 100
+
+// validPublishAt reports whether a chirp can be scheduled for publishAt,
+// which has to be in the future but not too far into it
+func validPublishAt(publishAt time.Time) (time.Time, bool) {
+	now := time.Now().UTC()
+	publishAt = publishAt.UTC()
+	if !publishAt.After(now) || publishAt.After(now.Add(maxScheduleAhead)) {
+		return time.Time{}, false
+	}
+	return publishAt, true
+}
+
+// RunScheduler publishes scheduled chirps as they fall due until ctx is done.
+// Pending chirps only live in the database, so a restart picks up whatever
+// came due while the server was down on its first tick.
+func (a *APIConfig) RunScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := a.publishDueChirps(ctx); err != nil {
+			log.Println(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (a *APIConfig) publishDueChirps(ctx context.Context) error {
+	for {
+		sliceOfChirps, err := a.PtrToQueries.PublishDueChirps(ctx, publishBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, chirp := range sliceOfChirps {
+			if chirp.Visibility != visibilityPublic || chirp.Protected {
+				continue
+			}
+			a.Trends.Record(hashtagsIn(chirp.Body), chirp.CreatedAt)
+		}
+		if len(sliceOfChirps) > 0 {
+			a.wakeTimelineFanout()
+		}
+		if len(sliceOfChirps) < publishBatchSize {
+			return nil
+		}
+	}
+}
+
+func (a *APIConfig) GetScheduledChirps(writer http.ResponseWriter, req *http.Request) {
+	type validResponse struct {
+		Chirps []chirpPayload `json:"chirps"`
+		NextCursor *string `json:"next_cursor"`
+	}
+
+	jwtToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	userID, err := auth.ValidateJWT(jwtToken, a.SecretKey)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	limit, err := pagination.ParseLimit(req.URL.Query().Get("limit"))
+	if err != nil {
+		InvalidParameterResponseWriter(writer, "limit")
+		return
+	}
+
+	// One extra row is fetched to tell whether another page exists
+	listScheduledChirpsParams := database.ListScheduledChirpsParams{
+		UserID: userID,
+		RowLimit: limit + 1,
+	}
+	if encodedCursor := req.URL.Query().Get("cursor"); encodedCursor != "" {
+		cursor, err := pagination.DecodeCursor(encodedCursor)
+		if err != nil {
+			InvalidParameterResponseWriter(writer, "cursor")
+			return
+		}
+		listScheduledChirpsParams.CursorTime = sql.NullTime{Time: cursor.Time, Valid: true}
+		listScheduledChirpsParams.CursorID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+	sliceOfChirps, err := a.PtrToQueries.ListScheduledChirps(req.Context(), listScheduledChirpsParams)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+
+	formattedResponse := validResponse{
+		Chirps: []chirpPayload{},
+	}
+	for index, chirp := range sliceOfChirps {
+		if index == int(limit) {
+			lastChirp := sliceOfChirps[index-1]
+			nextCursor := pagination.EncodeCursor(pagination.Cursor{
+				Time: lastChirp.PublishAt.Time,
+				ID: lastChirp.ID,
+			})
+			formattedResponse.NextCursor = &nextCursor
+			writer.Header().Set("Link", pagination.NextLink(req.URL.Path, req.URL.Query(), "cursor", nextCursor))
+			break
+		}
+		formattedChirp := chirpPayload{
+			ID: chirp.ID,
+			Body: chirp.Body,
+			UserID: chirp.UserID,
+			ChirpyRed: chirp.IsChirpyRed,
+			CreatedAt: chirp.CreatedAt,
+			UpdatedAt: chirp.UpdatedAt,
+			Edited: isEdited(chirp.CreatedAt, chirp.UpdatedAt),
+			InReplyTo: nullUUIDToPointer(chirp.InReplyTo),
+			RechirpOf: nullUUIDToPointer(chirp.RechirpOf),
+			QuoteOf: nullUUIDToPointer(chirp.QuoteOf),
+			PublishAt: nullTimeToPointer(chirp.PublishAt),
+			ContentWarning: nullStringToPointer(chirp.ContentWarning),
+			Sensitive: chirp.Sensitive,
+			Visibility: chirp.Visibility,
+		}
+		formattedResponse.Chirps = append(formattedResponse.Chirps, formattedChirp)
+	}
+
+	chirpsToDecorate := []*chirpPayload{}
+	for index := range formattedResponse.Chirps {
+		chirpsToDecorate = append(chirpsToDecorate, &formattedResponse.Chirps[index])
+	}
+	if err := a.decorateChirps(req.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirpsToDecorate); err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+
+	chirpsInBytes, err := json.Marshal(formattedResponse)
+	if err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	if _, err := writer.Write(chirpsInBytes); err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+	}
+}
+
+func (a *APIConfig) PutChirpSchedule(writer http.ResponseWriter, req *http.Request) {
+	type requestBody struct {
+		PublishAt *time.Time `json:"publish_at"`
+	}
+
+	jwtToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	userID, err := auth.ValidateJWT(jwtToken, a.SecretKey)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+
+	chirpID := req.PathValue("chirpID")
+	if chirpID == "" {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	parsedChirpID, err := uuid.Parse(chirpID)
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+
+	dataReceivedInBytes, err := io.ReadAll(req.Body)
+	if err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+		return
+	}
+	dataReceived := &requestBody{}
+	if err := json.Unmarshal(dataReceivedInBytes, dataReceived); err != nil || dataReceived.PublishAt == nil {
+		ErrorResponseWriter(writer, BadRequest)
+		return
+	}
+	publishAt, ok := validPublishAt(*dataReceived.PublishAt)
+	if !ok {
+		ErrorResponseWriter(writer, BadRequest)
+		return
+	}
+
+	tx, err := a.PtrToDB.BeginTx(req.Context(), nil)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	defer tx.Rollback()
+	queriesInTx := a.PtrToQueries.WithTx(tx)
+
+	// Chirps that are not the caller's or have already gone out are not found
+	rescheduleChirpParams := database.RescheduleChirpParams{
+		ID: parsedChirpID,
+		UserID: userID,
+		PublishAt: publishAt,
+	}
+	rescheduledChirp, err := queriesInTx.RescheduleChirp(req.Context(), rescheduleChirpParams)
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	// A poll only opens when its chirp goes out, so it has to stay open for
+	// a sensible time after the new publish time as well
+	pollRows, err := queriesInTx.GetPollsForChirps(req.Context(), []uuid.UUID{parsedChirpID})
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	if len(pollRows) > 0 {
+		pollOptions := []string{}
+		for _, pollRow := range pollRows {
+			pollOptions = append(pollOptions, pollRow.Text)
+		}
+		if _, err := polls.Validate(pollOptions, pollRows[0].ClosesAt, publishAt); err != nil {
+			ErrorResponseWriter(writer, BadRequest)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+
+	formattedChirp := chirpPayload{
+		ID: rescheduledChirp.ID,
+		Body: rescheduledChirp.Body,
+		UserID: rescheduledChirp.UserID,
+		ChirpyRed: rescheduledChirp.IsChirpyRed,
+		CreatedAt: rescheduledChirp.CreatedAt,
+		UpdatedAt: rescheduledChirp.UpdatedAt,
+		Edited: isEdited(rescheduledChirp.CreatedAt, rescheduledChirp.UpdatedAt),
+		InReplyTo: nullUUIDToPointer(rescheduledChirp.InReplyTo),
+		RechirpOf: nullUUIDToPointer(rescheduledChirp.RechirpOf),
+		QuoteOf: nullUUIDToPointer(rescheduledChirp.QuoteOf),
+		PublishAt: nullTimeToPointer(rescheduledChirp.PublishAt),
+		ContentWarning: nullStringToPointer(rescheduledChirp.ContentWarning),
+		Sensitive: rescheduledChirp.Sensitive,
+		Visibility: rescheduledChirp.Visibility,
+	}
+	if err := a.decorateChirps(req.Context(), uuid.NullUUID{UUID: userID, Valid: true}, []*chirpPayload{&formattedChirp}); err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+
+	chirpInBytes, err := json.Marshal(formattedChirp)
+	if err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	if _, err := writer.Write(chirpInBytes); err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+	}
+}
+
+func (a *APIConfig) DeleteChirpSchedule(writer http.ResponseWriter, req *http.Request) {
+	jwtToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	userID, err := auth.ValidateJWT(jwtToken, a.SecretKey)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+
+	chirpID := req.PathValue("chirpID")
+	if chirpID == "" {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	parsedChirpID, err := uuid.Parse(chirpID)
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+
+	tx, err := a.PtrToDB.BeginTx(req.Context(), nil)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	defer tx.Rollback()
+	queriesInTx := a.PtrToQueries.WithTx(tx)
+
+	// Cancelling throws the chirp away, it was never seen by anyone else.
+	// Its media goes first, deleting the chirp would cascade away the links
+	// that find it, and all of it rolls back when the chirp isn't cancellable
+	storageKeys, err := queriesInTx.GetStorageKeysForChirps(req.Context(), []uuid.UUID{parsedChirpID})
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	if err := queriesInTx.DeleteMediaForChirps(req.Context(), []uuid.UUID{parsedChirpID}); err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	cancelScheduledChirpParams := database.CancelScheduledChirpParams{
+		ID: parsedChirpID,
+		UserID: userID,
+	}
+	cancelledRows, err := queriesInTx.CancelScheduledChirp(req.Context(), cancelScheduledChirpParams)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	if cancelledRows == 0 {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+
+	for _, storageKey := range storageKeys {
+		if err := a.Storage.Delete(req.Context(), storageKey); err != nil {
+			log.Println(err)
+		}
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,153 @@
+package state
+
+import (
+	"net/http"
+	"strconv"
+	"errors"
+	"encoding/json"
+	"database/sql"
+	"github.com/junwei890/chirpy/internal/database"
+	"github.com/junwei890/chirpy/internal/filters"
+	"github.com/junwei890/chirpy/internal/pagination"
+	"github.com/junwei890/chirpy/internal/search"
+)
+
+func (a *APIConfig) GetChirpsSearch(writer http.ResponseWriter, req *http.Request) {
+	type oneResult struct {
+		chirpPayload
+		Rank float32 `json:"rank"`
+		Snippet string `json:"snippet"`
+	}
+	type validResponse struct {
+		Results []oneResult `json:"results"`
+		NextOffset *int32 `json:"next_offset"`
+	}
+
+	viewerID, err := a.optionalUserID(req)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+
+	searchQuery, err := search.BuildTSQuery(req.URL.Query().Get("q"))
+	if err != nil {
+		InvalidParameterResponseWriter(writer, "q")
+		return
+	}
+	chirpFilters, err := filters.ParseChirpFilters(req.URL.Query())
+	if err != nil {
+		var invalidParameterError *filters.InvalidParameterError
+		if errors.As(err, &invalidParameterError) {
+			InvalidParameterResponseWriter(writer, invalidParameterError.Parameter)
+			return
+		}
+		ErrorResponseWriter(writer, BadRequest)
+		return
+	}
+	// Results are ordered by rank, so the listing's sort options don't apply
+	for _, parameter := range []string{"sort", "sort_by"} {
+		if req.URL.Query().Has(parameter) {
+			InvalidParameterResponseWriter(writer, parameter)
+			return
+		}
+	}
+	limit, err := pagination.ParseLimit(req.URL.Query().Get("limit"))
+	if err != nil {
+		InvalidParameterResponseWriter(writer, "limit")
+		return
+	}
+	sensitiveContent, err := a.sensitiveContentFor(req.Context(), viewerID)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	offset, err := pagination.ParseOffset(req.URL.Query().Get("offset"))
+	if err != nil {
+		InvalidParameterResponseWriter(writer, "offset")
+		return
+	}
+
+	// One extra row is fetched to tell whether another page exists
+	searchChirpsParams := database.SearchChirpsParams{
+		HideSensitive: sensitiveContent == sensitiveFilter,
+		SearchQuery: searchQuery,
+		AuthorIds: chirpFilters.AuthorIDs,
+		ViewerID: viewerID,
+		RowLimit: limit + 1,
+		RowOffset: offset,
+	}
+	if chirpFilters.Since != nil {
+		searchChirpsParams.Since = sql.NullTime{Time: *chirpFilters.Since, Valid: true}
+	}
+	if chirpFilters.Until != nil {
+		searchChirpsParams.Until = sql.NullTime{Time: *chirpFilters.Until, Valid: true}
+	}
+	if chirpFilters.ChirpyRed != nil {
+		searchChirpsParams.IsChirpyRed = sql.NullBool{Bool: *chirpFilters.ChirpyRed, Valid: true}
+	}
+	if chirpFilters.HasMedia != nil {
+		searchChirpsParams.HasMedia = sql.NullBool{Bool: *chirpFilters.HasMedia, Valid: true}
+	}
+	sliceOfResults, err := a.PtrToQueries.SearchChirps(req.Context(), searchChirpsParams)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+
+	returnResults := []oneResult{}
+	for _, result := range sliceOfResults {
+		formattedResult := oneResult{
+			chirpPayload: chirpPayload{
+				ID: result.ID,
+				Body: result.Body,
+				UserID: result.UserID,
+				ChirpyRed: result.IsChirpyRed,
+				CreatedAt: result.CreatedAt,
+				UpdatedAt: result.UpdatedAt,
+				Edited: isEdited(result.CreatedAt, result.UpdatedAt),
+				InReplyTo: nullUUIDToPointer(result.InReplyTo),
+				RechirpOf: nullUUIDToPointer(result.RechirpOf),
+				QuoteOf: nullUUIDToPointer(result.QuoteOf),
+				PublishAt: nullTimeToPointer(result.PublishAt),
+				ContentWarning: nullStringToPointer(result.ContentWarning),
+				Sensitive: result.Sensitive,
+				Visibility: result.Visibility,
+			},
+			Rank: result.Rank,
+			Snippet: result.Snippet,
+		}
+		returnResults = append(returnResults, formattedResult)
+	}
+
+	formattedResponse := validResponse{
+		Results: returnResults,
+	}
+	if len(returnResults) > int(limit) {
+		formattedResponse.Results = returnResults[:limit]
+		// Pages past the offset limit are never offered
+		if nextOffset := offset + limit; nextOffset <= pagination.MaxOffset {
+			formattedResponse.NextOffset = &nextOffset
+			writer.Header().Set("Link", pagination.NextLink(req.URL.Path, req.URL.Query(), "offset", strconv.Itoa(int(nextOffset))))
+		}
+	}
+
+	chirpsToDecorate := []*chirpPayload{}
+	for index := range formattedResponse.Results {
+		chirpsToDecorate = append(chirpsToDecorate, &formattedResponse.Results[index].chirpPayload)
+	}
+	if err := a.decorateChirps(req.Context(), viewerID, chirpsToDecorate); err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+
+	resultsInBytes, err := json.Marshal(formattedResponse)
+	if err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	if _, err := writer.Write(resultsInBytes); err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+	}
+}
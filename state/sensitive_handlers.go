@@ -0,0 +1,253 @@
+package state
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"encoding/json"
+	"database/sql"
+	"github.com/junwei890/chirpy/internal/database"
+	"github.com/junwei890/chirpy/internal/auth"
+	"github.com/google/uuid"
+)
+
+const maxContentWarningLength = 100
+
+// What a user wants done with chirps that are sensitive or carry a content
+// warning when they list chirps
+const (
+	sensitiveCollapse = "collapse"
+	sensitiveShow = "show"
+	sensitiveFilter = "filter"
+)
+
+var sensitiveContentModes = map[string]struct{}{
+	sensitiveCollapse: {},
+	sensitiveShow: {},
+	sensitiveFilter: {},
+}
+
+// validContentWarning trims contentWarning, treating a blank one as no
+// warning at all
+func validContentWarning(contentWarning *string) (sql.NullString, bool) {
+	if contentWarning == nil {
+		return sql.NullString{}, true
+	}
+	trimmed := strings.TrimSpace(*contentWarning)
+	if trimmed == "" {
+		return sql.NullString{}, true
+	}
+	if len(trimmed) > maxContentWarningLength {
+		return sql.NullString{}, false
+	}
+	return sql.NullString{String: trimmed, Valid: true}, true
+}
+
+// sensitiveContentFor returns the viewer's preference, anonymous viewers
+// getting the default of collapsing
+func (a *APIConfig) sensitiveContentFor(ctx context.Context, viewerID uuid.NullUUID) (string, error) {
+	if !viewerID.Valid {
+		return sensitiveCollapse, nil
+	}
+	return a.PtrToQueries.GetSensitiveContent(ctx, viewerID.UUID)
+}
+
+// collapseSensitive marks chirps, and the originals shown inside them, that
+// clients should hide behind their warning until the viewer opens them
+func collapseSensitive(chirps []*chirpPayload) {
+	for _, chirp := range chirps {
+		chirp.Collapsed = chirp.Sensitive || chirp.ContentWarning != nil
+		if chirp.Original != nil {
+			chirp.Original.Collapsed = chirp.Original.Sensitive || chirp.Original.ContentWarning != nil
+		}
+	}
+}
+
+func (a *APIConfig) PutChirpSensitivity(writer http.ResponseWriter, req *http.Request) {
+	type requestBody struct {
+		ContentWarning *string `json:"content_warning"`
+		Sensitive bool `json:"sensitive"`
+	}
+
+	jwtToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	userID, err := auth.ValidateJWT(jwtToken, a.SecretKey)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+
+	chirpID := req.PathValue("chirpID")
+	if chirpID == "" {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	parsedChirpID, err := uuid.Parse(chirpID)
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+
+	dataReceivedInBytes, err := io.ReadAll(req.Body)
+	if err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+		return
+	}
+	dataReceived := &requestBody{}
+	if err := json.Unmarshal(dataReceivedInBytes, dataReceived); err != nil {
+		ErrorResponseWriter(writer, BadRequest)
+		return
+	}
+	contentWarning, ok := validContentWarning(dataReceived.ContentWarning)
+	if !ok {
+		ErrorResponseWriter(writer, BadRequest)
+		return
+	}
+
+	setChirpSensitivityParams := database.SetChirpSensitivityParams{
+		ContentWarning: contentWarning,
+		Sensitive: dataReceived.Sensitive,
+		ID: parsedChirpID,
+		UserID: userID,
+	}
+	updatedRows, err := a.PtrToQueries.SetChirpSensitivity(req.Context(), setChirpSensitivityParams)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	// Chirps by someone else are reported as missing rather than forbidden
+	if updatedRows == 0 {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+type preferencesPayload struct {
+	SensitiveContent string `json:"sensitive_content"`
+	Protected bool `json:"protected"`
+}
+
+func (a *APIConfig) GetPreferences(writer http.ResponseWriter, req *http.Request) {
+	jwtToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	userID, err := auth.ValidateJWT(jwtToken, a.SecretKey)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+
+	preferences, err := a.PtrToQueries.GetPreferences(req.Context(), userID)
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+
+	preferencesInBytes, err := json.Marshal(preferencesPayload(preferences))
+	if err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	if _, err := writer.Write(preferencesInBytes); err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+	}
+}
+
+// PutPreferences changes whichever preferences the body sets and leaves the
+// rest as they are
+func (a *APIConfig) PutPreferences(writer http.ResponseWriter, req *http.Request) {
+	type requestBody struct {
+		SensitiveContent *string `json:"sensitive_content"`
+		Protected *bool `json:"protected"`
+	}
+
+	jwtToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	userID, err := auth.ValidateJWT(jwtToken, a.SecretKey)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+
+	dataReceivedInBytes, err := io.ReadAll(req.Body)
+	if err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+		return
+	}
+	dataReceived := &requestBody{}
+	if err := json.Unmarshal(dataReceivedInBytes, dataReceived); err != nil {
+		ErrorResponseWriter(writer, BadRequest)
+		return
+	}
+	if dataReceived.SensitiveContent == nil && dataReceived.Protected == nil {
+		ErrorResponseWriter(writer, BadRequest)
+		return
+	}
+	if dataReceived.SensitiveContent != nil {
+		if _, ok := sensitiveContentModes[*dataReceived.SensitiveContent]; !ok {
+			ErrorResponseWriter(writer, BadRequest)
+			return
+		}
+	}
+
+	tx, err := a.PtrToDB.BeginTx(req.Context(), nil)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	defer tx.Rollback()
+	queriesInTx := a.PtrToQueries.WithTx(tx)
+	if dataReceived.SensitiveContent != nil {
+		setSensitiveContentParams := database.SetSensitiveContentParams{
+			ID: userID,
+			SensitiveContent: *dataReceived.SensitiveContent,
+		}
+		updatedRows, err := queriesInTx.SetSensitiveContent(req.Context(), setSensitiveContentParams)
+		if err != nil {
+			ErrorResponseWriter(writer, DatabaseError)
+			return
+		}
+		if updatedRows == 0 {
+			ErrorResponseWriter(writer, NotFound)
+			return
+		}
+	}
+	if dataReceived.Protected != nil {
+		if err := setProtected(req.Context(), queriesInTx, userID, *dataReceived.Protected); err != nil {
+			ErrorResponseWriter(writer, responseErrorFor(err))
+			return
+		}
+	}
+	preferences, err := queriesInTx.GetPreferences(req.Context(), userID)
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+
+	preferencesInBytes, err := json.Marshal(preferencesPayload(preferences))
+	if err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	if _, err := writer.Write(preferencesInBytes); err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+	}
+}
@@ -1,25 +1,46 @@
 package state
 
 import (
+	"context"
 	"sync/atomic"
 	"net/http"
 	"fmt"
 	"io"
 	"time"
 	"encoding/json"
+	"database/sql"
+	"errors"
 	"strings"
-	"sort"
 	"github.com/junwei890/chirpy/internal/database"
 	"github.com/junwei890/chirpy/internal/auth"
+	"github.com/junwei890/chirpy/internal/pagination"
+	"github.com/junwei890/chirpy/internal/filters"
+	"github.com/junwei890/chirpy/internal/trends"
+	"github.com/junwei890/chirpy/internal/media"
+	"github.com/junwei890/chirpy/internal/polls"
+	"github.com/junwei890/chirpy/internal/entities"
 	"github.com/google/uuid"
 )
 
 type APIConfig struct {
 	FileServerHits atomic.Int32
+	PtrToDB *sql.DB
 	PtrToQueries *database.Queries
 	Platform string
 	SecretKey string
 	WebhookKey string
+	AdminKey string
+	ChirpEditWindow time.Duration
+	TrashRetention time.Duration
+	MaxPins int
+	MaxPinsChirpyRed int
+	MaxChirpLength int
+	MaxChirpLengthChirpyRed int
+	TimelineFanoutLimit int
+	TimelineWake chan struct{}
+	Trends *trends.Tracker
+	Storage media.Storage
+	MediaWorkers *media.WorkerPool
 }
 
 func GetReadiness(writer http.ResponseWriter, req *http.Request) {
@@ -61,18 +82,28 @@ func (a *APIConfig) PostMetrics(writer http.ResponseWriter, req *http.Request) {
 	}
 }
 
+// defaultHandle is the placeholder handle of a user who did not pick one,
+// the same shape existing users were given when handles were introduced
+func defaultHandle() string {
+	return "user_" + strings.ReplaceAll(uuid.NewString(), "-", "")[:16]
+}
+
 func (a *APIConfig) PostUsers(writer http.ResponseWriter, req *http.Request) {
 	type requestBody struct {
 		Email string `json:"email"`
 		Password string `json:"password"`
+		Handle *string `json:"handle"`
 	}
 
 	type validResponse struct {
 		ID uuid.UUID `json:"id"`
 		Email string `json:"email"`
+		Handle string `json:"handle"`
 		CreatedAt time.Time `json:"created_at"`
 		UpdatedAt time.Time `json:"updated_at"`
 		ChirpyRed bool `json:"is_chirpy_red"`
+		FollowerCount int64 `json:"follower_count"`
+		FollowingCount int64 `json:"following_count"`
 	}
 	
 	dataReceivedInBytes, err := io.ReadAll(req.Body)
@@ -86,6 +117,16 @@ func (a *APIConfig) PostUsers(writer http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	handle := defaultHandle()
+	if dataReceived.Handle != nil {
+		normalizedHandle, ok := entities.NormalizeHandle(*dataReceived.Handle)
+		if !ok {
+			ErrorResponseWriter(writer, BadRequest)
+			return
+		}
+		handle = normalizedHandle
+	}
+
 	hashedPassword, err := auth.HashPassword(dataReceived.Password)
 	if err != nil {
 		ErrorResponseWriter(writer, ServiceError)
@@ -95,15 +136,21 @@ func (a *APIConfig) PostUsers(writer http.ResponseWriter, req *http.Request) {
 	createUserParams := database.CreateUserParams{
 		Email: dataReceived.Email,
 		HashedPassword: hashedPassword,
+		Handle: handle,
 	}
 	userCreationDetails, err := a.PtrToQueries.CreateUser(req.Context(), createUserParams)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ErrorResponseWriter(writer, AlreadyExists)
+			return
+		}
 		ErrorResponseWriter(writer, DatabaseError)
 		return
 	}
 	formattedUserCreationDetails := validResponse{
 		ID: userCreationDetails.ID,
 		Email: dataReceived.Email,
+		Handle: userCreationDetails.Handle,
 		CreatedAt: userCreationDetails.CreatedAt,
 		UpdatedAt: userCreationDetails.UpdatedAt,
 		ChirpyRed: userCreationDetails.IsChirpyRed,
@@ -121,30 +168,26 @@ func (a *APIConfig) PostUsers(writer http.ResponseWriter, req *http.Request) {
 	}
 }
 
-func (a *APIConfig) PostChirps(writer http.ResponseWriter, req *http.Request) {
-	type requestBody struct {
-		Body string `json:"body"`
-	}
-	type validResponse struct {
-		ID uuid.UUID `json:"id"`
-		Body string `json:"body"`
-		UserID uuid.UUID `json:"user_id"`
-		ChirpyRed bool `json:"is_chirpy_red"`
-		CreatedAt time.Time `json:"created_at"`
-		UpdatedAt time.Time `json:"updated_at"`
-	}
-	profanities := map[string]struct{}{
-		"kerfuffle": {},
-		"sharbert": {},
-		"fornax": {},
-	}
+// chirpInput is everything a client can send when creating a chirp
+type chirpInput struct {
+	Body string `json:"body"`
+	InReplyTo *uuid.UUID `json:"in_reply_to"`
+	QuoteOf *uuid.UUID `json:"quote_of"`
+	MediaIDs []uuid.UUID `json:"media_ids"`
+	PublishAt *time.Time `json:"publish_at"`
+	Poll *pollInput `json:"poll"`
+	ContentWarning *string `json:"content_warning"`
+	Sensitive bool `json:"sensitive"`
+	Visibility string `json:"visibility"`
+}
 
+func (a *APIConfig) PostChirps(writer http.ResponseWriter, req *http.Request) {
 	dataReceivedInBytes, err := io.ReadAll(req.Body)
 	if err != nil {
 		ErrorResponseWriter(writer, ServiceError)
 		return
 	}
-	dataReceived := &requestBody{}
+	dataReceived := &chirpInput{}
 	if err := json.Unmarshal(dataReceivedInBytes, dataReceived); err != nil {
 		ErrorResponseWriter(writer, BadRequest)
 		return
@@ -161,106 +204,376 @@ func (a *APIConfig) PostChirps(writer http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	if len(dataReceived.Body) > 140 {
-		ErrorResponseWriter(writer, LongChirp)
+	formattedChirpCreationDetails, err := a.createChirp(req.Context(), userID, *dataReceived, nil)
+	if err != nil {
+		chirpErrorResponseWriter(writer, err)
 		return
 	}
-	chirpInSlice := strings.Split(dataReceived.Body, " ")
-	for index, word := range chirpInSlice {
-		if _, ok := profanities[word]; ok {
-			chirpInSlice[index] = "****"
-		}
+
+	chirpCreationDetailsInBytes, err := json.Marshal(formattedChirpCreationDetails)
+	if err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusCreated)
+	if _, err := writer.Write(chirpCreationDetailsInBytes); err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+	}
+}
+
+// createChirp validates input and stores it as a chirp by userID. Validation
+// failures come back as an Error to respond with. inTx, when given, runs in
+// the same transaction just before it commits so callers can make other
+// changes that must only happen if the chirp is created.
+func (a *APIConfig) createChirp(ctx context.Context, userID uuid.UUID, input chirpInput, inTx func(queries *database.Queries) error) (chirpPayload, error) {
+	author, err := a.PtrToQueries.GetUserByID(ctx, userID)
+	if err != nil {
+		return chirpPayload{}, err
+	}
+	chirp, err := validateChirp(input.Body, a.chirpLengthLimit(author.IsChirpyRed))
+	if err != nil {
+		return chirpPayload{}, err
+	}
+
+	contentWarning, ok := validContentWarning(input.ContentWarning)
+	if !ok {
+		return chirpPayload{}, BadRequest
+	}
+
+	if input.Visibility == "" {
+		input.Visibility = visibilityPublic
+	}
+	if _, ok := chirpVisibilities[input.Visibility]; !ok {
+		return chirpPayload{}, BadRequest
 	}
-	chirp := strings.Join(chirpInSlice, " ")
 
 	createChirpParams := database.CreateChirpParams{
 		Body: chirp,
 		UserID: userID,
+		ContentWarning: contentWarning,
+		Sensitive: input.Sensitive,
+		Visibility: input.Visibility,
+	}
+	if input.PublishAt != nil {
+		publishAt, ok := validPublishAt(*input.PublishAt)
+		if !ok {
+			return chirpPayload{}, BadRequest
+		}
+		createChirpParams.PublishAt = sql.NullTime{Time: publishAt, Valid: true}
+	}
+	if input.InReplyTo != nil {
+		getOneChirpParams := database.GetOneChirpParams{
+			ID: *input.InReplyTo,
+			ViewerID: uuid.NullUUID{UUID: userID, Valid: true},
+		}
+		// Scheduled chirps can't be replied to or quoted, not even by their
+		// author, as the reply could go out before them
+		chirpToReplyTo, err := a.PtrToQueries.GetOneChirp(ctx, getOneChirpParams)
+		if err != nil || chirpToReplyTo.PublishAt.Valid {
+			return chirpPayload{}, BadRequest
+		}
+		createChirpParams.InReplyTo = uuid.NullUUID{UUID: *input.InReplyTo, Valid: true}
+	}
+	if input.QuoteOf != nil {
+		getOneChirpParams := database.GetOneChirpParams{
+			ID: *input.QuoteOf,
+			ViewerID: uuid.NullUUID{UUID: userID, Valid: true},
+		}
+		quotedChirp, err := a.PtrToQueries.GetOneChirp(ctx, getOneChirpParams)
+		if err != nil || quotedChirp.PublishAt.Valid || !isShareable(quotedChirp.Visibility) {
+			return chirpPayload{}, BadRequest
+		}
+		// Quoting a bare rechirp quotes the chirp it reposted
+		if quotedChirp.RechirpOf.Valid {
+			quotedChirp.ID = quotedChirp.RechirpOf.UUID
+		}
+		sharesProtected, err := a.sharesProtectedChirp(ctx, quotedChirp.ID, userID)
+		if err != nil {
+			return chirpPayload{}, err
+		}
+		if sharesProtected {
+			return chirpPayload{}, BadRequest
+		}
+		createChirpParams.QuoteOf = uuid.NullUUID{UUID: quotedChirp.ID, Valid: true}
+	}
+	if len(input.MediaIDs) > media.MaxPerChirp {
+		return chirpPayload{}, TooManyMedia
+	}
+	pollOptions := []string{}
+	if input.Poll != nil {
+		// A scheduled poll is only open once its chirp goes out
+		opensAt := time.Now().UTC()
+		if createChirpParams.PublishAt.Valid {
+			opensAt = createChirpParams.PublishAt.Time
+		}
+		pollOptions, err = polls.Validate(input.Poll.Options, input.Poll.ClosesAt.UTC(), opensAt)
+		if err != nil {
+			return chirpPayload{}, BadRequest
+		}
 	}
-	createdChirp, err := a.PtrToQueries.CreateChirp(req.Context(), createChirpParams)
+	createChirpMediaParams := database.CreateChirpMediaParams{
+		MediaIds: []uuid.UUID{},
+		Positions: []int32{},
+	}
+	seenMediaIDs := map[uuid.UUID]struct{}{}
+	for index, mediaID := range input.MediaIDs {
+		if _, ok := seenMediaIDs[mediaID]; ok {
+			return chirpPayload{}, BadRequest
+		}
+		seenMediaIDs[mediaID] = struct{}{}
+		createChirpMediaParams.MediaIds = append(createChirpMediaParams.MediaIds, mediaID)
+		createChirpMediaParams.Positions = append(createChirpMediaParams.Positions, int32(index))
+	}
+
+	tx, err := a.PtrToDB.BeginTx(ctx, nil)
 	if err != nil {
-		ErrorResponseWriter(writer, DatabaseError)
-		return
+		return chirpPayload{}, err
+	}
+	defer tx.Rollback()
+	queriesInTx := a.PtrToQueries.WithTx(tx)
+	if len(createChirpMediaParams.MediaIds) > 0 {
+		getAttachableMediaParams := database.GetAttachableMediaParams{
+			Ids: createChirpMediaParams.MediaIds,
+			UserID: userID,
+		}
+		attachableMedia, err := queriesInTx.GetAttachableMedia(ctx, getAttachableMediaParams)
+		if err != nil {
+			return chirpPayload{}, err
+		}
+		if len(attachableMedia) != len(createChirpMediaParams.MediaIds) {
+			return chirpPayload{}, BadRequest
+		}
 	}
-	formattedChirpCreationDetails := validResponse{
+	createdChirp, err := queriesInTx.CreateChirp(ctx, createChirpParams)
+	if err != nil {
+		return chirpPayload{}, err
+	}
+	if err := storeChirpEntities(ctx, queriesInTx, createdChirp.ID, createdChirp.Body); err != nil {
+		return chirpPayload{}, err
+	}
+	if len(createChirpMediaParams.MediaIds) > 0 {
+		createChirpMediaParams.ChirpID = createdChirp.ID
+		if err := queriesInTx.CreateChirpMedia(ctx, createChirpMediaParams); err != nil {
+			return chirpPayload{}, err
+		}
+	}
+	if input.Poll != nil {
+		createPollParams := database.CreatePollParams{
+			ChirpID: createdChirp.ID,
+			ClosesAt: input.Poll.ClosesAt.UTC(),
+		}
+		createdPoll, err := queriesInTx.CreatePoll(ctx, createPollParams)
+		if err != nil {
+			return chirpPayload{}, err
+		}
+		createPollOptionsParams := database.CreatePollOptionsParams{
+			PollID: createdPoll.ID,
+			Positions: []int32{},
+			Texts: pollOptions,
+		}
+		for index := range pollOptions {
+			createPollOptionsParams.Positions = append(createPollOptionsParams.Positions, int32(index))
+		}
+		if err := queriesInTx.CreatePollOptions(ctx, createPollOptionsParams); err != nil {
+			return chirpPayload{}, err
+		}
+	}
+	if inTx != nil {
+		if err := inTx(queriesInTx); err != nil {
+			return chirpPayload{}, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return chirpPayload{}, err
+	}
+	// Scheduled chirps count towards trends once the scheduler publishes them,
+	// and only public chirps by unprotected accounts count at all
+	if !createdChirp.PublishAt.Valid && createdChirp.Visibility == visibilityPublic && !author.Protected {
+		a.Trends.Record(hashtagsIn(createdChirp.Body), time.Now().UTC())
+	}
+	if !createdChirp.PublishAt.Valid {
+		a.wakeTimelineFanout()
+	}
+
+	formattedChirp := chirpPayload{
 		ID: createdChirp.ID,
 		Body: chirp,
 		UserID: createdChirp.UserID,
 		ChirpyRed: createdChirp.IsChirpyRed,
 		CreatedAt: createdChirp.CreatedAt,
 		UpdatedAt: createdChirp.UpdatedAt,
+		Edited: isEdited(createdChirp.CreatedAt, createdChirp.UpdatedAt),
+		InReplyTo: nullUUIDToPointer(createdChirp.InReplyTo),
+		RechirpOf: nullUUIDToPointer(createdChirp.RechirpOf),
+		QuoteOf: nullUUIDToPointer(createdChirp.QuoteOf),
+		PublishAt: nullTimeToPointer(createdChirp.PublishAt),
+		ContentWarning: nullStringToPointer(createdChirp.ContentWarning),
+		Sensitive: createdChirp.Sensitive,
+		Visibility: createdChirp.Visibility,
+	}
+	if err := a.decorateChirps(ctx, uuid.NullUUID{UUID: userID, Valid: true}, []*chirpPayload{&formattedChirp}); err != nil {
+		return chirpPayload{}, err
+	}
+	return formattedChirp, nil
+}
+
+func (a *APIConfig) GetChirps(writer http.ResponseWriter, req *http.Request) {
+	type validResponse struct {
+		Chirps []chirpPayload `json:"chirps"`
+		NextCursor *string `json:"next_cursor"`
 	}
 
-	chirpCreationDetailsInBytes, err := json.Marshal(formattedChirpCreationDetails)
+	viewerID, err := a.optionalUserID(req)
 	if err != nil {
-		ErrorResponseWriter(writer, ServiceError)
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
 		return
 	}
-	writer.Header().Set("Content-Type", "application/json")
-	writer.WriteHeader(http.StatusCreated)
-	if _, err := writer.Write(chirpCreationDetailsInBytes); err != nil {
-		ErrorResponseWriter(writer, ServiceError)
-	}
-}
 
-func (a *APIConfig) GetChirps(writer http.ResponseWriter, req *http.Request) {
-	type oneChirp struct {
-		ID uuid.UUID `json:"id"`
-		Body string `json:"body"`
-		UserID uuid.UUID `json:"user_id"`
-		ChirpyRed bool `json:"is_chirpy_red"`
-		CreatedAt time.Time `json:"created_at"`
-		UpdatedAt time.Time `json:"updated_at"`
+	chirpFilters, err := filters.ParseChirpFilters(req.URL.Query())
+	if err != nil {
+		var invalidParameterError *filters.InvalidParameterError
+		if errors.As(err, &invalidParameterError) {
+			InvalidParameterResponseWriter(writer, invalidParameterError.Parameter)
+			return
+		}
+		ErrorResponseWriter(writer, BadRequest)
+		return
 	}
-
-	sortOrder := req.URL.Query().Get("sort")
-	authorID := req.URL.Query().Get("author_id")
-	parsedAuthorID, err := uuid.Parse(authorID)
+	limit, err := pagination.ParseLimit(req.URL.Query().Get("limit"))
 	if err != nil {
-		parsedAuthorID = uuid.Nil
+		InvalidParameterResponseWriter(writer, "limit")
+		return
 	}
+	sensitiveContent, err := a.sensitiveContentFor(req.Context(), viewerID)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+
+	// One extra row is fetched to tell whether another page exists
+	listChirpsParams := database.ListChirpsByCreatedAtDescParams{
+		AuthorIds: chirpFilters.AuthorIDs,
+		ViewerID: viewerID,
+		HideSensitive: sensitiveContent == sensitiveFilter,
+		RowLimit: limit + 1,
+	}
+	if chirpFilters.Since != nil {
+		listChirpsParams.Since = sql.NullTime{Time: *chirpFilters.Since, Valid: true}
+	}
+	if chirpFilters.Until != nil {
+		listChirpsParams.Until = sql.NullTime{Time: *chirpFilters.Until, Valid: true}
+	}
+	if chirpFilters.ChirpyRed != nil {
+		listChirpsParams.IsChirpyRed = sql.NullBool{Bool: *chirpFilters.ChirpyRed, Valid: true}
+	}
+	if chirpFilters.HasMedia != nil {
+		listChirpsParams.HasMedia = sql.NullBool{Bool: *chirpFilters.HasMedia, Valid: true}
+	}
+	encodedCursor := req.URL.Query().Get("cursor")
+	if encodedCursor != "" {
+		cursor, err := pagination.DecodeCursor(encodedCursor)
+		if err != nil {
+			InvalidParameterResponseWriter(writer, "cursor")
+			return
+		}
+		listChirpsParams.CursorTime = sql.NullTime{Time: cursor.Time, Valid: true}
+		listChirpsParams.CursorID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+	// A single author's unfiltered chirps are their profile, which leads
+	// with their pins on the first page and leaves them out of the rest
+	showPins := len(chirpFilters.AuthorIDs) == 1 && chirpFilters.Since == nil && chirpFilters.Until == nil &&
+		chirpFilters.ChirpyRed == nil && chirpFilters.HasMedia == nil
+	listChirpsParams.ExcludePinned = showPins
 
-	sliceOfAllChirps, err := a.PtrToQueries.GetAllChirps(req.Context())
+	sliceOfChirps, err := a.listChirps(req.Context(), chirpFilters.Order(), listChirpsParams)
 	if err != nil {
 		ErrorResponseWriter(writer, DatabaseError)
 		return
 	}
-	returnChirps := []oneChirp{}
-	if parsedAuthorID != uuid.Nil {
-		for _, chirp := range sliceOfAllChirps {
-			if parsedAuthorID == chirp.UserID {
-				formattedChirp := oneChirp{
-					ID: chirp.ID,
-					Body: chirp.Body,
-					UserID: chirp.UserID,
-					ChirpyRed: chirp.IsChirpyRed,
-					CreatedAt: chirp.CreatedAt,
-					UpdatedAt: chirp.UpdatedAt,
-				}
-				returnChirps = append(returnChirps, formattedChirp)
-			}
+	returnChirps := []chirpPayload{}
+	for _, chirp := range sliceOfChirps {
+		formattedChirp := chirpPayload{
+			ID: chirp.ID,
+			Body: chirp.Body,
+			UserID: chirp.UserID,
+			ChirpyRed: chirp.IsChirpyRed,
+			CreatedAt: chirp.CreatedAt,
+			UpdatedAt: chirp.UpdatedAt,
+			Edited: isEdited(chirp.CreatedAt, chirp.UpdatedAt),
+			InReplyTo: nullUUIDToPointer(chirp.InReplyTo),
+			RechirpOf: nullUUIDToPointer(chirp.RechirpOf),
+			QuoteOf: nullUUIDToPointer(chirp.QuoteOf),
+			PublishAt: nullTimeToPointer(chirp.PublishAt),
+			ContentWarning: nullStringToPointer(chirp.ContentWarning),
+			Sensitive: chirp.Sensitive,
+			Visibility: chirp.Visibility,
+		}
+		returnChirps = append(returnChirps, formattedChirp)
+	}
+
+	formattedResponse := validResponse{
+		Chirps: returnChirps,
+	}
+	if len(returnChirps) > int(limit) {
+		formattedResponse.Chirps = returnChirps[:limit]
+		lastChirp := formattedResponse.Chirps[limit-1]
+		cursorTime := lastChirp.CreatedAt
+		if chirpFilters.SortBy == "updated_at" {
+			cursorTime = lastChirp.UpdatedAt
+		}
+		nextCursor := pagination.EncodeCursor(pagination.Cursor{
+			Time: cursorTime,
+			ID: lastChirp.ID,
+		})
+		formattedResponse.NextCursor = &nextCursor
+		writer.Header().Set("Link", pagination.NextLink(req.URL.Path, req.URL.Query(), "cursor", nextCursor))
+	}
+	if showPins && encodedCursor == "" {
+		listPinnedChirpsParams := database.ListPinnedChirpsParams{
+			UserID: chirpFilters.AuthorIDs[0],
+			ViewerID: viewerID,
+			HideSensitive: sensitiveContent == sensitiveFilter,
+		}
+		sliceOfPinnedChirps, err := a.PtrToQueries.ListPinnedChirps(req.Context(), listPinnedChirpsParams)
+		if err != nil {
+			ErrorResponseWriter(writer, DatabaseError)
+			return
 		}
-	} else {	
-		for _, chirp := range sliceOfAllChirps {
-			formattedChirp := oneChirp{
+		pinnedChirps := []chirpPayload{}
+		for _, chirp := range sliceOfPinnedChirps {
+			formattedChirp := chirpPayload{
 				ID: chirp.ID,
 				Body: chirp.Body,
 				UserID: chirp.UserID,
 				ChirpyRed: chirp.IsChirpyRed,
 				CreatedAt: chirp.CreatedAt,
 				UpdatedAt: chirp.UpdatedAt,
+				Edited: isEdited(chirp.CreatedAt, chirp.UpdatedAt),
+				InReplyTo: nullUUIDToPointer(chirp.InReplyTo),
+				RechirpOf: nullUUIDToPointer(chirp.RechirpOf),
+				QuoteOf: nullUUIDToPointer(chirp.QuoteOf),
+				PublishAt: nullTimeToPointer(chirp.PublishAt),
+				ContentWarning: nullStringToPointer(chirp.ContentWarning),
+				Sensitive: chirp.Sensitive,
+				Visibility: chirp.Visibility,
+				Pinned: true,
 			}
-			returnChirps = append(returnChirps, formattedChirp)
+			pinnedChirps = append(pinnedChirps, formattedChirp)
 		}
+		formattedResponse.Chirps = append(pinnedChirps, formattedResponse.Chirps...)
 	}
 
-	if sortOrder == "desc" {
-		sort.Slice(returnChirps, func(i, j int) bool {
-			return returnChirps[i].CreatedAt.After(returnChirps[j].CreatedAt)
-		})
+	chirpsToDecorate := []*chirpPayload{}
+	for index := range formattedResponse.Chirps {
+		chirpsToDecorate = append(chirpsToDecorate, &formattedResponse.Chirps[index])
+	}
+	if err := a.decorateChirps(req.Context(), viewerID, chirpsToDecorate); err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
 	}
 
-	chirpsInBytes, err := json.Marshal(returnChirps)
+	chirpsInBytes, err := json.Marshal(formattedResponse)
 	if err != nil {
 		ErrorResponseWriter(writer, ServiceError)
 		return
@@ -272,14 +585,52 @@ func (a *APIConfig) GetChirps(writer http.ResponseWriter, req *http.Request) {
 	}
 }
 
+// listChirps runs the listing query for the requested order. The four only
+// differ in name as far as their parameters and rows go
+func (a *APIConfig) listChirps(ctx context.Context, order filters.Order, listChirpsParams database.ListChirpsByCreatedAtDescParams) ([]database.ListChirpsByCreatedAtDescRow, error) {
+	sliceOfChirps := []database.ListChirpsByCreatedAtDescRow{}
+	switch order {
+	case filters.UpdatedAtDesc:
+		sliceOfRows, err := a.PtrToQueries.ListChirpsByUpdatedAtDesc(ctx, database.ListChirpsByUpdatedAtDescParams(listChirpsParams))
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range sliceOfRows {
+			sliceOfChirps = append(sliceOfChirps, database.ListChirpsByCreatedAtDescRow(row))
+		}
+	case filters.UpdatedAtAsc:
+		sliceOfRows, err := a.PtrToQueries.ListChirpsByUpdatedAtAsc(ctx, database.ListChirpsByUpdatedAtAscParams(listChirpsParams))
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range sliceOfRows {
+			sliceOfChirps = append(sliceOfChirps, database.ListChirpsByCreatedAtDescRow(row))
+		}
+	case filters.CreatedAtDesc:
+		return a.PtrToQueries.ListChirpsByCreatedAtDesc(ctx, listChirpsParams)
+	default:
+		sliceOfRows, err := a.PtrToQueries.ListChirpsByCreatedAtAsc(ctx, database.ListChirpsByCreatedAtAscParams(listChirpsParams))
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range sliceOfRows {
+			sliceOfChirps = append(sliceOfChirps, database.ListChirpsByCreatedAtDescRow(row))
+		}
+	}
+	return sliceOfChirps, nil
+}
+
 func (a *APIConfig) GetChirpsByID(writer http.ResponseWriter, req *http.Request) {
 	type validResponse struct {
-		ID uuid.UUID `json:"id"`
-		CreatedAt time.Time `json:"created_at"`
-		UpdatedAt time.Time `json:"updated_at"`
-		Body string `json:"body"`
-		UserID uuid.UUID `json:"user_id"`
-		ChirpyRed bool `json:"is_chirpy_red"`
+		chirpPayload
+		RechirpCount int64 `json:"rechirp_count"`
+		QuoteCount int64 `json:"quote_count"`
+	}
+
+	viewerID, err := a.optionalUserID(req)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
 	}
 
 	chirpID := req.PathValue("chirpID")
@@ -292,20 +643,47 @@ func (a *APIConfig) GetChirpsByID(writer http.ResponseWriter, req *http.Request)
 		ErrorResponseWriter(writer, NotFound)
 		return
 	}
-	chirpToGet, err := a.PtrToQueries.GetOneChirp(req.Context(), castedChirpID)
+	getOneChirpParams := database.GetOneChirpParams{
+		ID: castedChirpID,
+		ViewerID: viewerID,
+	}
+	chirpToGet, err := a.PtrToQueries.GetOneChirp(req.Context(), getOneChirpParams)
 	if err != nil {
 		ErrorResponseWriter(writer, NotFound)
 		return
 	}
 
+	rechirpCounts, err := a.PtrToQueries.GetRechirpCounts(req.Context(), chirpToGet.ID)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+
 	formattedChirpToGet := validResponse{
-		ID: chirpToGet.ID,
-		CreatedAt: chirpToGet.CreatedAt,
-		UpdatedAt: chirpToGet.UpdatedAt,
-		Body: chirpToGet.Body,
-		UserID: chirpToGet.UserID,
-		ChirpyRed: chirpToGet.IsChirpyRed,
+		chirpPayload: chirpPayload{
+			ID: chirpToGet.ID,
+			Body: chirpToGet.Body,
+			UserID: chirpToGet.UserID,
+			ChirpyRed: chirpToGet.IsChirpyRed,
+			CreatedAt: chirpToGet.CreatedAt,
+			UpdatedAt: chirpToGet.UpdatedAt,
+			Edited: isEdited(chirpToGet.CreatedAt, chirpToGet.UpdatedAt),
+			InReplyTo: nullUUIDToPointer(chirpToGet.InReplyTo),
+			RechirpOf: nullUUIDToPointer(chirpToGet.RechirpOf),
+			QuoteOf: nullUUIDToPointer(chirpToGet.QuoteOf),
+			PublishAt: nullTimeToPointer(chirpToGet.PublishAt),
+			ContentWarning: nullStringToPointer(chirpToGet.ContentWarning),
+			Sensitive: chirpToGet.Sensitive,
+			Visibility: chirpToGet.Visibility,
+		},
+		RechirpCount: rechirpCounts.RechirpCount,
+		QuoteCount: rechirpCounts.QuoteCount,
+	}
+	if err := a.decorateChirps(req.Context(), viewerID, []*chirpPayload{&formattedChirpToGet.chirpPayload}); err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
 	}
+
 	chirpToGetInBytes, err := json.Marshal(formattedChirpToGet)
 	if err != nil {
 		ErrorResponseWriter(writer, ServiceError)
@@ -328,7 +706,10 @@ func (a *APIConfig) PostLogin(writer http.ResponseWriter, req *http.Request) {
 		CreatedAt time.Time `json:"created_at"`
 		UpdatedAt time.Time `json:"updated_at"`
 		Email string `json:"email"`
+		Handle string `json:"handle"`
 		ChirpyRed bool `json:"is_chirpy_red"`
+		FollowerCount int64 `json:"follower_count"`
+		FollowingCount int64 `json:"following_count"`
 		Token string `json:"token"`
 		RefreshToken string `json:"refresh_token"`
 	}
@@ -370,13 +751,21 @@ func (a *APIConfig) PostLogin(writer http.ResponseWriter, req *http.Request) {
 		ErrorResponseWriter(writer, DatabaseError)
 		return
 	}
+	followCounts, err := a.PtrToQueries.GetFollowCounts(req.Context(), userDetails.ID)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
 
 	formattedUserDetails := validResponse{
 		ID: userDetails.ID,
 		CreatedAt: userDetails.CreatedAt,
 		UpdatedAt: userDetails.UpdatedAt,
 		Email: userDetails.Email,
+		Handle: userDetails.Handle,
 		ChirpyRed: userDetails.IsChirpyRed,
+		FollowerCount: followCounts.FollowerCount,
+		FollowingCount: followCounts.FollowingCount,
 		Token: jwtToken,
 		RefreshToken: createdRefreshToken.Token,
 	}
@@ -451,13 +840,17 @@ func (a *APIConfig) PutUsers(writer http.ResponseWriter, req *http.Request) {
 	type requestBody struct {
 		Email string `json:"email"`
 		Password string `json:"password"`
+		Handle *string `json:"handle"`
 	}
 	type validResponse struct {
 		ID uuid.UUID `json:"id"`
 		CreatedAt time.Time `json:"created_at"`
 		UpdatedAt time.Time `json:"updated_at"`
 		Email string `json:"email"`
+		Handle string `json:"handle"`
 		ChirpyRed bool `json:"is_chirpy_red"`
+		FollowerCount int64 `json:"follower_count"`
+		FollowingCount int64 `json:"following_count"`
 	}
 
 	jwtToken, err := auth.GetBearerToken(req.Header)
@@ -488,12 +881,48 @@ func (a *APIConfig) PutUsers(writer http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	tx, err := a.PtrToDB.BeginTx(req.Context(), nil)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	defer tx.Rollback()
+	queriesInTx := a.PtrToQueries.WithTx(tx)
+	if dataReceived.Handle != nil {
+		handle, ok := entities.NormalizeHandle(*dataReceived.Handle)
+		if !ok {
+			ErrorResponseWriter(writer, BadRequest)
+			return
+		}
+		setHandleParams := database.SetHandleParams{
+			ID: userID,
+			Handle: handle,
+		}
+		updatedRows, err := queriesInTx.SetHandle(req.Context(), setHandleParams)
+		if err != nil {
+			ErrorResponseWriter(writer, DatabaseError)
+			return
+		}
+		if updatedRows == 0 {
+			ErrorResponseWriter(writer, AlreadyExists)
+			return
+		}
+	}
 	updateUserParams := database.UpdateUserDetailsParams{
 		Email: dataReceived.Email,
 		HashedPassword: hashedPassword,
 		ID: userID,
 	}
-	updatedUserDetails, err := a.PtrToQueries.UpdateUserDetails(req.Context(), updateUserParams)
+	updatedUserDetails, err := queriesInTx.UpdateUserDetails(req.Context(), updateUserParams)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	followCounts, err := a.PtrToQueries.GetFollowCounts(req.Context(), userID)
 	if err != nil {
 		ErrorResponseWriter(writer, DatabaseError)
 		return
@@ -504,7 +933,10 @@ func (a *APIConfig) PutUsers(writer http.ResponseWriter, req *http.Request) {
 		CreatedAt: updatedUserDetails.CreatedAt,
 		UpdatedAt: updatedUserDetails.UpdatedAt,
 		Email: updatedUserDetails.Email,
+		Handle: updatedUserDetails.Handle,
 		ChirpyRed: updatedUserDetails.IsChirpyRed,
+		FollowerCount: followCounts.FollowerCount,
+		FollowingCount: followCounts.FollowingCount,
 	}
 	validResponseInBytes, err := json.Marshal(formattedValidResponse)
 	if err != nil {
@@ -541,7 +973,11 @@ func (a *APIConfig) DeleteChirps(writer http.ResponseWriter, req *http.Request)
 		return
 	}
 
-	returnedChirp, err := a.PtrToQueries.GetOneChirp(req.Context(), parsedChirpID)
+	getOneChirpParams := database.GetOneChirpParams{
+		ID: parsedChirpID,
+		ViewerID: uuid.NullUUID{UUID: userID, Valid: true},
+	}
+	returnedChirp, err := a.PtrToQueries.GetOneChirp(req.Context(), getOneChirpParams)
 	if err != nil {
 		ErrorResponseWriter(writer, NotFound)
 		return
@@ -551,13 +987,210 @@ func (a *APIConfig) DeleteChirps(writer http.ResponseWriter, req *http.Request)
 		return
 	}
 
-	if err := a.PtrToQueries.DeleteChirp(req.Context(), returnedChirp.ID); err != nil {
+	// A bare rechirp has nothing worth restoring, everything else goes to
+	// the trash until it is purged
+	if returnedChirp.RechirpOf.Valid {
+		if err := a.PtrToQueries.DeleteChirp(req.Context(), returnedChirp.ID); err != nil {
+			ErrorResponseWriter(writer, DatabaseError)
+			return
+		}
+		writer.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if err := a.PtrToQueries.SoftDeleteChirp(req.Context(), returnedChirp.ID); err != nil {
 		ErrorResponseWriter(writer, DatabaseError)
 		return
 	}
 	writer.WriteHeader(http.StatusNoContent)
 }
 
+func (a *APIConfig) PutChirps(writer http.ResponseWriter, req *http.Request) {
+	type requestBody struct {
+		Body string `json:"body"`
+	}
+
+	jwtToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	userID, err := auth.ValidateJWT(jwtToken, a.SecretKey)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+
+	chirpID := req.PathValue("chirpID")
+	if chirpID == "" {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	parsedChirpID, err := uuid.Parse(chirpID)
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+
+	dataReceivedInBytes, err := io.ReadAll(req.Body)
+	if err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+		return
+	}
+	dataReceived := &requestBody{}
+	if err := json.Unmarshal(dataReceivedInBytes, dataReceived); err != nil {
+		ErrorResponseWriter(writer, BadRequest)
+		return
+	}
+
+	getOneChirpParams := database.GetOneChirpParams{
+		ID: parsedChirpID,
+		ViewerID: uuid.NullUUID{UUID: userID, Valid: true},
+	}
+	returnedChirp, err := a.PtrToQueries.GetOneChirp(req.Context(), getOneChirpParams)
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	if returnedChirp.UserID != userID {
+		ErrorResponseWriter(writer, Forbidden)
+		return
+	}
+	if returnedChirp.RechirpOf.Valid {
+		ErrorResponseWriter(writer, BadRequest)
+		return
+	}
+	// The edit window only starts once a scheduled chirp goes out
+	if !returnedChirp.PublishAt.Valid && time.Since(returnedChirp.CreatedAt) > a.ChirpEditWindow {
+		ErrorResponseWriter(writer, EditWindowClosed)
+		return
+	}
+
+	chirp, err := validateChirp(dataReceived.Body, a.chirpLengthLimit(returnedChirp.IsChirpyRed))
+	if err != nil {
+		chirpErrorResponseWriter(writer, err)
+		return
+	}
+
+	updateChirpParams := database.UpdateChirpParams{
+		ID: returnedChirp.ID,
+		Body: chirp,
+	}
+	tx, err := a.PtrToDB.BeginTx(req.Context(), nil)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	defer tx.Rollback()
+	queriesInTx := a.PtrToQueries.WithTx(tx)
+	updatedChirp, err := queriesInTx.UpdateChirp(req.Context(), updateChirpParams)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	if err := storeChirpEntities(req.Context(), queriesInTx, updatedChirp.ID, updatedChirp.Body); err != nil {
+		ErrorResponseWriter(writer, responseErrorFor(err))
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	formattedUpdatedChirp := chirpPayload{
+		ID: updatedChirp.ID,
+		Body: updatedChirp.Body,
+		UserID: updatedChirp.UserID,
+		ChirpyRed: updatedChirp.IsChirpyRed,
+		CreatedAt: updatedChirp.CreatedAt,
+		UpdatedAt: updatedChirp.UpdatedAt,
+		Edited: isEdited(updatedChirp.CreatedAt, updatedChirp.UpdatedAt),
+		InReplyTo: nullUUIDToPointer(updatedChirp.InReplyTo),
+		RechirpOf: nullUUIDToPointer(updatedChirp.RechirpOf),
+		QuoteOf: nullUUIDToPointer(updatedChirp.QuoteOf),
+		PublishAt: nullTimeToPointer(updatedChirp.PublishAt),
+		ContentWarning: nullStringToPointer(updatedChirp.ContentWarning),
+		Sensitive: updatedChirp.Sensitive,
+		Visibility: updatedChirp.Visibility,
+	}
+	if err := a.decorateChirps(req.Context(), uuid.NullUUID{UUID: userID, Valid: true}, []*chirpPayload{&formattedUpdatedChirp}); err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+
+	updatedChirpInBytes, err := json.Marshal(formattedUpdatedChirp)
+	if err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	if _, err := writer.Write(updatedChirpInBytes); err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+	}
+}
+
+func (a *APIConfig) GetChirpRevisions(writer http.ResponseWriter, req *http.Request) {
+	type oneRevision struct {
+		ID uuid.UUID `json:"id"`
+		ChirpID uuid.UUID `json:"chirp_id"`
+		Body string `json:"body"`
+		CreatedAt time.Time `json:"created_at"`
+		ReplacedAt time.Time `json:"replaced_at"`
+	}
+
+	viewerID, err := a.optionalUserID(req)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+
+	chirpID := req.PathValue("chirpID")
+	if chirpID == "" {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	parsedChirpID, err := uuid.Parse(chirpID)
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	getOneChirpParams := database.GetOneChirpParams{
+		ID: parsedChirpID,
+		ViewerID: viewerID,
+	}
+	if _, err := a.PtrToQueries.GetOneChirp(req.Context(), getOneChirpParams); err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+
+	sliceOfRevisions, err := a.PtrToQueries.GetChirpRevisions(req.Context(), parsedChirpID)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	returnRevisions := []oneRevision{}
+	for _, revision := range sliceOfRevisions {
+		formattedRevision := oneRevision{
+			ID: revision.ID,
+			ChirpID: revision.ChirpID,
+			Body: revision.Body,
+			CreatedAt: revision.CreatedAt,
+			ReplacedAt: revision.ReplacedAt,
+		}
+		returnRevisions = append(returnRevisions, formattedRevision)
+	}
+
+	revisionsInBytes, err := json.Marshal(returnRevisions)
+	if err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	if _, err := writer.Write(revisionsInBytes); err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+	}
+}
+
 func (a *APIConfig) PostRed(writer http.ResponseWriter, req *http.Request) {
 	type data struct {
 		UserID string `json:"user_id"`
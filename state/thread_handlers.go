@@ -0,0 +1,184 @@
+package state
+
+import (
+	"net/http"
+	"database/sql"
+	"encoding/json"
+	"github.com/junwei890/chirpy/internal/database"
+	"github.com/junwei890/chirpy/internal/pagination"
+	"github.com/google/uuid"
+)
+
+const maxThreadDepth = 50
+
+func (a *APIConfig) GetChirpThread(writer http.ResponseWriter, req *http.Request) {
+	type oneReply struct {
+		chirpPayload
+		Depth int32 `json:"depth"`
+	}
+	type validResponse struct {
+		Chirp chirpPayload `json:"chirp"`
+		Ancestors []chirpPayload `json:"ancestors"`
+		Replies []oneReply `json:"replies"`
+		NextCursor *string `json:"next_cursor"`
+	}
+
+	viewerID, err := a.optionalUserID(req)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+
+	chirpID := req.PathValue("chirpID")
+	if chirpID == "" {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	parsedChirpID, err := uuid.Parse(chirpID)
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	limit, err := pagination.ParseLimit(req.URL.Query().Get("limit"))
+	if err != nil {
+		InvalidParameterResponseWriter(writer, "limit")
+		return
+	}
+	sensitiveContent, err := a.sensitiveContentFor(req.Context(), viewerID)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+
+	getOneChirpParams := database.GetOneChirpParams{
+		ID: parsedChirpID,
+		ViewerID: viewerID,
+	}
+	chirpToGet, err := a.PtrToQueries.GetOneChirp(req.Context(), getOneChirpParams)
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	getChirpAncestorsParams := database.GetChirpAncestorsParams{
+		ID: parsedChirpID,
+		ViewerID: viewerID,
+	}
+	sliceOfAncestors, err := a.PtrToQueries.GetChirpAncestors(req.Context(), getChirpAncestorsParams)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+
+	// One extra row is fetched to tell whether another page exists
+	getChirpDescendantsParams := database.GetChirpDescendantsParams{
+		HideSensitive: sensitiveContent == sensitiveFilter,
+		ID: parsedChirpID,
+		MaxDepth: maxThreadDepth,
+		ViewerID: viewerID,
+		RowLimit: limit + 1,
+	}
+	if encodedCursor := req.URL.Query().Get("cursor"); encodedCursor != "" {
+		cursorPath, err := pagination.DecodePathCursor(encodedCursor)
+		if err != nil {
+			InvalidParameterResponseWriter(writer, "cursor")
+			return
+		}
+		getChirpDescendantsParams.CursorPath = sql.NullString{String: cursorPath, Valid: true}
+	}
+	sliceOfDescendants, err := a.PtrToQueries.GetChirpDescendants(req.Context(), getChirpDescendantsParams)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+
+	formattedResponse := validResponse{
+		Chirp: chirpPayload{
+			ID: chirpToGet.ID,
+			Body: chirpToGet.Body,
+			UserID: chirpToGet.UserID,
+			ChirpyRed: chirpToGet.IsChirpyRed,
+			CreatedAt: chirpToGet.CreatedAt,
+			UpdatedAt: chirpToGet.UpdatedAt,
+			Edited: isEdited(chirpToGet.CreatedAt, chirpToGet.UpdatedAt),
+			InReplyTo: nullUUIDToPointer(chirpToGet.InReplyTo),
+			RechirpOf: nullUUIDToPointer(chirpToGet.RechirpOf),
+			QuoteOf: nullUUIDToPointer(chirpToGet.QuoteOf),
+			PublishAt: nullTimeToPointer(chirpToGet.PublishAt),
+			ContentWarning: nullStringToPointer(chirpToGet.ContentWarning),
+			Sensitive: chirpToGet.Sensitive,
+			Visibility: chirpToGet.Visibility,
+		},
+		Ancestors: []chirpPayload{},
+		Replies: []oneReply{},
+	}
+	for _, ancestor := range sliceOfAncestors {
+		formattedAncestor := chirpPayload{
+			ID: ancestor.ID,
+			Body: ancestor.Body,
+			UserID: ancestor.UserID,
+			ChirpyRed: ancestor.IsChirpyRed,
+			CreatedAt: ancestor.CreatedAt,
+			UpdatedAt: ancestor.UpdatedAt,
+			Edited: isEdited(ancestor.CreatedAt, ancestor.UpdatedAt),
+			InReplyTo: nullUUIDToPointer(ancestor.InReplyTo),
+			RechirpOf: nullUUIDToPointer(ancestor.RechirpOf),
+			QuoteOf: nullUUIDToPointer(ancestor.QuoteOf),
+			PublishAt: nullTimeToPointer(ancestor.PublishAt),
+			ContentWarning: nullStringToPointer(ancestor.ContentWarning),
+			Sensitive: ancestor.Sensitive,
+			Visibility: ancestor.Visibility,
+		}
+		formattedResponse.Ancestors = append(formattedResponse.Ancestors, formattedAncestor)
+	}
+	for _, descendant := range sliceOfDescendants {
+		formattedReply := oneReply{
+			chirpPayload: chirpPayload{
+				ID: descendant.ID,
+				Body: descendant.Body,
+				UserID: descendant.UserID,
+				ChirpyRed: descendant.IsChirpyRed,
+				CreatedAt: descendant.CreatedAt,
+				UpdatedAt: descendant.UpdatedAt,
+				Edited: isEdited(descendant.CreatedAt, descendant.UpdatedAt),
+				InReplyTo: nullUUIDToPointer(descendant.InReplyTo),
+				RechirpOf: nullUUIDToPointer(descendant.RechirpOf),
+				QuoteOf: nullUUIDToPointer(descendant.QuoteOf),
+				PublishAt: nullTimeToPointer(descendant.PublishAt),
+				ContentWarning: nullStringToPointer(descendant.ContentWarning),
+				Sensitive: descendant.Sensitive,
+				Visibility: descendant.Visibility,
+			},
+			Depth: descendant.Depth,
+		}
+		formattedResponse.Replies = append(formattedResponse.Replies, formattedReply)
+	}
+	if len(formattedResponse.Replies) > int(limit) {
+		formattedResponse.Replies = formattedResponse.Replies[:limit]
+		nextCursor := pagination.EncodePathCursor(sliceOfDescendants[limit-1].Path)
+		formattedResponse.NextCursor = &nextCursor
+		writer.Header().Set("Link", pagination.NextLink(req.URL.Path, req.URL.Query(), "cursor", nextCursor))
+	}
+
+	chirpsToDecorate := []*chirpPayload{&formattedResponse.Chirp}
+	for index := range formattedResponse.Ancestors {
+		chirpsToDecorate = append(chirpsToDecorate, &formattedResponse.Ancestors[index])
+	}
+	for index := range formattedResponse.Replies {
+		chirpsToDecorate = append(chirpsToDecorate, &formattedResponse.Replies[index].chirpPayload)
+	}
+	if err := a.decorateChirps(req.Context(), viewerID, chirpsToDecorate); err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+
+	threadInBytes, err := json.Marshal(formattedResponse)
+	if err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	if _, err := writer.Write(threadInBytes); err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+	}
+}
@@ -0,0 +1,211 @@
+package state
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+	"encoding/json"
+	"database/sql"
+	"github.com/junwei890/chirpy/internal/database"
+	"github.com/junwei890/chirpy/internal/auth"
+	"github.com/junwei890/chirpy/internal/pagination"
+	"github.com/google/uuid"
+)
+
+const fanoutBatchSize = 100
+const timelineBackfillSize = 50
+
+// wakeTimelineFanout gets new chirps onto timelines without waiting for the
+// next tick, a wake up that is already pending covers this one too
+func (a *APIConfig) wakeTimelineFanout() {
+	select {
+	case a.TimelineWake <- struct{}{}:
+	default:
+	}
+}
+
+// RunTimelineFanout writes published chirps to their author's followers'
+// timelines until ctx is done. Chirps waiting to be fanned out are marked in
+// the database, so a restart picks up whatever was missed on its first run.
+func (a *APIConfig) RunTimelineFanout(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := a.fanOutChirps(ctx); err != nil {
+			log.Println(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-a.TimelineWake:
+		}
+	}
+}
+
+func (a *APIConfig) fanOutChirps(ctx context.Context) error {
+	for {
+		fannedOutCount, err := a.fanOutBatch(ctx)
+		if err != nil {
+			return err
+		}
+		if fannedOutCount < fanoutBatchSize {
+			return nil
+		}
+	}
+}
+
+// fanOutBatch writes one batch of chirps to timelines. Chirps by accounts
+// with more followers than TimelineFanoutLimit are only marked, timelines
+// read those from their author instead of having them written to each one
+func (a *APIConfig) fanOutBatch(ctx context.Context) (int, error) {
+	tx, err := a.PtrToDB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	queriesInTx := a.PtrToQueries.WithTx(tx)
+
+	pendingChirps, err := queriesInTx.ClaimPendingFanouts(ctx, fanoutBatchSize)
+	if err != nil || len(pendingChirps) == 0 {
+		return 0, err
+	}
+	followerCounts := map[uuid.UUID]int64{}
+	for _, chirp := range pendingChirps {
+		followerCount, ok := followerCounts[chirp.UserID]
+		if !ok {
+			followerCount, err = queriesInTx.CountFollowers(ctx, chirp.UserID)
+			if err != nil {
+				return 0, err
+			}
+			followerCounts[chirp.UserID] = followerCount
+		}
+
+		fanoutOnRead := followerCount > int64(a.TimelineFanoutLimit)
+		if !fanoutOnRead {
+			fanOutChirpParams := database.FanOutChirpParams{
+				ChirpID: chirp.ID,
+				CreatedAt: chirp.CreatedAt,
+				UserID: chirp.UserID,
+			}
+			if err := queriesInTx.FanOutChirp(ctx, fanOutChirpParams); err != nil {
+				return 0, err
+			}
+		}
+		markChirpFannedOutParams := database.MarkChirpFannedOutParams{
+			ID: chirp.ID,
+			FanoutOnRead: fanoutOnRead,
+		}
+		if err := queriesInTx.MarkChirpFannedOut(ctx, markChirpFannedOutParams); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(pendingChirps), nil
+}
+
+func (a *APIConfig) GetTimeline(writer http.ResponseWriter, req *http.Request) {
+	type validResponse struct {
+		Chirps []chirpPayload `json:"chirps"`
+		NextCursor *string `json:"next_cursor"`
+	}
+
+	jwtToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	userID, err := auth.ValidateJWT(jwtToken, a.SecretKey)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	limit, err := pagination.ParseLimit(req.URL.Query().Get("limit"))
+	if err != nil {
+		InvalidParameterResponseWriter(writer, "limit")
+		return
+	}
+	viewerID := uuid.NullUUID{UUID: userID, Valid: true}
+	sensitiveContent, err := a.sensitiveContentFor(req.Context(), viewerID)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+
+	// One extra row is fetched to tell whether another page exists
+	listTimelineParams := database.ListTimelineParams{
+		UserID: userID,
+		HideSensitive: sensitiveContent == sensitiveFilter,
+		RowLimit: limit + 1,
+	}
+	if encodedCursor := req.URL.Query().Get("cursor"); encodedCursor != "" {
+		cursor, err := pagination.DecodeCursor(encodedCursor)
+		if err != nil {
+			InvalidParameterResponseWriter(writer, "cursor")
+			return
+		}
+		listTimelineParams.CursorTime = sql.NullTime{Time: cursor.Time, Valid: true}
+		listTimelineParams.CursorID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+	sliceOfChirps, err := a.PtrToQueries.ListTimeline(req.Context(), listTimelineParams)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+
+	formattedResponse := validResponse{
+		Chirps: []chirpPayload{},
+	}
+	for index, chirp := range sliceOfChirps {
+		if index == int(limit) {
+			lastChirp := sliceOfChirps[index-1]
+			nextCursor := pagination.EncodeCursor(pagination.Cursor{
+				Time: lastChirp.CreatedAt,
+				ID: lastChirp.ID,
+			})
+			formattedResponse.NextCursor = &nextCursor
+			writer.Header().Set("Link", pagination.NextLink(req.URL.Path, req.URL.Query(), "cursor", nextCursor))
+			break
+		}
+		formattedChirp := chirpPayload{
+			ID: chirp.ID,
+			Body: chirp.Body,
+			UserID: chirp.UserID,
+			ChirpyRed: chirp.IsChirpyRed,
+			CreatedAt: chirp.CreatedAt,
+			UpdatedAt: chirp.UpdatedAt,
+			Edited: isEdited(chirp.CreatedAt, chirp.UpdatedAt),
+			InReplyTo: nullUUIDToPointer(chirp.InReplyTo),
+			RechirpOf: nullUUIDToPointer(chirp.RechirpOf),
+			QuoteOf: nullUUIDToPointer(chirp.QuoteOf),
+			PublishAt: nullTimeToPointer(chirp.PublishAt),
+			ContentWarning: nullStringToPointer(chirp.ContentWarning),
+			Sensitive: chirp.Sensitive,
+			Visibility: chirp.Visibility,
+		}
+		formattedResponse.Chirps = append(formattedResponse.Chirps, formattedChirp)
+	}
+
+	chirpsToDecorate := []*chirpPayload{}
+	for index := range formattedResponse.Chirps {
+		chirpsToDecorate = append(chirpsToDecorate, &formattedResponse.Chirps[index])
+	}
+	if err := a.decorateChirps(req.Context(), viewerID, chirpsToDecorate); err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+
+	chirpsInBytes, err := json.Marshal(formattedResponse)
+	if err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	if _, err := writer.Write(chirpsInBytes); err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+	}
+}
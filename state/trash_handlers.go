@@ -0,0 +1,251 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"net/http"
+	"time"
+	"encoding/json"
+	"github.com/junwei890/chirpy/internal/database"
+	"github.com/junwei890/chirpy/internal/auth"
+	"github.com/junwei890/chirpy/internal/pagination"
+	"github.com/google/uuid"
+)
+
+const purgeBatchSize = 100
+
+func (a *APIConfig) GetTrash(writer http.ResponseWriter, req *http.Request) {
+	type validResponse struct {
+		Chirps []chirpPayload `json:"chirps"`
+		NextCursor *string `json:"next_cursor"`
+	}
+
+	jwtToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	userID, err := auth.ValidateJWT(jwtToken, a.SecretKey)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	limit, err := pagination.ParseLimit(req.URL.Query().Get("limit"))
+	if err != nil {
+		InvalidParameterResponseWriter(writer, "limit")
+		return
+	}
+
+	// Chirps past retention are waiting on the purge and can't be restored,
+	// so they are left out even if the purge has not got to them yet
+	listTrashedChirpsParams := database.ListTrashedChirpsParams{
+		UserID: userID,
+		DeletedSince: time.Now().UTC().Add(-a.TrashRetention),
+		RowLimit: limit + 1,
+	}
+	if encodedCursor := req.URL.Query().Get("cursor"); encodedCursor != "" {
+		cursor, err := pagination.DecodeCursor(encodedCursor)
+		if err != nil {
+			InvalidParameterResponseWriter(writer, "cursor")
+			return
+		}
+		listTrashedChirpsParams.CursorTime = sql.NullTime{Time: cursor.Time, Valid: true}
+		listTrashedChirpsParams.CursorID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+	sliceOfChirps, err := a.PtrToQueries.ListTrashedChirps(req.Context(), listTrashedChirpsParams)
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+
+	formattedResponse := validResponse{
+		Chirps: []chirpPayload{},
+	}
+	for index, chirp := range sliceOfChirps {
+		if index == int(limit) {
+			lastChirp := sliceOfChirps[index-1]
+			nextCursor := pagination.EncodeCursor(pagination.Cursor{
+				Time: lastChirp.DeletedAt,
+				ID: lastChirp.ID,
+			})
+			formattedResponse.NextCursor = &nextCursor
+			writer.Header().Set("Link", pagination.NextLink(req.URL.Path, req.URL.Query(), "cursor", nextCursor))
+			break
+		}
+		formattedChirp := chirpPayload{
+			ID: chirp.ID,
+			Body: chirp.Body,
+			UserID: chirp.UserID,
+			ChirpyRed: chirp.IsChirpyRed,
+			CreatedAt: chirp.CreatedAt,
+			UpdatedAt: chirp.UpdatedAt,
+			Edited: isEdited(chirp.CreatedAt, chirp.UpdatedAt),
+			InReplyTo: nullUUIDToPointer(chirp.InReplyTo),
+			RechirpOf: nullUUIDToPointer(chirp.RechirpOf),
+			QuoteOf: nullUUIDToPointer(chirp.QuoteOf),
+			PublishAt: nullTimeToPointer(chirp.PublishAt),
+			ContentWarning: nullStringToPointer(chirp.ContentWarning),
+			Sensitive: chirp.Sensitive,
+			Visibility: chirp.Visibility,
+			DeletedAt: &chirp.DeletedAt,
+		}
+		formattedResponse.Chirps = append(formattedResponse.Chirps, formattedChirp)
+	}
+
+	chirpsToDecorate := []*chirpPayload{}
+	for index := range formattedResponse.Chirps {
+		chirpsToDecorate = append(chirpsToDecorate, &formattedResponse.Chirps[index])
+	}
+	if err := a.decorateChirps(req.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirpsToDecorate); err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+
+	chirpsInBytes, err := json.Marshal(formattedResponse)
+	if err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	if _, err := writer.Write(chirpsInBytes); err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+	}
+}
+
+func (a *APIConfig) PostTrashRestore(writer http.ResponseWriter, req *http.Request) {
+	jwtToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+	userID, err := auth.ValidateJWT(jwtToken, a.SecretKey)
+	if err != nil {
+		ErrorResponseWriter(writer, UnauthorizedBadJWT)
+		return
+	}
+
+	chirpID := req.PathValue("chirpID")
+	if chirpID == "" {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	parsedChirpID, err := uuid.Parse(chirpID)
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+
+	restoreChirpParams := database.RestoreChirpParams{
+		ID: parsedChirpID,
+		UserID: userID,
+		DeletedSince: time.Now().UTC().Add(-a.TrashRetention),
+	}
+	restoredChirp, err := a.PtrToQueries.RestoreChirp(req.Context(), restoreChirpParams)
+	if err != nil {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+
+	formattedChirp := chirpPayload{
+		ID: restoredChirp.ID,
+		Body: restoredChirp.Body,
+		UserID: restoredChirp.UserID,
+		ChirpyRed: restoredChirp.IsChirpyRed,
+		CreatedAt: restoredChirp.CreatedAt,
+		UpdatedAt: restoredChirp.UpdatedAt,
+		Edited: isEdited(restoredChirp.CreatedAt, restoredChirp.UpdatedAt),
+		InReplyTo: nullUUIDToPointer(restoredChirp.InReplyTo),
+		RechirpOf: nullUUIDToPointer(restoredChirp.RechirpOf),
+		QuoteOf: nullUUIDToPointer(restoredChirp.QuoteOf),
+		PublishAt: nullTimeToPointer(restoredChirp.PublishAt),
+		ContentWarning: nullStringToPointer(restoredChirp.ContentWarning),
+		Sensitive: restoredChirp.Sensitive,
+		Visibility: restoredChirp.Visibility,
+	}
+	if err := a.decorateChirps(req.Context(), uuid.NullUUID{UUID: userID, Valid: true}, []*chirpPayload{&formattedChirp}); err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+
+	chirpInBytes, err := json.Marshal(formattedChirp)
+	if err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	if _, err := writer.Write(chirpInBytes); err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+	}
+}
+
+// RunTrashPurge removes chirps that have been in the trash longer than the
+// retention period until ctx is done
+func (a *APIConfig) RunTrashPurge(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := a.purgeTrash(ctx); err != nil {
+			log.Println(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (a *APIConfig) purgeTrash(ctx context.Context) error {
+	for {
+		purgedCount, err := a.purgeTrashBatch(ctx)
+		if err != nil {
+			return err
+		}
+		if purgedCount < purgeBatchSize {
+			return nil
+		}
+	}
+}
+
+// purgeTrashBatch deletes one batch of expired chirps along with their media.
+// Files are only removed once the rows are gone for good.
+func (a *APIConfig) purgeTrashBatch(ctx context.Context) (int, error) {
+	tx, err := a.PtrToDB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	queriesInTx := a.PtrToQueries.WithTx(tx)
+
+	claimPurgeableChirpsParams := database.ClaimPurgeableChirpsParams{
+		DeletedBefore: time.Now().UTC().Add(-a.TrashRetention),
+		RowLimit: purgeBatchSize,
+	}
+	chirpIDs, err := queriesInTx.ClaimPurgeableChirps(ctx, claimPurgeableChirpsParams)
+	if err != nil || len(chirpIDs) == 0 {
+		return 0, err
+	}
+	storageKeys, err := queriesInTx.GetStorageKeysForChirps(ctx, chirpIDs)
+	if err != nil {
+		return 0, err
+	}
+	if err := queriesInTx.DeleteMediaForChirps(ctx, chirpIDs); err != nil {
+		return 0, err
+	}
+	if err := queriesInTx.PurgeChirps(ctx, chirpIDs); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	for _, storageKey := range storageKeys {
+		if err := a.Storage.Delete(ctx, storageKey); err != nil {
+			log.Println(err)
+		}
+	}
+	return len(chirpIDs), nil
+}
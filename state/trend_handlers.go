@@ -0,0 +1,228 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"encoding/json"
+	"github.com/junwei890/chirpy/internal/auth"
+	"github.com/junwei890/chirpy/internal/entities"
+	"github.com/google/uuid"
+)
+
+// LoadTrends fills the trend tracker from chirps already in the database so a
+// restart does not wipe the rankings, it runs once before the worker starts
+func (a *APIConfig) LoadTrends(ctx context.Context) error {
+	if err := a.loadTrendExclusions(ctx); err != nil {
+		return err
+	}
+
+	longestWindow := time.Duration(0)
+	for _, window := range a.Trends.Windows() {
+		longestWindow = max(longestWindow, window)
+	}
+	sliceOfHashtags, err := a.PtrToQueries.GetRecentHashtags(ctx, time.Now().UTC().Add(-longestWindow))
+	if err != nil {
+		return err
+	}
+	tagsByChirp := map[uuid.UUID][]string{}
+	createdAtByChirp := map[uuid.UUID]time.Time{}
+	for _, hashtag := range sliceOfHashtags {
+		tagsByChirp[hashtag.ChirpID] = append(tagsByChirp[hashtag.ChirpID], hashtag.Tag)
+		createdAtByChirp[hashtag.ChirpID] = hashtag.CreatedAt
+	}
+	for chirpID, tags := range tagsByChirp {
+		a.Trends.Record(tags, createdAtByChirp[chirpID])
+	}
+	return nil
+}
+
+// RunTrends refreshes the trend rankings every interval until ctx is done.
+// Exclusions are reloaded first each time so that ones made through any
+// instance reach every instance's rankings
+func (a *APIConfig) RunTrends(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := a.loadTrendExclusions(ctx); err != nil {
+			log.Println(err)
+		}
+		a.Trends.Refresh()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (a *APIConfig) loadTrendExclusions(ctx context.Context) error {
+	sliceOfExclusions, err := a.PtrToQueries.GetTrendExclusions(ctx)
+	if err != nil {
+		return err
+	}
+	excludedTags := []string{}
+	for _, exclusion := range sliceOfExclusions {
+		excludedTags = append(excludedTags, exclusion.Tag)
+	}
+	a.Trends.SetExclusions(excludedTags)
+	return nil
+}
+
+func hashtagsIn(body string) []string {
+	tags := []string{}
+	for _, entity := range entities.Extract(body) {
+		if entity.Type == entities.Hashtag {
+			tags = append(tags, entity.Text)
+		}
+	}
+	return tags
+}
+
+func formatWindow(window time.Duration) string {
+	if window%time.Hour == 0 {
+		return fmt.Sprintf("%dh", window/time.Hour)
+	}
+	return fmt.Sprintf("%dm", window/time.Minute)
+}
+
+func (a *APIConfig) GetTrends(writer http.ResponseWriter, req *http.Request) {
+	type oneTrend struct {
+		Tag string `json:"tag"`
+		Score float64 `json:"score"`
+		ChirpCount int `json:"chirp_count"`
+	}
+	type oneWindow struct {
+		Window string `json:"window"`
+		Trends []oneTrend `json:"trends"`
+	}
+
+	windows := a.Trends.Windows()
+	if rawWindow := req.URL.Query().Get("window"); rawWindow != "" {
+		parsedWindow, err := time.ParseDuration(rawWindow)
+		if err != nil {
+			InvalidParameterResponseWriter(writer, "window")
+			return
+		}
+		windows = []time.Duration{parsedWindow}
+	}
+
+	returnWindows := []oneWindow{}
+	for _, window := range windows {
+		trends, ok := a.Trends.Trending(window)
+		if !ok {
+			InvalidParameterResponseWriter(writer, "window")
+			return
+		}
+		formattedWindow := oneWindow{
+			Window: formatWindow(window),
+			Trends: []oneTrend{},
+		}
+		for _, trend := range trends {
+			formattedTrend := oneTrend{
+				Tag: trend.Tag,
+				Score: trend.Score,
+				ChirpCount: trend.ChirpCount,
+			}
+			formattedWindow.Trends = append(formattedWindow.Trends, formattedTrend)
+		}
+		returnWindows = append(returnWindows, formattedWindow)
+	}
+
+	trendsInBytes, err := json.Marshal(returnWindows)
+	if err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	if _, err := writer.Write(trendsInBytes); err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+	}
+}
+
+func (a *APIConfig) checkAdminKey(req *http.Request) bool {
+	apiKey, err := auth.GetAPIKey(req.Header)
+	if err != nil {
+		return false
+	}
+	return a.AdminKey != "" && apiKey == a.AdminKey
+}
+
+func (a *APIConfig) GetTrendExclusions(writer http.ResponseWriter, req *http.Request) {
+	type oneExclusion struct {
+		Tag string `json:"tag"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+
+	if !a.checkAdminKey(req) {
+		ErrorResponseWriter(writer, UnauthorizedBadAPIKey)
+		return
+	}
+
+	sliceOfExclusions, err := a.PtrToQueries.GetTrendExclusions(req.Context())
+	if err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	returnExclusions := []oneExclusion{}
+	for _, exclusion := range sliceOfExclusions {
+		formattedExclusion := oneExclusion{
+			Tag: exclusion.Tag,
+			CreatedAt: exclusion.CreatedAt,
+		}
+		returnExclusions = append(returnExclusions, formattedExclusion)
+	}
+
+	exclusionsInBytes, err := json.Marshal(returnExclusions)
+	if err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	if _, err := writer.Write(exclusionsInBytes); err != nil {
+		ErrorResponseWriter(writer, ServiceError)
+	}
+}
+
+func (a *APIConfig) PutTrendExclusion(writer http.ResponseWriter, req *http.Request) {
+	if !a.checkAdminKey(req) {
+		ErrorResponseWriter(writer, UnauthorizedBadAPIKey)
+		return
+	}
+
+	tag := strings.ToLower(strings.TrimPrefix(req.PathValue("tag"), "#"))
+	if tag == "" {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	if err := a.PtrToQueries.CreateTrendExclusion(req.Context(), tag); err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	a.Trends.Exclude(tag)
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (a *APIConfig) DeleteTrendExclusion(writer http.ResponseWriter, req *http.Request) {
+	if !a.checkAdminKey(req) {
+		ErrorResponseWriter(writer, UnauthorizedBadAPIKey)
+		return
+	}
+
+	tag := strings.ToLower(strings.TrimPrefix(req.PathValue("tag"), "#"))
+	if tag == "" {
+		ErrorResponseWriter(writer, NotFound)
+		return
+	}
+	if err := a.PtrToQueries.DeleteTrendExclusion(req.Context(), tag); err != nil {
+		ErrorResponseWriter(writer, DatabaseError)
+		return
+	}
+	a.Trends.Include(tag)
+	writer.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,55 @@
+package tests
+
+import (
+	"testing"
+	"reflect"
+	"github.com/junwei890/chirpy/internal/entities"
+)
+
+func TestExtractEntities(t *testing.T) {
+	testCases := []struct {
+		name string
+		body string
+		expected []entities.Entity
+	}{
+		{
+			name: "Hashtag is lowercased",
+			body: "Loving #GoLang today",
+			expected: []entities.Entity{
+				{Type: entities.Hashtag, Text: "golang", Start: 7, End: 14},
+			},
+		},
+		{
+			name: "Offsets count characters not bytes",
+			body: "héllo #café @Bob_1.",
+			expected: []entities.Entity{
+				{Type: entities.Hashtag, Text: "café", Start: 6, End: 11},
+				{Type: entities.Mention, Text: "bob_1", Start: 12, End: 18},
+			},
+		},
+		{
+			name: "Email addresses are not mentions",
+			body: "ask @bob@example.com or @bob.smith@example.com",
+			expected: []entities.Entity{},
+		},
+		{
+			name: "Handles past the length limit are not mentions",
+			body: "hi @abcdefghijklmnopqrstuvwxyz12345",
+			expected: []entities.Entity{},
+		},
+		{
+			name: "Signs inside words and bare numbers are ignored",
+			body: "mail me at bob@example.com about issue#4 or #123",
+			expected: []entities.Entity{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			foundEntities := entities.Extract(testCase.body)
+			if !reflect.DeepEqual(foundEntities, testCase.expected) {
+				t.Errorf("test case: %s, failed. got %+v", testCase.name, foundEntities)
+			}
+		})
+	}
+}
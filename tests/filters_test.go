@@ -0,0 +1,133 @@
+package tests
+
+import (
+	"testing"
+	"errors"
+	"net/url"
+	"github.com/google/uuid"
+	"github.com/junwei890/chirpy/internal/filters"
+)
+
+func TestParseChirpFilters(t *testing.T) {
+	authorID1 := uuid.New()
+	authorID2 := uuid.New()
+
+	testCases := []struct {
+		name string
+		rawQuery string
+		expectedAuthors int
+		expectedSortDesc bool
+		invalidParameter string
+	}{
+		{
+			name: "No filters given",
+			rawQuery: "",
+			expectedAuthors: 0,
+			expectedSortDesc: false,
+			invalidParameter: "",
+		},
+		{
+			name: "Repeated and comma separated authors",
+			rawQuery: "author_id=" + authorID1.String() + "," + authorID2.String() + "&author_id=" + authorID1.String() + "&sort=desc",
+			expectedAuthors: 3,
+			expectedSortDesc: true,
+			invalidParameter: "",
+		},
+		{
+			name: "Malformed author",
+			rawQuery: "author_id=not-a-uuid",
+			invalidParameter: "author_id",
+		},
+		{
+			name: "Malformed since",
+			rawQuery: "since=yesterday",
+			invalidParameter: "since",
+		},
+		{
+			name: "Until before since",
+			rawQuery: "since=2025-06-02T00:00:00Z&until=2025-06-01T00:00:00Z",
+			invalidParameter: "until",
+		},
+		{
+			name: "Malformed has_media",
+			rawQuery: "has_media=sometimes",
+			invalidParameter: "has_media",
+		},
+		{
+			name: "Unknown sort field",
+			rawQuery: "sort_by=body",
+			invalidParameter: "sort_by",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			query, _ := url.ParseQuery(testCase.rawQuery)
+			chirpFilters, err := filters.ParseChirpFilters(query)
+			if testCase.invalidParameter != "" {
+				var invalidParameterError *filters.InvalidParameterError
+				if !errors.As(err, &invalidParameterError) || invalidParameterError.Parameter != testCase.invalidParameter {
+					t.Errorf("test case: %s, failed.", testCase.name)
+				}
+				return
+			}
+			if err != nil || len(chirpFilters.AuthorIDs) != testCase.expectedAuthors || chirpFilters.SortDesc != testCase.expectedSortDesc {
+				t.Errorf("test case: %s, failed.", testCase.name)
+			}
+		})
+	}
+}
+
+func TestChirpFiltersOrder(t *testing.T) {
+	testCases := []struct {
+		name string
+		rawQuery string
+		expected filters.Order
+	}{
+		{
+			name: "Defaults to oldest created first",
+			rawQuery: "",
+			expected: filters.CreatedAtAsc,
+		},
+		{
+			name: "Created ascending",
+			rawQuery: "sort_by=created_at&sort=asc",
+			expected: filters.CreatedAtAsc,
+		},
+		{
+			name: "Created descending",
+			rawQuery: "sort_by=created_at&sort=desc",
+			expected: filters.CreatedAtDesc,
+		},
+		{
+			name: "Descending without sort_by sorts by creation",
+			rawQuery: "sort=desc",
+			expected: filters.CreatedAtDesc,
+		},
+		{
+			name: "Updated ascending",
+			rawQuery: "sort_by=updated_at&sort=asc",
+			expected: filters.UpdatedAtAsc,
+		},
+		{
+			name: "Updated without sort is ascending",
+			rawQuery: "sort_by=updated_at",
+			expected: filters.UpdatedAtAsc,
+		},
+		{
+			name: "Updated descending",
+			rawQuery: "sort_by=updated_at&sort=desc",
+			expected: filters.UpdatedAtDesc,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			query, _ := url.ParseQuery(testCase.rawQuery)
+			chirpFilters, err := filters.ParseChirpFilters(query)
+			if err != nil || chirpFilters.Order() != testCase.expected {
+				t.Errorf("test case: %s, failed.", testCase.name)
+			}
+		})
+	}
+}
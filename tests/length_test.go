@@ -0,0 +1,71 @@
+package tests
+
+import (
+	"testing"
+	"strings"
+	"github.com/junwei890/chirpy/internal/length"
+)
+
+func TestNormalizeChirp(t *testing.T) {
+	testCases := []struct {
+		name string
+		body string
+		expected string
+	}{
+		{
+			name: "Runs of spaces and tabs collapse",
+			body: "  hello \t  world  ",
+			expected: "hello world",
+		},
+		{
+			name: "At most one blank line is kept",
+			body: "first\r\n\r\n\r\n\n second",
+			expected: "first\n\nsecond",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if normalized := length.Normalize(testCase.body); normalized != testCase.expected {
+				t.Errorf("test case: %s, failed. got %q", testCase.name, normalized)
+			}
+		})
+	}
+}
+
+func TestCountChirp(t *testing.T) {
+	testCases := []struct {
+		name string
+		body string
+		expected int
+	}{
+		{
+			name: "Plain text counts characters",
+			body: "hello world",
+			expected: 11,
+		},
+		{
+			name: "Emoji and CJK count once each",
+			body: strings.Repeat("👍🏽", 50) + "你好",
+			expected: 52,
+		},
+		{
+			name: "Combining marks stay with their letter",
+			body: "éé",
+			expected: 2,
+		},
+		{
+			name: "Links count as a fixed weight",
+			body: "see https://example.com/" + strings.Repeat("a", 100) + ".",
+			expected: 4 + length.URLWeight + 1,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if count := length.Count(testCase.body); count != testCase.expected {
+				t.Errorf("test case: %s, failed. got %d", testCase.name, count)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+	"github.com/junwei890/chirpy/internal/media"
+)
+
+// jpegWithExif encodes a width by height JPEG and splices in an APP1 segment
+// carrying an orientation tag and a GPS looking string that must not survive
+func jpegWithExif(t *testing.T, width, height int, orientation byte) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			img.Set(x, y, color.RGBA{R: uint8(x * 10), G: uint8(y * 10), B: 200, A: 255})
+		}
+	}
+	encoded := bytes.Buffer{}
+	if err := jpeg.Encode(&encoded, img, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	tiff = append(tiff, 0x00, 0x01)
+	tiff = append(tiff, 0x01, 0x12, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01, 0x00, orientation, 0x00, 0x00)
+	tiff = append(tiff, 0x00, 0x00, 0x00, 0x00)
+	tiff = append(tiff, []byte("GPS 51.5007N 0.1246W")...)
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	segmentLength := len(segment) + 2
+
+	withExif := []byte{0xFF, 0xD8, 0xFF, 0xE1, byte(segmentLength >> 8), byte(segmentLength)}
+	withExif = append(withExif, segment...)
+	return append(withExif, encoded.Bytes()[2:]...)
+}
+
+func pngOfSize(t *testing.T, width, height int) []byte {
+	encoded := bytes.Buffer{}
+	if err := png.Encode(&encoded, image.NewNRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return encoded.Bytes()
+}
+
+// gifWithFrames encodes an animation of frames width by height frames
+func gifWithFrames(t *testing.T, frames, width, height int) []byte {
+	animation := &gif.GIF{}
+	palette := color.Palette{color.Black, color.White}
+	for range frames {
+		animation.Image = append(animation.Image, image.NewPaletted(image.Rect(0, 0, width, height), palette))
+		animation.Delay = append(animation.Delay, 10)
+	}
+	encoded := bytes.Buffer{}
+	if err := gif.EncodeAll(&encoded, animation); err != nil {
+		t.Fatal(err)
+	}
+	return encoded.Bytes()
+}
+
+func TestProcessMedia(t *testing.T) {
+	testCases := []struct {
+		name string
+		data []byte
+		expectedErr error
+		expectedType string
+		expectedWidth int
+		expectedHeight int
+	}{
+		{
+			name: "Rotated JPEG is turned upright and stripped",
+			data: jpegWithExif(t, 40, 20, 6),
+			expectedType: "image/jpeg",
+			expectedWidth: 20,
+			expectedHeight: 40,
+		},
+		{
+			name: "Upright JPEG keeps its dimensions",
+			data: jpegWithExif(t, 40, 20, 1),
+			expectedType: "image/jpeg",
+			expectedWidth: 40,
+			expectedHeight: 20,
+		},
+		{
+			name: "Large PNG gets scaled thumbnails",
+			data: pngOfSize(t, 2000, 1000),
+			expectedType: "image/png",
+			expectedWidth: 2000,
+			expectedHeight: 1000,
+		},
+		{
+			name: "Too many pixels",
+			data: pngOfSize(t, 8192, 4096),
+			expectedErr: media.ErrDimensionsTooLarge,
+		},
+		{
+			name: "Animated GIF keeps its dimensions",
+			data: gifWithFrames(t, 3, 30, 20),
+			expectedType: "image/gif",
+			expectedWidth: 30,
+			expectedHeight: 20,
+		},
+		{
+			name: "Too many GIF frames",
+			data: gifWithFrames(t, media.MaxGIFFrames + 1, 1, 1),
+			expectedErr: media.ErrDimensionsTooLarge,
+		},
+		{
+			name: "Too many pixels across GIF frames",
+			data: gifWithFrames(t, 5, 4096, 4096),
+			expectedErr: media.ErrDimensionsTooLarge,
+		},
+		{
+			name: "Not an image",
+			data: []byte("definitely not an image"),
+			expectedErr: media.ErrUndecodable,
+		},
+	}
+
+	for _, testCase := range testCases {
+		processed, err := media.Process(testCase.data)
+		if testCase.expectedErr != nil {
+			if !errors.Is(err, testCase.expectedErr) {
+				t.Errorf("test case: %s, failed. expected error %v, got %v", testCase.name, testCase.expectedErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test case: %s, failed. %v", testCase.name, err)
+			continue
+		}
+		original := processed.Original
+		if original.ContentType != testCase.expectedType || original.Width != testCase.expectedWidth || original.Height != testCase.expectedHeight {
+			t.Errorf("test case: %s, failed. got %s %dx%d", testCase.name, original.ContentType, original.Width, original.Height)
+		}
+		if bytes.Contains(original.Data, []byte("Exif")) || bytes.Contains(original.Data, []byte("GPS")) {
+			t.Errorf("test case: %s, failed. metadata survived re-encoding", testCase.name)
+		}
+		if len(processed.Thumbnails) != len(media.Thumbnails) {
+			t.Errorf("test case: %s, failed. expected %d thumbnails, got %d", testCase.name, len(media.Thumbnails), len(processed.Thumbnails))
+			continue
+		}
+		for index, thumbnail := range processed.Thumbnails {
+			maxSide := media.Thumbnails[index].MaxSide
+			if thumbnail.Width > maxSide || thumbnail.Height > maxSide {
+				t.Errorf("test case: %s, failed. %s thumbnail is %dx%d", testCase.name, thumbnail.Name, thumbnail.Width, thumbnail.Height)
+			}
+			// Aspect ratio is kept, only images smaller than the limit stay as they are
+			if thumbnail.Width * original.Height != thumbnail.Height * original.Width {
+				t.Errorf("test case: %s, failed. %s thumbnail is %dx%d", testCase.name, thumbnail.Name, thumbnail.Width, thumbnail.Height)
+			}
+		}
+		if len(processed.Blurhash) != 28 {
+			t.Errorf("test case: %s, failed. unexpected blurhash %q", testCase.name, processed.Blurhash)
+		}
+	}
+}
+
+func TestBlurhash(t *testing.T) {
+	black := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for y := range 8 {
+		for x := range 8 {
+			black.Set(x, y, color.Black)
+		}
+	}
+
+	// A flat image has no detail, every AC component sits at the midpoint
+	expected := "L00000" + strings.Repeat("fQ", 11)
+	if hash := media.Blurhash(black, 4, 3); hash != expected {
+		t.Errorf("test case: %s, failed. expected %s, got %s", "Flat black image", expected, hash)
+	}
+}
+
+func TestWorkerPool(t *testing.T) {
+	pool := media.NewWorkerPool(2, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Nothing is running yet, so the second job finds the queue full
+	if !pool.Submit(func(ctx context.Context) {}) {
+		t.Errorf("test case: %s, failed.", "First job is queued")
+	}
+	if pool.Submit(func(ctx context.Context) {}) {
+		t.Errorf("test case: %s, failed.", "Full queue turns jobs away")
+	}
+
+	go pool.Run(ctx)
+	var ran atomic.Int32
+	deadline := time.Now().Add(time.Second)
+	for ran.Load() < 3 && time.Now().Before(deadline) {
+		pool.Submit(func(ctx context.Context) {
+			ran.Add(1)
+		})
+		time.Sleep(5 * time.Millisecond)
+	}
+	if ran.Load() < 3 {
+		t.Errorf("test case: %s, failed.", "Workers drain the queue")
+	}
+}
@@ -0,0 +1,70 @@
+package tests
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"github.com/junwei890/chirpy/internal/mutes"
+)
+
+func TestNormalizeTerm(t *testing.T) {
+	testCases := []struct {
+		name string
+		kind string
+		term string
+		expected string
+		expectedErr error
+	}{
+		{
+			name: "Keywords are lowercased",
+			kind: mutes.Keyword,
+			term: "Spoiler",
+			expected: "spoiler",
+		},
+		{
+			name: "Whitespace in phrases is collapsed",
+			kind: mutes.Keyword,
+			term: "  Game\tof   thrones ",
+			expected: "game of thrones",
+		},
+		{
+			name: "Hashtags lose their leading #",
+			kind: mutes.Hashtag,
+			term: "#Finale",
+			expected: "finale",
+		},
+		{
+			name: "Hashtags are a single word",
+			kind: mutes.Hashtag,
+			term: "#season finale",
+			expectedErr: mutes.ErrInvalidTerm,
+		},
+		{
+			name: "A bare # is not a hashtag",
+			kind: mutes.Hashtag,
+			term: "#",
+			expectedErr: mutes.ErrInvalidTerm,
+		},
+		{
+			name: "Blank keyword",
+			kind: mutes.Keyword,
+			term: " \t ",
+			expectedErr: mutes.ErrInvalidTerm,
+		},
+		{
+			name: "Keyword too long",
+			kind: mutes.Keyword,
+			term: strings.Repeat("a", mutes.MaxTermLength + 1),
+			expectedErr: mutes.ErrInvalidTerm,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			normalized, err := mutes.NormalizeTerm(testCase.kind, testCase.term)
+			if !errors.Is(err, testCase.expectedErr) || normalized != testCase.expected {
+				t.Errorf("test case: %s, failed. got %q, %v", testCase.name, normalized, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package tests
+
+import (
+	"testing"
+	"time"
+	"github.com/google/uuid"
+	"github.com/junwei890/chirpy/internal/pagination"
+)
+
+func TestEncodingAndDecodingCursor(t *testing.T) {
+	cursor := pagination.Cursor{
+		Time: time.Date(2025, time.June, 1, 12, 30, 0, 123456000, time.UTC),
+		ID: uuid.New(),
+	}
+
+	testCases := []struct {
+		name string
+		encodedCursor string
+		expected pagination.Cursor
+		errorPresent bool
+	}{
+		{
+			name: "Cursor round trips",
+			encodedCursor: pagination.EncodeCursor(cursor),
+			expected: cursor,
+			errorPresent: false,
+		},
+		{
+			name: "Cursor is not base64",
+			encodedCursor: "not a cursor!",
+			expected: pagination.Cursor{},
+			errorPresent: true,
+		},
+		{
+			name: "Cursor is missing its id",
+			encodedCursor: "MjAyNS0wNi0wMVQxMjozMDowMFo",
+			expected: pagination.Cursor{},
+			errorPresent: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			decodedCursor, err := pagination.DecodeCursor(testCase.encodedCursor)
+			if (err != nil) != testCase.errorPresent {
+				t.Errorf("test case: %s, failed.", testCase.name)
+			}
+			if !decodedCursor.Time.Equal(testCase.expected.Time) || decodedCursor.ID != testCase.expected.ID {
+				t.Errorf("test case: %s, failed.", testCase.name)
+			}
+		})
+	}
+}
+
+func TestEncodingAndDecodingPathCursor(t *testing.T) {
+	path := "20240102030405000000" + uuid.New().String()
+	decodedPath, err := pagination.DecodePathCursor(pagination.EncodePathCursor(path))
+	if err != nil || decodedPath != path {
+		t.Errorf("test case: %s, failed.", "Path survives a round trip")
+	}
+	for _, encodedCursor := range []string{"", "not base64!"} {
+		if _, err := pagination.DecodePathCursor(encodedCursor); err == nil {
+			t.Errorf("test case: %s, failed. %q decoded", "Malformed path cursor", encodedCursor)
+		}
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	testCases := []struct {
+		name string
+		rawLimit string
+		expected int32
+		errorPresent bool
+	}{
+		{
+			name: "Limit not given",
+			rawLimit: "",
+			expected: pagination.DefaultLimit,
+			errorPresent: false,
+		},
+		{
+			name: "Limit within range",
+			rawLimit: "50",
+			expected: 50,
+			errorPresent: false,
+		},
+		{
+			name: "Limit above maximum",
+			rawLimit: "1000",
+			expected: 0,
+			errorPresent: true,
+		},
+		{
+			name: "Limit not a number",
+			rawLimit: "ten",
+			expected: 0,
+			errorPresent: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			limit, err := pagination.ParseLimit(testCase.rawLimit)
+			if (err != nil) != testCase.errorPresent || limit != testCase.expected {
+				t.Errorf("test case: %s, failed.", testCase.name)
+			}
+		})
+	}
+}
+
+func TestParseOffset(t *testing.T) {
+	testCases := []struct {
+		name string
+		rawOffset string
+		expected int32
+		errorPresent bool
+	}{
+		{
+			name: "Offset not given",
+			rawOffset: "",
+			expected: 0,
+			errorPresent: false,
+		},
+		{
+			name: "Offset within range",
+			rawOffset: "40",
+			expected: 40,
+			errorPresent: false,
+		},
+		{
+			name: "Negative offset",
+			rawOffset: "-1",
+			expected: 0,
+			errorPresent: true,
+		},
+		{
+			name: "Offset above maximum",
+			rawOffset: "10001",
+			expected: 0,
+			errorPresent: true,
+		},
+		{
+			name: "Offset past 32 bits",
+			rawOffset: "4294967296",
+			expected: 0,
+			errorPresent: true,
+		},
+		{
+			name: "Offset not a number",
+			rawOffset: "ten",
+			expected: 0,
+			errorPresent: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			offset, err := pagination.ParseOffset(testCase.rawOffset)
+			if (err != nil) != testCase.errorPresent || offset != testCase.expected {
+				t.Errorf("test case: %s, failed.", testCase.name)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"github.com/junwei890/chirpy/internal/polls"
+)
+
+func TestValidatePoll(t *testing.T) {
+	now := time.Date(2025, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name string
+		options []string
+		closesAt time.Time
+		expectedOptions []string
+		expectedErr error
+	}{
+		{
+			name: "Options are trimmed",
+			options: []string{" yes ", "no"},
+			closesAt: now.Add(time.Hour),
+			expectedOptions: []string{"yes", "no"},
+		},
+		{
+			name: "Too few options",
+			options: []string{"yes"},
+			closesAt: now.Add(time.Hour),
+			expectedErr: polls.ErrOptionCount,
+		},
+		{
+			name: "Too many options",
+			options: []string{"a", "b", "c", "d", "e"},
+			closesAt: now.Add(time.Hour),
+			expectedErr: polls.ErrOptionCount,
+		},
+		{
+			name: "Duplicate options ignoring case",
+			options: []string{"Yes", "yes"},
+			closesAt: now.Add(time.Hour),
+			expectedErr: polls.ErrInvalidOption,
+		},
+		{
+			name: "Blank option",
+			options: []string{"yes", "   "},
+			closesAt: now.Add(time.Hour),
+			expectedErr: polls.ErrInvalidOption,
+		},
+		{
+			name: "Option too long",
+			options: []string{"yes", strings.Repeat("n", polls.MaxOptionLength + 1)},
+			closesAt: now.Add(time.Hour),
+			expectedErr: polls.ErrInvalidOption,
+		},
+		{
+			name: "Closes too soon",
+			options: []string{"yes", "no"},
+			closesAt: now.Add(time.Minute),
+			expectedErr: polls.ErrInvalidClosingTime,
+		},
+		{
+			name: "Closes too late",
+			options: []string{"yes", "no"},
+			closesAt: now.Add(8 * 24 * time.Hour),
+			expectedErr: polls.ErrInvalidClosingTime,
+		},
+	}
+
+	for _, testCase := range testCases {
+		options, err := polls.Validate(testCase.options, testCase.closesAt, now)
+		if !errors.Is(err, testCase.expectedErr) {
+			t.Errorf("test case: %s, failed. expected error %v, got %v", testCase.name, testCase.expectedErr, err)
+			continue
+		}
+		if strings.Join(options, "|") != strings.Join(testCase.expectedOptions, "|") {
+			t.Errorf("test case: %s, failed. expected %v, got %v", testCase.name, testCase.expectedOptions, options)
+		}
+	}
+}
+
+func TestShowTallies(t *testing.T) {
+	closesAt := time.Date(2025, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name string
+		hasVoted bool
+		now time.Time
+		expected bool
+	}{
+		{
+			name: "Open and not voted",
+			now: closesAt.Add(-time.Minute),
+			expected: false,
+		},
+		{
+			name: "Open and voted",
+			hasVoted: true,
+			now: closesAt.Add(-time.Minute),
+			expected: true,
+		},
+		{
+			name: "Closed",
+			now: closesAt,
+			expected: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		if polls.ShowTallies(testCase.hasVoted, closesAt, testCase.now) != testCase.expected {
+			t.Errorf("test case: %s, failed.", testCase.name)
+		}
+	}
+}
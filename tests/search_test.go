@@ -0,0 +1,49 @@
+package tests
+
+import (
+	"testing"
+	"github.com/junwei890/chirpy/internal/search"
+)
+
+func TestBuildTSQuery(t *testing.T) {
+	testCases := []struct {
+		name string
+		rawQuery string
+		expected string
+		errorPresent bool
+	}{
+		{
+			name: "Plain words",
+			rawQuery: "hello world",
+			expected: "hello & world",
+			errorPresent: false,
+		},
+		{
+			name: "Phrase and prefix",
+			rawQuery: `"good morning" chir*`,
+			expected: "(good <-> morning) & chir:*",
+			errorPresent: false,
+		},
+		{
+			name: "Operators are dropped",
+			rawQuery: "cats | !dogs & (birds)",
+			expected: "cats & dogs & birds",
+			errorPresent: false,
+		},
+		{
+			name: "Nothing searchable",
+			rawQuery: `"" & |`,
+			expected: "",
+			errorPresent: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			tsQuery, err := search.BuildTSQuery(testCase.rawQuery)
+			if (err != nil) != testCase.errorPresent || tsQuery != testCase.expected {
+				t.Errorf("test case: %s, failed. got %q", testCase.name, tsQuery)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"testing"
+	"time"
+	"github.com/junwei890/chirpy/internal/trends"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (f *fakeClock) Now() time.Time {
+	return f.now
+}
+
+func TestTrendingHashtags(t *testing.T) {
+	start := time.Date(2025, time.June, 1, 12, 0, 0, 0, time.UTC)
+	clock := &fakeClock{now: start}
+	tracker := trends.NewTracker(clock, []time.Duration{time.Hour, 24 * time.Hour})
+
+	// golang was busier but half a day ago, gophers is busy right now
+	for range 5 {
+		tracker.Record([]string{"golang"}, start.Add(-12*time.Hour))
+	}
+	for range 3 {
+		tracker.Record([]string{"gophers", "gophers"}, start.Add(-time.Minute))
+	}
+	tracker.Record([]string{"ancient"}, start.Add(-48*time.Hour))
+	tracker.Refresh()
+
+	testCases := []struct {
+		name string
+		window time.Duration
+		excluded string
+		expected []string
+		expectedCount int
+	}{
+		{
+			name: "Hour window only sees recent tags",
+			window: time.Hour,
+			expected: []string{"gophers"},
+			expectedCount: 3,
+		},
+		{
+			name: "Day window ranks recent bursts above older ones",
+			window: 24 * time.Hour,
+			expected: []string{"gophers", "golang"},
+			expectedCount: 3,
+		},
+		{
+			name: "Excluded tags are left out",
+			window: 24 * time.Hour,
+			excluded: "gophers",
+			expected: []string{"golang"},
+			expectedCount: 5,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			tracker.SetExclusions([]string{testCase.excluded})
+			trending, ok := tracker.Trending(testCase.window)
+			if !ok || len(trending) != len(testCase.expected) {
+				t.Fatalf("test case: %s, failed. got %+v", testCase.name, trending)
+			}
+			for index, tag := range testCase.expected {
+				if trending[index].Tag != tag {
+					t.Errorf("test case: %s, failed. got %+v", testCase.name, trending)
+				}
+			}
+			if trending[0].ChirpCount != testCase.expectedCount {
+				t.Errorf("test case: %s, failed. got %+v", testCase.name, trending)
+			}
+		})
+	}
+
+	t.Run("Tags age out of the window", func(t *testing.T) {
+		tracker.SetExclusions([]string{})
+		clock.now = start.Add(2 * time.Hour)
+		tracker.Refresh()
+		trending, _ := tracker.Trending(time.Hour)
+		if len(trending) != 0 {
+			t.Errorf("expected no trends, got %+v", trending)
+		}
+	})
+
+	t.Run("Unknown window", func(t *testing.T) {
+		if _, ok := tracker.Trending(time.Minute); ok {
+			t.Errorf("expected unknown window to be rejected")
+		}
+	})
+}